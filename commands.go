@@ -1,15 +1,65 @@
 package main
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+)
 
-func (c *commands) register(name string, f func(*state, command) error) {
+func (c *commands) register(name string, f func(context.Context, *state, command) error) {
 	c.registeredCommands[name] = f
 }
 
-func (c *commands) run(s *state, cmd command) error {
+func (c *commands) run(ctx context.Context, s *state, cmd command) error {
 	f, ok := c.registeredCommands[cmd.Name]
 	if !ok {
 		return errors.New("command not found")
 	}
-	return f(s, cmd)
+	return f(ctx, s, cmd)
+}
+
+// subcommands returns a handler that dispatches on its first argument, so
+// "gator webhook add <url>" runs the handler registered under "add" with
+// the command name "webhook add".
+func subcommands(handlers map[string]func(context.Context, *state, command) error) func(context.Context, *state, command) error {
+	return func(ctx context.Context, s *state, cmd command) error {
+		names := make([]string, 0, len(handlers))
+		for name := range handlers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		if len(cmd.Args) == 0 {
+			return fmt.Errorf("usage: %v <%v> [args...]", cmd.Name, strings.Join(names, "|"))
+		}
+
+		f, ok := handlers[cmd.Args[0]]
+		if !ok {
+			return fmt.Errorf("unknown %v command %q, expected one of: %v", cmd.Name, cmd.Args[0], strings.Join(names, ", "))
+		}
+
+		return f(ctx, s, command{Name: cmd.Name + " " + cmd.Args[0], Args: cmd.Args[1:]})
+	}
+}
+
+// parseFlags parses flags that may appear anywhere among args, unlike
+// flag.FlagSet.Parse which stops at the first positional argument, and
+// returns the positional arguments that remain.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
 }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+	"github.com/inscrutabletaco/gator/internal/sqlite"
+	"github.com/inscrutabletaco/gator/internal/store"
+	"github.com/inscrutabletaco/gator/sql/schema"
+	sqliteschema "github.com/inscrutabletaco/gator/sql/sqlite/schema"
+	_ "github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+)
+
+const sqliteScheme = "sqlite:"
+
+// openDatabase connects s to the database at dbURL: a SQLite file for
+// sqlite:<path> urls and Postgres for anything else.
+func openDatabase(s *state, dbURL string) error {
+	if path, ok := strings.CutPrefix(dbURL, sqliteScheme); ok {
+		// sqlite:///home/me/gator.db and sqlite:gator.db both work.
+		path = strings.TrimPrefix(path, "//")
+		if path == "" {
+			return fmt.Errorf("db_url %v has no file path", dbURL)
+		}
+		db, err := sqlite.Open(path)
+		if err != nil {
+			return err
+		}
+		queries, err := sqlite.New(db)
+		if err != nil {
+			db.Close()
+			return err
+		}
+		s.db = database.New(queries)
+		s.sqlDB = db
+		s.dialect = goose.DialectSQLite3
+		s.migrations = sqliteschema.FS
+		return nil
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return err
+	}
+	s.db = database.New(db)
+	s.sqlDB = db
+	s.dialect = goose.DialectPostgres
+	s.migrations = schema.FS
+	return nil
+}
+
+// isDuplicateKey reports whether err is a unique constraint violation, as
+// Postgres, SQLite or the store word it.
+func isDuplicateKey(err error) bool {
+	return errors.Is(err, store.ErrDuplicateKey) ||
+		strings.Contains(err.Error(), "duplicate key") ||
+		strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
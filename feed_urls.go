@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// trackingParams are query parameters added by newsletters and analytics
+// that never change which feed a url points to.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+}
+
+// canonicalFeedURL normalizes a feed url so different spellings of the same
+// feed are stored once: the scheme and host are lowercased, default ports,
+// fragments, trailing slashes and tracking parameters are dropped, and the
+// remaining query parameters are sorted.
+func canonicalFeedURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("invalid feed url %q: %w", raw, err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("feed url must be an absolute http(s) url, got: %s", raw)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	u.Host = host
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = strings.TrimRight(u.RawPath, "/")
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+	u.RawQuery = query.Encode()
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// feedURLKey identifies a canonical feed url regardless of scheme, since a
+// feed served over both http and https is the same feed.
+func feedURLKey(canonical string) string {
+	_, rest, _ := strings.Cut(canonical, "://")
+	return rest
+}
+
+// lookupFeed finds the feed a user means by rawURL. It tries the canonical
+// url first, then the same url with the other scheme, then rawURL exactly
+// as given for feeds stored before urls were canonicalized.
+func lookupFeed(ctx context.Context, s *state, rawURL string) (database.Feed, error) {
+	candidates := []string{}
+	if canonical, err := canonicalFeedURL(rawURL); err == nil {
+		other := "https://" + feedURLKey(canonical)
+		if strings.HasPrefix(canonical, "https://") {
+			other = "http://" + feedURLKey(canonical)
+		}
+		candidates = append(candidates, canonical, other)
+	}
+	candidates = append(candidates, rawURL)
+
+	return s.db.GetFeedByUrls(ctx, candidates)
+}
+
+func handlerFeedDedupe(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		return err
+	}
+
+	groups := map[string][]database.Feed{}
+	var keys []string
+	for _, feed := range feeds {
+		canonical, err := canonicalFeedURL(feed.Url)
+		if err != nil {
+			fmt.Printf("Skipping %v: %v\n", feed.Url, err)
+			continue
+		}
+		key := feedURLKey(canonical)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], feed)
+	}
+	sort.Strings(keys)
+
+	merged, renamed := 0, 0
+	for _, key := range keys {
+		group := groups[key]
+
+		// Keep the https feed if there is one, and otherwise the oldest.
+		sort.SliceStable(group, func(i, j int) bool {
+			iHTTPS := strings.HasPrefix(strings.ToLower(group[i].Url), "https:")
+			jHTTPS := strings.HasPrefix(strings.ToLower(group[j].Url), "https:")
+			if iHTTPS != jHTTPS {
+				return iHTTPS
+			}
+			return group[i].CreatedAt.Before(group[j].CreatedAt)
+		})
+		keep := group[0]
+
+		for _, duplicate := range group[1:] {
+			err := mergeFeeds(ctx, s, duplicate, keep)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Merged %v into %v\n", duplicate.Url, keep.Url)
+			merged++
+		}
+
+		canonical, _ := canonicalFeedURL(keep.Url)
+		if canonical != keep.Url {
+			err := s.db.SetFeedUrl(ctx, database.SetFeedUrlParams{
+				ID:  keep.ID,
+				Url: canonical,
+			})
+			if err != nil {
+				return fmt.Errorf("couldn't update url of %v: %w", keep.Url, err)
+			}
+			fmt.Printf("Renamed %v to %v\n", keep.Url, canonical)
+			renamed++
+		}
+	}
+
+	fmt.Printf("Merged %d duplicate feeds and canonicalized %d urls\n", merged, renamed)
+	return nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+	"github.com/microcosm-cc/bluemonday"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	fullTextBatchSize    = 4
+	fullTextMaxAttempts  = 3
+	fullTextPollInterval = 10 * time.Second
+	fullTextTimeout      = 15 * time.Second
+
+	// fullTextMaxBytes caps how much of an article page is read.
+	fullTextMaxBytes = 5 << 20
+)
+
+// fullTextPolicy is what's left of an article page once it's safe to hand to
+// a reader: text, links, images and formatting, but no scripts, event
+// handlers, javascript: links or embedded objects.
+var fullTextPolicy = bluemonday.UGCPolicy()
+
+// fetchFullTexts downloads the full text of queued posts until ctx is done.
+// Like webhook deliveries it runs on its own goroutine next to the agg loop,
+// so slow article pages hold up neither fetching nor WebSub pushes, which
+// only queue their posts.
+func fetchFullTexts(ctx context.Context, s *state) {
+	ticker := time.NewTicker(fullTextPollInterval)
+	defer ticker.Stop()
+	for {
+		err := fetchPendingFullTexts(ctx, s)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to fetch full texts: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchPendingFullTexts fetches batches of at most fullTextBatchSize pages
+// at a time until none are due or ctx is done.
+func fetchPendingFullTexts(ctx context.Context, s *state) error {
+	for ctx.Err() == nil {
+		fetches, err := s.db.ClaimFullTextFetches(ctx, fullTextBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(fetches) == 0 {
+			return nil
+		}
+
+		var wg sync.WaitGroup
+		for _, fetch := range fetches {
+			wg.Add(1)
+			go func(fetch database.FullTextFetch) {
+				defer wg.Done()
+				fetchPostFullText(ctx, s, fetch)
+			}(fetch)
+		}
+		wg.Wait()
+	}
+	return nil
+}
+
+// fetchPostFullText saves the full text of a claimed post and takes it off
+// the queue. A page that fails is left queued, and claiming it again waits a
+// few minutes, until it has failed fullTextMaxAttempts times.
+func fetchPostFullText(ctx context.Context, s *state, fetch database.FullTextFetch) {
+	saveCtx := context.WithoutCancel(ctx)
+	content, err := fetchFullText(ctx, fetch.Url)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Failed to fetch full text of %s: %v", fetch.Url, err)
+		if fetch.Attempts < fullTextMaxAttempts {
+			return
+		}
+	} else {
+		err = s.db.SetPostContent(saveCtx, database.SetPostContentParams{
+			ID:      fetch.PostID,
+			Content: sql.NullString{String: content, Valid: content != ""},
+		})
+		if err != nil {
+			log.Printf("Failed to save full text of %s: %v", fetch.Url, err)
+			return
+		}
+	}
+
+	err = s.db.DeleteFullTextFetch(saveCtx, fetch.PostID)
+	if err != nil {
+		log.Printf("Failed to dequeue full text of %s: %v", fetch.Url, err)
+	}
+}
+
+// fetchFullText downloads the page a post links to and returns the HTML of
+// its main content: the first <article>, else <main>, else <body>, without
+// page furniture, sanitized and with its links made absolute so they still
+// work in a reader.
+func fetchFullText(ctx context.Context, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "gator")
+
+	client := &http.Client{
+		Timeout: fullTextTimeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return "", &fetchStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	doc, err := html.Parse(io.LimitReader(resp.Body, fullTextMaxBytes))
+	if err != nil {
+		return "", err
+	}
+
+	var content *html.Node
+	for _, a := range []atom.Atom{atom.Article, atom.Main, atom.Body} {
+		content = findElement(doc, a)
+		if content != nil {
+			break
+		}
+	}
+	if content == nil {
+		return "", errors.New("page has no content")
+	}
+
+	stripElements(content, atom.Script, atom.Style, atom.Noscript, atom.Nav, atom.Header, atom.Footer, atom.Aside, atom.Form, atom.Iframe)
+	// Links are relative to where the page ended up after redirects.
+	resolveURLs(content, resp.Request.URL)
+
+	var buf bytes.Buffer
+	for child := content.FirstChild; child != nil; child = child.NextSibling {
+		err = html.Render(&buf, child)
+		if err != nil {
+			return "", fmt.Errorf("couldn't render content: %w", err)
+		}
+	}
+	return fullTextPolicy.Sanitize(buf.String()), nil
+}
+
+// resolveURLs makes the links and image sources under n absolute. srcset
+// isn't resolved; the sanitizer drops it.
+func resolveURLs(n *html.Node, base *url.URL) {
+	if n.Type == html.ElementNode {
+		for i, attr := range n.Attr {
+			switch attr.Key {
+			case "href", "src", "poster", "cite":
+				n.Attr[i].Val = resolveURL(base, attr.Val)
+			}
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		resolveURLs(child, base)
+	}
+}
+
+// resolveURL resolves ref against base, leaving it alone if it can't be
+// parsed; the sanitizer then drops it.
+func resolveURL(base *url.URL, ref string) string {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func stripElements(n *html.Node, atoms ...atom.Atom) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		strip := false
+		if child.Type == html.ElementNode {
+			for _, a := range atoms {
+				if child.DataAtom == a {
+					strip = true
+					break
+				}
+			}
+		}
+		if strip {
+			n.RemoveChild(child)
+		} else {
+			stripElements(child, atoms...)
+		}
+		child = next
+	}
+}
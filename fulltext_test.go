@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+func TestFetchFullText(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/posts/1", http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><nav>Menu</nav><article>
+<p onclick="steal()">Hello <a href="javascript:alert(1)">there</a></p>
+<a href="../about">About</a>
+<img src="cat.png" srcset="cat.png 1x, /big/cat.png 2x" onerror="steal()">
+<object data="x.swf"></object><embed src="x.swf"><svg onload="steal()"><circle r="1"/></svg>
+<script>steal()</script>
+</article></body></html>`))
+	}))
+	defer srv.Close()
+
+	content, err := fetchFullText(context.Background(), srv.URL+"/old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"steal", "javascript:", "onclick", "onerror", "<object", "<embed", "<svg", "srcset", "Menu"} {
+		if strings.Contains(content, bad) {
+			t.Errorf("full text contains %q: %v", bad, content)
+		}
+	}
+	// Links are relative to the page after the redirect.
+	for _, good := range []string{"Hello", `href="` + srv.URL + `/about"`, `src="` + srv.URL + `/posts/cat.png"`} {
+		if !strings.Contains(content, good) {
+			t.Errorf("full text is missing %q: %v", good, content)
+		}
+	}
+}
+
+func TestFullTextQueue(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><article><p>The whole story</p></article></body></html>`))
+	}))
+	defer srv.Close()
+
+	s := newTestState(t)
+	alice := registerUser(t, s, "alice")
+	feed := addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+	feed.FetchFullText = true
+
+	content := func() string {
+		t.Helper()
+		posts, err := s.db.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: alice.ID, Limit: 10})
+		if err != nil || len(posts) != 1 {
+			t.Fatalf("got posts %+v, %v, want 1", posts, err)
+		}
+		return posts[0].Content.String
+	}
+
+	// Saving a post only queues its full text.
+	saved := savePosts(ctx, s, feed, []RSSItem{{Title: "Story", Link: srv.URL + "/story"}})
+	if saved != 1 || content() != "" {
+		t.Fatalf("savePosts saved %d posts with content %q, want 1 without", saved, content())
+	}
+
+	err := fetchPendingFullTexts(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := content(); !strings.Contains(got, "The whole story") {
+		t.Errorf("post has content %q, want the article", got)
+	}
+	left, err := s.db.ClaimFullTextFetches(ctx, 10)
+	if err != nil || len(left) != 0 {
+		t.Errorf("queue still holds %+v, %v", left, err)
+	}
+}
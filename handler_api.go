@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// The gator API is served by `gator serve` under /api/. Every endpoint but
+// /api/login needs an API token, sent as "Authorization: Bearer <token>".
+
+type apiUser struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Role string    `json:"role"`
+}
+
+type apiFeedFollow struct {
+	Name string   `json:"name"`
+	Url  string   `json:"url"`
+	Tags []string `json:"tags"`
+}
+
+type apiPost struct {
+	Title       string     `json:"title"`
+	Url         string     `json:"url"`
+	Description string     `json:"description,omitempty"`
+	Author      string     `json:"author,omitempty"`
+	Feed        string     `json:"feed"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Highlight   bool       `json:"highlight"`
+}
+
+type apiLoginRequest struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	// TokenName names the token issued, so logging in again from the same
+	// machine replaces its old token.
+	TokenName string `json:"token_name"`
+}
+
+type apiLoginResponse struct {
+	Token string  `json:"token"`
+	User  apiUser `json:"user"`
+}
+
+func newAPIUser(user database.User) apiUser {
+	return apiUser{ID: user.ID, Name: user.Name, Role: user.Role}
+}
+
+// middlewareAPIAuth authenticates requests by their API token.
+func middlewareAPIAuth(s *state, handler func(w http.ResponseWriter, r *http.Request, user database.User)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondWithError(w, http.StatusUnauthorized, "missing API token", nil)
+			return
+		}
+
+		tokenHash := hashAPIToken(token)
+		user, err := s.db.GetUserByApiToken(r.Context(), tokenHash)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondWithError(w, http.StatusUnauthorized, "invalid API token", nil)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't check API token", err)
+			return
+		}
+
+		err = s.db.TouchApiToken(r.Context(), tokenHash)
+		if err != nil {
+			log.Printf("Failed to record API token use: %v", err)
+		}
+
+		handler(w, r, user)
+	}
+}
+
+func apiLoginHandler(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req apiLoginRequest
+		err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "couldn't decode request", err)
+			return
+		}
+		if req.TokenName == "" {
+			req.TokenName = "login"
+		}
+
+		user, err := s.db.GetUser(r.Context(), req.Name)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusUnauthorized, "wrong name or password", nil)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't get user", err)
+			return
+		}
+		err = checkPassword(user, req.Password)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "wrong name or password", nil)
+			return
+		}
+
+		token, err := createAPIToken(r.Context(), s, user, req.TokenName)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't create token", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, apiLoginResponse{Token: token, User: newAPIUser(user)})
+	}
+}
+
+func apiMeHandler(s *state) http.HandlerFunc {
+	return middlewareAPIAuth(s, func(w http.ResponseWriter, r *http.Request, user database.User) {
+		respondWithJSON(w, http.StatusOK, newAPIUser(user))
+	})
+}
+
+func apiFollowingHandler(s *state) http.HandlerFunc {
+	return middlewareAPIAuth(s, func(w http.ResponseWriter, r *http.Request, user database.User) {
+		tag := r.URL.Query().Get("tag")
+		rows, err := s.db.GetFeedFollowsForUser(r.Context(), database.GetFeedFollowsForUserParams{
+			UserID: user.ID,
+			Tag:    sql.NullString{String: tag, Valid: tag != ""},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't get feeds", err)
+			return
+		}
+
+		follows := []apiFeedFollow{}
+		for _, row := range rows {
+			follows = append(follows, apiFeedFollow{
+				Name: row.FeedName,
+				Url:  row.FeedUrl,
+				Tags: splitTags(row.Tags),
+			})
+		}
+		respondWithJSON(w, http.StatusOK, follows)
+	})
+}
+
+func apiPostsHandler(s *state) http.HandlerFunc {
+	return middlewareAPIAuth(s, func(w http.ResponseWriter, r *http.Request, user database.User) {
+		query := r.URL.Query()
+		tag, viewName := query.Get("tag"), query.Get("view")
+		if tag != "" && viewName != "" {
+			respondWithError(w, http.StatusBadRequest, "tag and view can't be used together", nil)
+			return
+		}
+		limit := 20
+		if query.Has("limit") {
+			var err error
+			limit, err = strconv.Atoi(query.Get("limit"))
+			if err != nil || limit <= 0 || limit > 1000 {
+				respondWithError(w, http.StatusBadRequest, "limit must be between 1 and 1000", nil)
+				return
+			}
+		}
+
+		posts, err := browsePosts(r.Context(), s, user, tag, viewName, limit)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't get posts", err)
+			return
+		}
+
+		resp := []apiPost{}
+		for _, post := range posts {
+			item := apiPost{
+				Title:       post.Title,
+				Url:         post.Url,
+				Description: post.Description.String,
+				Author:      post.Author.String,
+				Feed:        post.FeedName,
+				Highlight:   post.Highlight,
+			}
+			if post.PublishedAt.Valid {
+				item.PublishedAt = &post.PublishedAt.Time
+			}
+			resp = append(resp, item)
+		}
+		respondWithJSON(w, http.StatusOK, resp)
+	})
+}
+
+// middlewareRemote runs remote instead of local once the CLI has logged in
+// to a gator server with `login --server`.
+func middlewareRemote(remote, local func(context.Context, *state, command) error) func(context.Context, *state, command) error {
+	return func(ctx context.Context, s *state, cmd command) error {
+		if s.cfg.ServerURL != "" {
+			return remote(ctx, s, cmd)
+		}
+		return local(ctx, s, cmd)
+	}
+}
+
+// apiRequest sends a request to the gator server the CLI is logged in to and
+// decodes its JSON response into out.
+func apiRequest(ctx context.Context, s *state, method, path string, body, out interface{}) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&reqBody).Encode(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(s.cfg.ServerURL, "/")+path, &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "gator")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.cfg.APIToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.APIToken)
+	}
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		if resp.StatusCode == http.StatusUnauthorized && path != "/api/login" {
+			return fmt.Errorf("%v rejected the request (%v), log in again with: gator login --server %v %v", s.cfg.ServerURL, apiErr.Error, s.cfg.ServerURL, s.cfg.CurrentUserName)
+		}
+		if apiErr.Error != "" {
+			return fmt.Errorf("%v: %v", resp.Status, apiErr.Error)
+		}
+		return fmt.Errorf("%v", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// remoteLogin logs the CLI in to the gator server at serverURL.
+func remoteLogin(ctx context.Context, s *state, serverURL, name string) error {
+	u, err := url.Parse(serverURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("server must be an http(s) url, got: %v", serverURL)
+	}
+
+	password, err := readPassword("Password: ")
+	if err != nil {
+		return err
+	}
+
+	tokenName := "cli"
+	if host, err := os.Hostname(); err == nil {
+		tokenName = "cli on " + host
+	}
+
+	cfg := *s.cfg
+	cfg.ServerURL = serverURL
+	cfg.APIToken = ""
+	remote := &state{cfg: &cfg}
+	var resp apiLoginResponse
+	err = apiRequest(ctx, remote, "POST", "/api/login", apiLoginRequest{
+		Name:      name,
+		Password:  password,
+		TokenName: tokenName,
+	}, &resp)
+	if err != nil {
+		return fmt.Errorf("couldn't log in to %v: %w", serverURL, err)
+	}
+
+	err = s.cfg.SetServerUser(serverURL, resp.Token, resp.User.Name)
+	if err != nil {
+		return fmt.Errorf("couldn't set current user: %w", err)
+	}
+
+	fmt.Printf("Logged in to %v as %v\n", serverURL, resp.User.Name)
+	return nil
+}
+
+func handlerBrowseRemote(ctx context.Context, s *state, cmd command) error {
+	tag, viewName, limit, err := parseBrowseArgs(cmd)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	if tag != "" {
+		query.Set("tag", tag)
+	}
+	if viewName != "" {
+		query.Set("view", viewName)
+	}
+
+	var resp []apiPost
+	err = apiRequest(ctx, s, "GET", "/api/posts?"+query.Encode(), nil, &resp)
+	if err != nil {
+		return err
+	}
+
+	var posts []browsedPost
+	for _, item := range resp {
+		post := browsedPost{Highlight: item.Highlight}
+		post.Title = item.Title
+		post.Url = item.Url
+		post.Description = sql.NullString{String: item.Description, Valid: item.Description != ""}
+		post.Author = sql.NullString{String: item.Author, Valid: item.Author != ""}
+		post.FeedName = item.Feed
+		if item.PublishedAt != nil {
+			post.PublishedAt = sql.NullTime{Time: *item.PublishedAt, Valid: true}
+		}
+		posts = append(posts, post)
+	}
+
+	printBrowsedPosts(posts)
+	return nil
+}
+
+func handlerFollowingRemote(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	tag := fs.String("tag", "", "only list feeds with this tag")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: following [--tag <tag>]")
+	}
+
+	path := "/api/following"
+	if *tag != "" {
+		path += "?" + url.Values{"tag": {*tag}}.Encode()
+	}
+
+	var follows []apiFeedFollow
+	err = apiRequest(ctx, s, "GET", path, nil, &follows)
+	if err != nil {
+		return err
+	}
+
+	for _, follow := range follows {
+		printFollowedFeed(follow.Name, follow.Url, follow.Tags)
+	}
+	return nil
+}
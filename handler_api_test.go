@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// serveAPI serves s the way `gator serve` does and returns its url.
+func serveAPI(t *testing.T, s *state) string {
+	t.Helper()
+	srv := httptest.NewServer(newServeMux(s))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// apiCall sends body as JSON to the gator API with token, decodes the
+// response into out if it is a 200, and returns its status.
+func apiCall(t *testing.T, base, method, path, token string, body, out interface{}) int {
+	t.Helper()
+	var reqBody bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&reqBody).Encode(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, base+path, &reqBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK && out != nil {
+		err = json.NewDecoder(resp.Body).Decode(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// apiLogin logs in to the API as name and returns the token issued.
+func apiLogin(t *testing.T, base, name, tokenName string) string {
+	t.Helper()
+	var resp apiLoginResponse
+	status := apiCall(t, base, "POST", "/api/login", "", apiLoginRequest{Name: name, Password: testPassword, TokenName: tokenName}, &resp)
+	if status != http.StatusOK {
+		t.Fatalf("logging in as %v answered %v", name, status)
+	}
+	return resp.Token
+}
+
+// addPost saves a post titled title to feed.
+func addPost(t *testing.T, s *state, feed database.Feed, title string) {
+	t.Helper()
+	_, err := s.db.CreatePost(context.Background(), database.CreatePostParams{
+		Title:  title,
+		Url:    feed.Url + "/" + title,
+		FeedID: feed.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAPILogin(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	base := serveAPI(t, s)
+
+	for _, req := range []apiLoginRequest{
+		{Name: "alice", Password: "wrong password"},
+		{Name: "alice", Password: ""},
+		{Name: "nobody", Password: testPassword},
+	} {
+		status := apiCall(t, base, "POST", "/api/login", "", req, nil)
+		if status != http.StatusUnauthorized {
+			t.Errorf("logging in as %q with %q answered %v, want 401", req.Name, req.Password, status)
+		}
+	}
+
+	token := apiLogin(t, base, "alice", "laptop")
+	var me apiUser
+	status := apiCall(t, base, "GET", "/api/me", token, nil, &me)
+	if status != http.StatusOK || me.Name != "alice" {
+		t.Errorf("/api/me answered %v with %+v, want alice", status, me)
+	}
+
+	// Logging in again from the same machine replaces its token.
+	newToken := apiLogin(t, base, "alice", "laptop")
+	status = apiCall(t, base, "GET", "/api/me", token, nil, nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("replaced token answered %v, want 401", status)
+	}
+	status = apiCall(t, base, "GET", "/api/me", newToken, nil, nil)
+	if status != http.StatusOK {
+		t.Errorf("new token answered %v, want 200", status)
+	}
+}
+
+func TestAPITokens(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := registerUser(t, s, "alice")
+	base := serveAPI(t, s)
+
+	for _, token := range []string{"", "gator_not_a_token"} {
+		status := apiCall(t, base, "GET", "/api/me", token, nil, nil)
+		if status != http.StatusUnauthorized {
+			t.Errorf("token %q answered %v, want 401", token, status)
+		}
+	}
+
+	token, err := createAPIToken(ctx, s, alice, "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := apiCall(t, base, "GET", "/api/me", token, nil, nil)
+	if status != http.StatusOK {
+		t.Fatalf("token answered %v, want 200", status)
+	}
+
+	replacement, err := createAPIToken(ctx, s, alice, "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status = apiCall(t, base, "GET", "/api/me", token, nil, nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("replaced token answered %v, want 401", status)
+	}
+
+	err = runCommand(s, middlewareLoggedIn(handlerTokenRevoke), "token revoke", "phone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status = apiCall(t, base, "GET", "/api/me", replacement, nil, nil)
+	if status != http.StatusUnauthorized {
+		t.Errorf("revoked token answered %v, want 401", status)
+	}
+	err = runCommand(s, middlewareLoggedIn(handlerTokenRevoke), "token revoke", "phone")
+	if err == nil {
+		t.Error("revoked a token twice")
+	}
+}
+
+func TestAPIShowsOnlyTheUsersOwnData(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	bob := registerUser(t, s, "bob")
+	aliceFeed := addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+	bobFeed := addFeed(t, s, "bob", "Bob's blog", "https://bob.example/feed")
+	addPost(t, s, aliceFeed, "alice-post")
+	addPost(t, s, bobFeed, "bob-post")
+	_, err := s.db.SaveView(ctx, database.SaveViewParams{UserID: bob.ID, Name: "bobs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := serveAPI(t, s)
+	token := apiLogin(t, base, "alice", "laptop")
+
+	var follows []apiFeedFollow
+	status := apiCall(t, base, "GET", "/api/following", token, nil, &follows)
+	if status != http.StatusOK || len(follows) != 1 || follows[0].Url != aliceFeed.Url {
+		t.Errorf("/api/following answered %v with %+v, want only alice's feed", status, follows)
+	}
+
+	var posts []apiPost
+	status = apiCall(t, base, "GET", "/api/posts", token, nil, &posts)
+	if status != http.StatusOK || len(posts) != 1 || posts[0].Title != "alice-post" {
+		t.Errorf("/api/posts answered %v with %+v, want only alice's post", status, posts)
+	}
+
+	posts = nil
+	status = apiCall(t, base, "GET", "/api/posts?view=bobs", token, nil, &posts)
+	if status == http.StatusOK || len(posts) != 0 {
+		t.Errorf("alice read bob's view: %v %+v", status, posts)
+	}
+}
+
+func TestAPIPostsLimit(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	feed := addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+	addPost(t, s, feed, "first")
+	addPost(t, s, feed, "second")
+	base := serveAPI(t, s)
+	token := apiLogin(t, base, "alice", "laptop")
+
+	for _, limit := range []string{"0", "-1", "1001", "ten", ""} {
+		status := apiCall(t, base, "GET", "/api/posts?limit="+limit, token, nil, nil)
+		if status != http.StatusBadRequest {
+			t.Errorf("limit %q answered %v, want 400", limit, status)
+		}
+	}
+	status := apiCall(t, base, "GET", "/api/posts?tag=a&view=b", token, nil, nil)
+	if status != http.StatusBadRequest {
+		t.Errorf("tag and view together answered %v, want 400", status)
+	}
+
+	var posts []apiPost
+	status = apiCall(t, base, "GET", "/api/posts?limit=1", token, nil, &posts)
+	if status != http.StatusOK || len(posts) != 1 {
+		t.Errorf("limit 1 answered %v with %d posts, want 1", status, len(posts))
+	}
+	status = apiCall(t, base, "GET", "/api/posts?limit=1000", token, nil, &posts)
+	if status != http.StatusOK || len(posts) != 2 {
+		t.Errorf("limit 1000 answered %v with %d posts, want 2", status, len(posts))
+	}
+}
+
+func TestRemoteLogin(t *testing.T) {
+	server := newTestState(t)
+	registerUser(t, server, "alice")
+	feed := addFeed(t, server, "alice", "Alice's blog", "https://alice.example/feed")
+	addPost(t, server, feed, "alice-post")
+	base := serveAPI(t, server)
+
+	s := newTestState(t)
+	withStdin(t, "wrong password")
+	err := runCommand(s, handlerLogin, "login", "--server", base, "alice")
+	if err == nil {
+		t.Error("logged in to the server with a wrong password")
+	}
+	if s.cfg.ServerURL != "" || s.cfg.APIToken != "" {
+		t.Errorf("failed login changed the config: %v, %v", s.cfg.ServerURL, s.cfg.APIToken)
+	}
+	err = runCommand(s, handlerLogin, "login", "--server", "ftp://gator.example.com", "alice")
+	if err == nil {
+		t.Error("logged in to an ftp server")
+	}
+
+	withStdin(t, testPassword)
+	err = runCommand(s, handlerLogin, "login", "--server", base, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.cfg.ServerURL != base || s.cfg.APIToken == "" || s.cfg.CurrentUserName != "alice" {
+		t.Errorf("config is logged in to %q as %q with token %q", s.cfg.ServerURL, s.cfg.CurrentUserName, s.cfg.APIToken)
+	}
+
+	// s has no database of its own, so this only works through the server.
+	browse := middlewareRemote(handlerBrowseRemote, middlewareLoggedIn(handlerBrowse))
+	err = runCommand(s, browse, "browse", "5")
+	if err != nil {
+		t.Errorf("browsing the server: %v", err)
+	}
+
+	err = s.cfg.SetServerUser(base, "gator_revoked", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = runCommand(s, browse, "browse", "5")
+	if err == nil {
+		t.Error("browsed the server with a bad token")
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+)
+
+const (
+	minPasswordLength = 8
+	// apiTokenPrefix makes gator tokens easy to recognize, e.g. by secret
+	// scanners, if one leaks into a repository or a log.
+	apiTokenPrefix = "gator_"
+)
+
+// stdin is shared by every prompt, so passwords piped in one per line are
+// not lost to a reader's buffer.
+var stdin = bufio.NewReader(os.Stdin)
+
+// readPassword prompts for a password without echoing it. When stdin isn't a
+// terminal the password is read as one line, so scripts can pipe it in.
+func readPassword(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		password, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+
+	line, err := stdin.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("couldn't read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readNewPassword prompts for a new password, and asks for it twice when
+// someone is typing it.
+func readNewPassword() (string, error) {
+	password, err := readPassword("New password: ")
+	if err != nil {
+		return "", err
+	}
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		again, err := readPassword("Repeat password: ")
+		if err != nil {
+			return "", err
+		}
+		if again != password {
+			return "", fmt.Errorf("passwords don't match")
+		}
+	}
+	return password, nil
+}
+
+func setPassword(ctx context.Context, s *state, user database.User, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("couldn't hash password: %w", err)
+	}
+	return s.db.SetUserPassword(ctx, database.SetUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: sql.NullString{String: string(hash), Valid: true},
+	})
+}
+
+// checkPassword returns an error unless password is user's password. Users
+// registered before passwords existed have none and can't pass the check
+// until an admin sets one.
+func checkPassword(user database.User, password string) error {
+	if !user.PasswordHash.Valid {
+		return noPasswordError(user)
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash.String), []byte(password))
+	if err != nil {
+		return fmt.Errorf("wrong password for %v", user.Name)
+	}
+	return nil
+}
+
+// confirmPassword asks for user's password before commands that hand out
+// access. The current user is only what the config, or GATOR_USER, says, so
+// these check that whoever runs gator knows the password too.
+func confirmPassword(user database.User, prompt string) error {
+	if !user.PasswordHash.Valid {
+		return noPasswordError(user)
+	}
+	password, err := readPassword(prompt)
+	if err != nil {
+		return err
+	}
+	return checkPassword(user, password)
+}
+
+func noPasswordError(user database.User) error {
+	return fmt.Errorf("%v has no password, ask an admin to set one with: gator user passwd %v", user.Name, user.Name)
+}
+
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createAPIToken issues a new token for user, replacing any token with the
+// same name, and returns it. Only its hash is stored.
+func createAPIToken(ctx context.Context, s *state, user database.User, name string) (string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	token := apiTokenPrefix + hex.EncodeToString(secret)
+
+	_, err = s.db.CreateApiToken(ctx, database.CreateApiTokenParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		Name:      name,
+		TokenHash: hashAPIToken(token),
+	})
+	if err != nil {
+		return "", fmt.Errorf("couldn't create token: %w", err)
+	}
+	return token, nil
+}
+
+func handlerPasswd(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	// Whoever runs gator as a user without a password hasn't shown they
+	// are them, so only an admin can give them one.
+	err := confirmPassword(user, "Current password: ")
+	if err != nil {
+		return err
+	}
+
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+
+	err = setPassword(ctx, s, user, password)
+	if err != nil {
+		return fmt.Errorf("couldn't set password: %w", err)
+	}
+
+	fmt.Println("Password changed!")
+	return nil
+}
+
+func handlerTokenCreate(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v <name>", cmd.Name)
+	}
+	name := strings.TrimSpace(cmd.Args[0])
+	if name == "" {
+		return fmt.Errorf("token name can't be empty")
+	}
+	err := confirmPassword(user, "Password: ")
+	if err != nil {
+		return err
+	}
+
+	token, err := createAPIToken(ctx, s, user, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created token %v. It won't be shown again:\n\n%v\n\n", name, token)
+	fmt.Println("Send it to gator serve as: Authorization: Bearer <token>")
+	return nil
+}
+
+func handlerTokenList(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	tokens, err := s.db.GetApiTokensForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get tokens: %w", err)
+	}
+
+	if len(tokens) == 0 {
+		fmt.Println("No API tokens.")
+		return nil
+	}
+
+	fmt.Printf("%-24s %-17s %-17s\n", "Name", "Created", "Last Used")
+	for _, token := range tokens {
+		lastUsed := "never"
+		if token.LastUsedAt.Valid {
+			lastUsed = token.LastUsedAt.Time.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-24s %-17s %-17s\n", token.Name, token.CreatedAt.Format("2006-01-02 15:04"), lastUsed)
+	}
+	return nil
+}
+
+func handlerTokenRevoke(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v <name>", cmd.Name)
+	}
+
+	deleted, err := s.db.DeleteApiToken(ctx, database.DeleteApiTokenParams{
+		UserID: user.ID,
+		Name:   cmd.Args[0],
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't revoke token: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("no token named %v", cmd.Args[0])
+	}
+
+	fmt.Println("Token revoked!")
+	return nil
+}
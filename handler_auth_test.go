@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPasswd(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	passwd := middlewareLoggedIn(handlerPasswd)
+
+	withStdin(t, "wrong password", "new password")
+	err := runCommand(s, passwd, "passwd")
+	if err == nil {
+		t.Error("changed the password without the current one")
+	}
+
+	withStdin(t, testPassword, "new password")
+	err = runCommand(s, passwd, "passwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.db.GetUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkPassword(user, "new password") != nil || checkPassword(user, testPassword) == nil {
+		t.Error("passwd didn't replace the password")
+	}
+}
+
+func TestTokenCreateAsksForPassword(t *testing.T) {
+	s := newTestState(t)
+	alice := registerUser(t, s, "alice")
+	tokenCreate := middlewareLoggedIn(handlerTokenCreate)
+
+	withStdin(t, "wrong password")
+	err := runCommand(s, tokenCreate, "token create", "laptop")
+	if err == nil {
+		t.Error("created a token without the password")
+	}
+
+	withStdin(t, testPassword)
+	err = runCommand(s, tokenCreate, "token create", "laptop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := s.db.GetApiTokensForUser(context.Background(), alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].Name != "laptop" {
+		t.Errorf("alice has tokens %+v, want laptop", tokens)
+	}
+}
+
+func TestServerLoginRefusesDatabaseCommands(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	registerUser(t, s, "bob")
+	err := s.cfg.SetServerUser("https://gator.example.com", "gator_token", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// alice on the server is an admin here, but nobody checked her password
+	// against this database.
+	err = runCommand(s, middlewareAdmin(handlerUserRole), "user role", "bob", roleAdmin)
+	if err == nil || !strings.Contains(err.Error(), "gator login") {
+		t.Errorf("user role after login --server returned %v, want to log in to the database", err)
+	}
+	err = runCommand(s, middlewareLoggedIn(handlerAddFeed), "addfeed", "Blog", "https://blog.example/feed")
+	if err == nil {
+		t.Error("addfeed ran against the database after login --server")
+	}
+	err = runCommand(s, handlerAgg, "agg", "1h", "--prune-every", "1h")
+	if err == nil {
+		t.Error("agg pruned with the server user's name")
+	}
+}
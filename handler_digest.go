@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+	"github.com/inscrutabletaco/gator/internal/mailer"
+)
+
+type digestFeed struct {
+	Name  string
+	Posts []digestPost
+}
+
+type digestPost struct {
+	database.GetDigestPostsForUserRow
+	Highlight bool
+}
+
+var digestHTML = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<h1>New posts for {{.User}}</h1>
+{{range .Feeds}}
+<h2>{{.Name}}</h2>
+<ul>
+{{range .Posts}}<li>{{if .Highlight}}<strong>&#9733; <a href="{{.Url}}">{{.Title}}</a></strong>{{else}}<a href="{{.Url}}">{{.Title}}</a>{{end}}{{if .PublishedAt.Valid}} <small>{{.PublishedAt.Time.Format "2006-01-02 15:04"}}</small>{{end}}</li>
+{{end}}</ul>
+{{end}}
+</body>
+</html>
+`))
+
+func handlerEmail(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v <address>", cmd.Name)
+	}
+
+	address := strings.TrimSpace(cmd.Args[0])
+	_, err := mail.ParseAddress(address)
+	if err != nil {
+		return fmt.Errorf("invalid email address %q: %w", address, err)
+	}
+
+	err = s.db.SetUserEmail(ctx, database.SetUserEmailParams{
+		ID:    user.ID,
+		Email: sql.NullString{String: address, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't set email: %w", err)
+	}
+
+	fmt.Printf("Digests for %v will be sent to %v\n", user.Name, address)
+	return nil
+}
+
+func handlerMailDigest(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "print the digests instead of sending them")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--dry-run]", cmd.Name)
+	}
+
+	return sendDigests(ctx, s, *dryRun)
+}
+
+// sendDigests emails every user with an address the posts added to their
+// feeds since their previous digest, or in the last day for a first digest.
+func sendDigests(ctx context.Context, s *state, dryRun bool) error {
+	if s.cfg.SMTP == nil && !dryRun {
+		return errors.New("smtp is not configured, add an \"smtp\" section to your config")
+	}
+
+	users, err := s.db.GetDigestRecipients(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get digest recipients: %w", err)
+	}
+
+	failed := 0
+	for _, user := range users {
+		posts, err := s.db.GetDigestPostsForUser(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("couldn't get posts for %v: %w", user.Name, err)
+		}
+		if len(posts) == 0 {
+			fmt.Printf("No new posts for %v\n", user.Name)
+			continue
+		}
+
+		rules, err := loadPostRules(ctx, s, user.ID)
+		if err != nil {
+			return err
+		}
+		var shown []digestPost
+		for _, post := range posts {
+			verdict := rules.apply(post.FeedID, post.Title, post.Url, post.Description, post.Author)
+			if verdict.Mute {
+				continue
+			}
+			if verdict.MarkRead {
+				// Posts marked read by a rule are left out like muted ones,
+				// except a dry run shouldn't change anything.
+				if !dryRun {
+					err = s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: post.ID})
+					if err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			shown = append(shown, digestPost{GetDigestPostsForUserRow: post, Highlight: verdict.Highlight})
+		}
+
+		if len(shown) == 0 {
+			fmt.Printf("No new posts for %v after applying rules\n", user.Name)
+			if !dryRun {
+				err = recordDigest(ctx, s, user, posts)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		from := ""
+		if s.cfg.SMTP != nil {
+			from = s.cfg.SMTP.From
+		}
+		msg, err := buildDigest(user, shown, from)
+		if err != nil {
+			return err
+		}
+
+		if dryRun {
+			fmt.Printf("To: %v\nSubject: %v\n\n%v\n", msg.To, msg.Subject, msg.Text)
+			continue
+		}
+
+		err = mailer.Send(ctx, *s.cfg.SMTP, msg)
+		if err != nil && ctx.Err() != nil {
+			return fmt.Errorf("stopped sending digests: %w", err)
+		}
+		if err != nil {
+			log.Printf("Failed to send digest to %v: %v", user.Name, err)
+			failed++
+			continue
+		}
+
+		// The digest is out, so record it even if gator is stopping, or it
+		// would be sent again.
+		err = recordDigest(context.WithoutCancel(ctx), s, user, posts)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Sent digest of %d posts to %v\n", len(shown), user.Email.String)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("couldn't send %d of %d digests", failed, len(users))
+	}
+	return nil
+}
+
+// recordDigest moves the user's digest past posts, including any that rules
+// kept out of it.
+func recordDigest(ctx context.Context, s *state, user database.User, posts []database.GetDigestPostsForUserRow) error {
+	// Posts are listed newest first within each feed, so find the newest
+	// overall to know where the next digest should pick up.
+	latest := posts[0].CreatedAt
+	for _, post := range posts {
+		if post.CreatedAt.After(latest) {
+			latest = post.CreatedAt
+		}
+	}
+	err := s.db.SetLastDigestAt(ctx, database.SetLastDigestAtParams{
+		ID:           user.ID,
+		LastDigestAt: sql.NullTime{Time: latest, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't record digest for %v: %w", user.Name, err)
+	}
+	return nil
+}
+
+func buildDigest(user database.User, posts []digestPost, from string) (mailer.Message, error) {
+	var feeds []digestFeed
+	for _, post := range posts {
+		if len(feeds) == 0 || feeds[len(feeds)-1].Name != post.FeedName {
+			feeds = append(feeds, digestFeed{Name: post.FeedName})
+		}
+		feeds[len(feeds)-1].Posts = append(feeds[len(feeds)-1].Posts, post)
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "New posts for %s\n", user.Name)
+	for _, feed := range feeds {
+		fmt.Fprintf(&text, "\n%s\n", feed.Name)
+		for _, post := range feed.Posts {
+			marker := "-"
+			if post.Highlight {
+				marker = "*"
+			}
+			fmt.Fprintf(&text, "  %s %s\n    %s\n", marker, post.Title, post.Url)
+		}
+	}
+
+	var html bytes.Buffer
+	err := digestHTML.Execute(&html, struct {
+		User  string
+		Feeds []digestFeed
+	}{user.Name, feeds})
+	if err != nil {
+		return mailer.Message{}, err
+	}
+
+	return mailer.Message{
+		From:    from,
+		To:      user.Email.String,
+		Subject: fmt.Sprintf("gator digest: %d new posts", len(posts)),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// mailDigestsDaily sends digests every day at hour:minute local time until
+// ctx is done.
+func mailDigestsDaily(ctx context.Context, s *state, hour, minute int) {
+	for {
+		now := time.Now()
+		next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+		if !next.After(now) {
+			next = next.AddDate(0, 0, 1)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		err := sendDigests(ctx, s, false)
+		if err != nil && ctx.Err() == nil {
+			fmt.Println("Encountered an error sending digests:", err)
+		}
+	}
+}
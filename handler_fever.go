@@ -0,0 +1,520 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// The Fever API is documented at https://feedafever.com/api. Clients send the
+// requested sections as bare query parameters (e.g. "?api&items&since_id=10")
+// and authenticate with api_key = md5("<username>:<password>").
+
+const (
+	feverAPIVersion = 3
+	feverItemsLimit = 50
+)
+
+type feverFeed struct {
+	ID                int64  `json:"id"`
+	FaviconID         int64  `json:"favicon_id"`
+	Title             string `json:"title"`
+	Url               string `json:"url"`
+	SiteUrl           string `json:"site_url"`
+	IsSpark           int    `json:"is_spark"`
+	LastUpdatedOnTime int64  `json:"last_updated_on_time"`
+}
+
+type feverItem struct {
+	ID            int64  `json:"id"`
+	FeedID        int64  `json:"feed_id"`
+	Title         string `json:"title"`
+	Author        string `json:"author"`
+	HTML          string `json:"html"`
+	Url           string `json:"url"`
+	IsSaved       int    `json:"is_saved"`
+	IsRead        int    `json:"is_read"`
+	CreatedOnTime int64  `json:"created_on_time"`
+}
+
+type feverGroup struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+type feverFeedsGroup struct {
+	GroupID int64  `json:"group_id"`
+	FeedIDs string `json:"feed_ids"`
+}
+
+func feverApiKey(userName, password string) string {
+	sum := md5.Sum([]byte(userName + ":" + password))
+	return hex.EncodeToString(sum[:])
+}
+
+func handlerFeverPassword(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	// The password is prompted for so it doesn't end up in shell history.
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+
+	err = s.db.SetFeverApiKey(ctx, database.SetFeverApiKeyParams{
+		ID:          user.ID,
+		FeverApiKey: sql.NullString{String: feverApiKey(user.Name, password), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't set fever password: %w", err)
+	}
+
+	fmt.Printf("Fever password set! Sign in to your client as %v\n", user.Name)
+	return nil
+}
+
+func feverHandler(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseMultipartForm(1 << 20)
+		if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			respondWithError(w, http.StatusBadRequest, "couldn't parse form", err)
+			return
+		}
+
+		resp := map[string]interface{}{
+			"api_version": feverAPIVersion,
+			"auth":        0,
+		}
+
+		apiKey := strings.ToLower(r.FormValue("api_key"))
+		if apiKey == "" {
+			respondWithJSON(w, http.StatusOK, resp)
+			return
+		}
+
+		user, err := s.db.GetUserByFeverApiKey(r.Context(), sql.NullString{String: apiKey, Valid: true})
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithJSON(w, http.StatusOK, resp)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't look up user", err)
+			return
+		}
+		resp["auth"] = 1
+
+		err = feverRespond(r.Context(), s, user, r.Form, resp)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't build response", err)
+			return
+		}
+
+		respondWithJSON(w, http.StatusOK, resp)
+	}
+}
+
+func feverRespond(ctx context.Context, s *state, user database.User, form url.Values, resp map[string]interface{}) error {
+	feeds, err := s.db.GetFollowedFeeds(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	var lastRefreshed int64
+	for _, feed := range feeds {
+		if feed.LastFetchedAt.Valid && feed.LastFetchedAt.Time.Unix() > lastRefreshed {
+			lastRefreshed = feed.LastFetchedAt.Time.Unix()
+		}
+	}
+	resp["last_refreshed_on_time"] = lastRefreshed
+
+	if form.Has("mark") {
+		err = feverMark(ctx, s, user, feeds, form)
+		if err != nil {
+			return err
+		}
+	}
+
+	if form.Has("groups") || form.Has("feeds") {
+		feedsGroups, err := feverFeedsGroups(ctx, s, user)
+		if err != nil {
+			return err
+		}
+		resp["feeds_groups"] = feedsGroups
+	}
+
+	if form.Has("groups") {
+		tags, err := s.db.GetTagsForUser(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		groups := make([]feverGroup, 0, len(tags))
+		for _, tag := range tags {
+			groups = append(groups, feverGroup{ID: tag.ShortID, Title: tag.Name})
+		}
+		resp["groups"] = groups
+	}
+
+	if form.Has("feeds") {
+		items := make([]feverFeed, 0, len(feeds))
+		for _, feed := range feeds {
+			var lastUpdated int64
+			if feed.LastFetchedAt.Valid {
+				lastUpdated = feed.LastFetchedAt.Time.Unix()
+			}
+			title := feed.Name
+			if feed.DisplayName.Valid {
+				title = feed.DisplayName.String
+			}
+			siteUrl := feed.Url
+			if feed.SiteUrl.Valid {
+				siteUrl = feed.SiteUrl.String
+			}
+			items = append(items, feverFeed{
+				ID:                feed.ShortID,
+				Title:             title,
+				Url:               feed.Url,
+				SiteUrl:           siteUrl,
+				LastUpdatedOnTime: lastUpdated,
+			})
+		}
+		resp["feeds"] = items
+	}
+
+	if form.Has("favicons") {
+		resp["favicons"] = []struct{}{}
+	}
+
+	if form.Has("links") {
+		resp["links"] = []struct{}{}
+	}
+
+	if form.Has("items") {
+		items, err := feverItems(ctx, s, user, form)
+		if err != nil {
+			return err
+		}
+		total, err := s.db.CountPostsForUser(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		resp["items"] = items
+		resp["total_items"] = total
+	}
+
+	if form.Has("unread_item_ids") {
+		ids, err := feverUnreadIDs(ctx, s, user)
+		if err != nil {
+			return err
+		}
+		resp["unread_item_ids"] = joinIDs(ids)
+	}
+
+	if form.Has("saved_item_ids") {
+		ids, err := s.db.GetStarredPostShortIDs(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		resp["saved_item_ids"] = joinIDs(ids)
+	}
+
+	return nil
+}
+
+// feverFeedsGroups maps each of the user's tags, which Fever calls groups,
+// to the feeds carrying it.
+func feverFeedsGroups(ctx context.Context, s *state, user database.User) ([]feverFeedsGroup, error) {
+	rows, err := s.db.GetFeedTagsForUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	feedsGroups := []feverFeedsGroup{}
+	var feedIDs []int64
+	for i, row := range rows {
+		feedIDs = append(feedIDs, row.FeedShortID)
+		if i == len(rows)-1 || rows[i+1].TagShortID != row.TagShortID {
+			feedsGroups = append(feedsGroups, feverFeedsGroup{
+				GroupID: row.TagShortID,
+				FeedIDs: joinIDs(feedIDs),
+			})
+			feedIDs = nil
+		}
+	}
+
+	return feedsGroups, nil
+}
+
+func feverItems(ctx context.Context, s *state, user database.User, form url.Values) ([]feverItem, error) {
+	rules, err := loadPostRules(ctx, s, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if form.Get("with_ids") != "" {
+		ids, err := splitIDs(form.Get("with_ids"))
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) > feverItemsLimit {
+			ids = ids[:feverItemsLimit]
+		}
+		results, err := s.db.GetPostItemsByShortIDs(ctx, database.GetPostItemsByShortIDsParams{
+			UserID:   user.ID,
+			ShortIds: ids,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var rows []database.GetPostItemsSinceRow
+		for _, row := range results {
+			rows = append(rows, database.GetPostItemsSinceRow(row))
+		}
+		return feverItemsFromRows(ctx, s, user, rules, rows)
+	}
+
+	var maxID, sinceID int64
+	if form.Get("max_id") != "" {
+		maxID, err = strconv.ParseInt(form.Get("max_id"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	} else if form.Get("since_id") != "" {
+		sinceID, err = strconv.ParseInt(form.Get("since_id"), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Clients page until they get no items back, so skip past pages that
+	// mute rules empty completely.
+	for {
+		var rows []database.GetPostItemsSinceRow
+		if maxID != 0 {
+			results, err := s.db.GetPostItemsBefore(ctx, database.GetPostItemsBeforeParams{
+				UserID:  user.ID,
+				ShortID: maxID,
+				Limit:   feverItemsLimit,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, row := range results {
+				rows = append(rows, database.GetPostItemsSinceRow(row))
+			}
+		} else {
+			rows, err = s.db.GetPostItemsSince(ctx, database.GetPostItemsSinceParams{
+				UserID:  user.ID,
+				ShortID: sinceID,
+				Limit:   feverItemsLimit,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		items, err := feverItemsFromRows(ctx, s, user, rules, rows)
+		if err != nil || len(items) > 0 || len(rows) < feverItemsLimit {
+			return items, err
+		}
+
+		if maxID != 0 {
+			maxID = rows[len(rows)-1].ShortID
+		} else {
+			sinceID = rows[len(rows)-1].ShortID
+		}
+	}
+}
+
+// feverItemsFromRows applies the user's rules to rows, leaving out muted
+// posts and marking posts read where a rule says to.
+func feverItemsFromRows(ctx context.Context, s *state, user database.User, rules postRules, rows []database.GetPostItemsSinceRow) ([]feverItem, error) {
+	items := make([]feverItem, 0, len(rows))
+	for _, row := range rows {
+		verdict := rules.apply(row.FeedID, row.Title, row.Url, row.Description, row.Author)
+		if verdict.Mute {
+			continue
+		}
+		if verdict.MarkRead && !row.IsRead {
+			err := s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: row.ID})
+			if err != nil {
+				return nil, err
+			}
+			row.IsRead = true
+		}
+
+		createdOn := row.CreatedAt
+		if row.PublishedAt.Valid {
+			createdOn = row.PublishedAt.Time
+		}
+		html := row.Description.String
+		if row.Content.Valid {
+			html = row.Content.String
+		}
+		items = append(items, feverItem{
+			ID:            row.ShortID,
+			FeedID:        row.FeedShortID,
+			Title:         row.Title,
+			Author:        row.Author.String,
+			HTML:          html,
+			Url:           row.Url,
+			IsSaved:       feverBool(row.IsStarred),
+			IsRead:        feverBool(row.IsRead),
+			CreatedOnTime: createdOn.Unix(),
+		})
+	}
+
+	return items, nil
+}
+
+// feverUnreadIDs lists the user's unread posts, leaving out muted ones so
+// clients don't count them, and marking read the ones rules say to.
+// Fever has no notion of highlighting, so highlight rules don't apply here.
+func feverUnreadIDs(ctx context.Context, s *state, user database.User) ([]int64, error) {
+	rules, err := loadPostRules(ctx, s, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without rules that hide posts, their short IDs are all that's needed.
+	if !slices.ContainsFunc(rules, func(rule postRule) bool { return rule.Action == "mute" || rule.Action == "mark-read" }) {
+		return s.db.GetUnreadPostShortIDs(ctx, user.ID)
+	}
+
+	posts, err := s.db.GetUnreadPostsForUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+	for _, post := range posts {
+		verdict := rules.apply(post.FeedID, post.Title, post.Url, post.Description, post.Author)
+		if verdict.Mute {
+			continue
+		}
+		if verdict.MarkRead {
+			err = s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: post.ID})
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		ids = append(ids, post.ShortID)
+	}
+
+	return ids, nil
+}
+
+func feverMark(ctx context.Context, s *state, user database.User, feeds []database.GetFollowedFeedsRow, form url.Values) error {
+	id, err := strconv.ParseInt(form.Get("id"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid id %q: %w", form.Get("id"), err)
+	}
+
+	before := time.Now().UTC()
+	if form.Get("before") != "" {
+		unix, err := strconv.ParseInt(form.Get("before"), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid before %q: %w", form.Get("before"), err)
+		}
+		before = time.Unix(unix, 0).UTC()
+	}
+
+	switch form.Get("mark") {
+	case "item":
+		// Only posts of feeds the user follows, like the items Fever lists.
+		post, err := s.db.GetPostByShortID(ctx, database.GetPostByShortIDParams{UserID: user.ID, ShortID: id})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch form.Get("as") {
+		case "read":
+			return s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: post.ID})
+		case "unread":
+			return s.db.MarkPostUnread(ctx, database.MarkPostUnreadParams{UserID: user.ID, PostID: post.ID})
+		case "saved":
+			return s.db.StarPost(ctx, database.StarPostParams{UserID: user.ID, PostID: post.ID})
+		case "unsaved":
+			return s.db.UnstarPost(ctx, database.UnstarPostParams{UserID: user.ID, PostID: post.ID})
+		}
+	case "feed":
+		if form.Get("as") != "read" {
+			return nil
+		}
+		for _, feed := range feeds {
+			if feed.ShortID == id {
+				return s.db.MarkFeedReadBefore(ctx, database.MarkFeedReadBeforeParams{
+					UserID:    user.ID,
+					FeedID:    feed.ID,
+					CreatedAt: before,
+				})
+			}
+		}
+	case "group":
+		if form.Get("as") != "read" {
+			return nil
+		}
+		// Group 0 is Fever's "Kindling" super group containing every feed;
+		// the others are the user's tags.
+		if id == 0 {
+			return s.db.MarkAllReadBefore(ctx, database.MarkAllReadBeforeParams{
+				UserID:    user.ID,
+				CreatedAt: before,
+			})
+		}
+		return s.db.MarkTagReadBefore(ctx, database.MarkTagReadBeforeParams{
+			UserID:    user.ID,
+			ShortID:   id,
+			CreatedAt: before,
+		})
+	}
+
+	return nil
+}
+
+func feverBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitIDs(s string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id %q: %w", part, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
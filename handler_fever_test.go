@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// feverRequest posts form to the Fever API and decodes the response.
+func feverRequest(t *testing.T, s *state, query string, form url.Values) map[string]interface{} {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/fever/?"+query, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	feverHandler(s)(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("fever answered %v: %v", w.Code, w.Body)
+	}
+
+	var resp map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestFeverPassword(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	feverPassword := middlewareLoggedIn(handlerFeverPassword)
+
+	err := runCommand(s, feverPassword, "fever-password", "secret password")
+	if err == nil {
+		t.Error("fever-password took the password as an argument")
+	}
+	withStdin(t, "short")
+	err = runCommand(s, feverPassword, "fever-password")
+	if err == nil {
+		t.Error("set a short fever password")
+	}
+
+	withStdin(t, "fever password")
+	err = runCommand(s, feverPassword, "fever-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := feverRequest(t, s, "api", url.Values{"api_key": {feverApiKey("alice", "fever password")}})
+	if resp["auth"] != 1.0 {
+		t.Errorf("fever didn't accept the new password: %v", resp)
+	}
+	resp = feverRequest(t, s, "api", url.Values{"api_key": {feverApiKey("alice", testPassword)}})
+	if resp["auth"] != 0.0 {
+		t.Errorf("fever accepted the login password: %v", resp)
+	}
+}
+
+func TestFeverFeeds(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	withStdin(t, testPassword)
+	err := runCommand(s, middlewareLoggedIn(handlerFeverPassword), "fever-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blog := addFeed(t, s, "alice", "Blog", "https://alice.example/feed")
+	addFeed(t, s, "alice", "News", "https://news.example/feed")
+	err = s.db.UpdateFeedMetadata(context.Background(), database.UpdateFeedMetadataParams{
+		ID:      blog.ID,
+		SiteUrl: sql.NullString{String: "https://alice.example/", Valid: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := feverRequest(t, s, "api&feeds", url.Values{"api_key": {feverApiKey("alice", testPassword)}})
+	feeds, ok := resp["feeds"].([]interface{})
+	if !ok || len(feeds) != 2 {
+		t.Fatalf("fever listed feeds %v, want 2", resp["feeds"])
+	}
+	siteUrls := map[string]interface{}{}
+	for _, feed := range feeds {
+		feed := feed.(map[string]interface{})
+		siteUrls[feed["title"].(string)] = feed["site_url"]
+	}
+	if siteUrls["Blog"] != "https://alice.example/" {
+		t.Errorf("Blog has site_url %v, want the one the feed gives", siteUrls["Blog"])
+	}
+	if siteUrls["News"] != "https://news.example/feed" {
+		t.Errorf("News has site_url %v, want the feed's url", siteUrls["News"])
+	}
+}
+
+func TestFeverMarkItemOnlyFollowedPosts(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := registerUser(t, s, "alice")
+	bob := registerUser(t, s, "bob")
+	feed := addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+	addPost(t, s, feed, "alice-post")
+	loginAs(t, s, "bob")
+	withStdin(t, testPassword)
+	err := runCommand(s, middlewareLoggedIn(handlerFeverPassword), "fever-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts, err := s.db.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: alice.ID, Limit: 1})
+	if err != nil || len(posts) != 1 {
+		t.Fatalf("got posts %+v, %v", posts, err)
+	}
+	id := strconv.FormatInt(posts[0].ShortID, 10)
+	for _, as := range []string{"read", "saved"} {
+		feverRequest(t, s, "api", url.Values{"api_key": {feverApiKey("bob", testPassword)}, "mark": {"item"}, "as": {as}, "id": {id}})
+	}
+
+	starred, err := s.db.GetStarredPostShortIDs(ctx, bob.ID)
+	if err != nil || len(starred) != 0 {
+		t.Errorf("bob starred %v, %v from a feed bob doesn't follow", starred, err)
+	}
+	// Were the post marked read, following the feed would hide it.
+	err = runCommand(s, middlewareLoggedIn(handlerFollow), "follow", feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unread, err := s.db.GetUnreadPostsForUser(ctx, bob.ID)
+	if err != nil || len(unread) != 1 {
+		t.Errorf("bob has unread posts %+v, %v, want alice-post", unread, err)
+	}
+}
+
+func TestFeverUnreadItemIDs(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := registerUser(t, s, "alice")
+	withStdin(t, testPassword)
+	err := runCommand(s, middlewareLoggedIn(handlerFeverPassword), "fever-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+	addPost(t, s, feed, "cats")
+	addPost(t, s, feed, "dogs")
+	apiKey := feverApiKey("alice", testPassword)
+
+	resp := feverRequest(t, s, "api&unread_item_ids", url.Values{"api_key": {apiKey}})
+	if resp["unread_item_ids"] != "1,2" {
+		t.Errorf("unread items are %v, want 1,2", resp["unread_item_ids"])
+	}
+
+	_, err = s.db.CreatePostRule(ctx, database.CreatePostRuleParams{UserID: alice.ID, Kind: "keyword", Pattern: "dogs", Action: "mute"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = feverRequest(t, s, "api&unread_item_ids", url.Values{"api_key": {apiKey}})
+	if resp["unread_item_ids"] != "1" {
+		t.Errorf("unread items are %v, want the unmuted 1", resp["unread_item_ids"])
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+func newMigrationProvider(s *state) (*goose.Provider, error) {
+	return goose.NewProvider(s.dialect, s.sqlDB, s.migrations)
+}
+
+// schemaPingTimeout bounds how long checkSchema waits for the database, so
+// an unreachable server doesn't hang commands that don't need it.
+const schemaPingTimeout = 3 * time.Second
+
+// checkSchema returns an error if the database is missing migrations this
+// gator needs. A database that can't be reached passes, so commands that
+// don't use it still run; the ones that do fail on their own.
+func checkSchema(ctx context.Context, s *state) error {
+	pingCtx, cancel := context.WithTimeout(ctx, schemaPingTimeout)
+	defer cancel()
+	if s.sqlDB.PingContext(pingCtx) != nil {
+		return nil
+	}
+	provider, err := newMigrationProvider(s)
+	if err != nil {
+		return err
+	}
+
+	current, target, err := provider.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't check the database schema: %w", err)
+	}
+	if current < target {
+		return fmt.Errorf("the database schema is at version %d but gator needs %d, run: gator migrate up", current, target)
+	}
+	return nil
+}
+
+func handlerMigrateUp(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	to := fs.Int64("to", 0, "only migrate up to this version")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--to <version>]", cmd.Name)
+	}
+
+	provider, err := newMigrationProvider(s)
+	if err != nil {
+		return err
+	}
+
+	var results []*goose.MigrationResult
+	if *to > 0 {
+		results, err = provider.UpTo(ctx, *to)
+	} else {
+		results, err = provider.Up(ctx)
+	}
+	printMigrationResults(results)
+	if err != nil {
+		return fmt.Errorf("couldn't migrate: %w", err)
+	}
+	if len(results) == 0 {
+		fmt.Println("The database schema is up to date.")
+	}
+	return nil
+}
+
+func handlerMigrateDown(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	to := fs.String("to", "", "roll back every migration after this version, 0 for all of them")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--to <version>]", cmd.Name)
+	}
+
+	provider, err := newMigrationProvider(s)
+	if err != nil {
+		return err
+	}
+
+	var results []*goose.MigrationResult
+	if *to != "" {
+		version, err := strconv.ParseInt(*to, 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("version must be a non-negative integer, got: %v", *to)
+		}
+		results, err = provider.DownTo(ctx, version)
+		printMigrationResults(results)
+		if err != nil {
+			return fmt.Errorf("couldn't roll back: %w", err)
+		}
+	} else {
+		result, err := provider.Down(ctx)
+		if result != nil {
+			results = append(results, result)
+		}
+		printMigrationResults(results)
+		if err != nil {
+			return fmt.Errorf("couldn't roll back: %w", err)
+		}
+	}
+	if len(results) == 0 {
+		fmt.Println("Nothing to roll back.")
+	}
+	return nil
+}
+
+func handlerMigrateStatus(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	provider, err := newMigrationProvider(s)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := provider.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get migration status: %w", err)
+	}
+
+	fmt.Printf("%-17s %s\n", "Applied At", "Migration")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.State == goose.StateApplied {
+			appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-17s %s\n", appliedAt, status.Source.Path)
+	}
+	return nil
+}
+
+func printMigrationResults(results []*goose.MigrationResult) {
+	for _, result := range results {
+		if result.Error != nil {
+			fmt.Printf("FAILED %-5s %v: %v\n", result.Direction, result.Source.Path, result.Error)
+			continue
+		}
+		fmt.Printf("OK     %-5s %v (%v)\n", result.Direction, result.Source.Path, result.Duration.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+func handlerExport(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) > 1 {
+		return fmt.Errorf("usage: %v [file]", cmd.Name)
+	}
+
+	feedFollows, err := s.db.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{
+		UserID: user.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if len(cmd.Args) == 1 {
+		file, err := os.Create(cmd.Args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return writeOPML(out, user, feedFollows)
+}
+
+// writeOPML writes the feeds user follows to out, with tags as categories.
+func writeOPML(out io.Writer, user database.User, feedFollows []database.GetFeedFollowsForUserRow) error {
+	var opml OPML
+	opml.Version = "2.0"
+	opml.Head.Title = fmt.Sprintf("gator feeds for %s", user.Name)
+	opml.Head.DateCreated = time.Now().UTC().Format(time.RFC1123Z)
+	for _, row := range feedFollows {
+		opml.Body.Outlines = append(opml.Body.Outlines, OPMLOutline{
+			Text:     row.FeedName,
+			Title:    row.FeedName,
+			Type:     "rss",
+			XMLUrl:   row.FeedUrl,
+			Category: row.Tags,
+		})
+	}
+
+	_, err := io.WriteString(out, xml.Header)
+	if err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	err = encoder.Encode(opml)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, "\n")
+	return err
+}
+
+func handlerImport(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v <file>", cmd.Name)
+	}
+
+	data, err := os.ReadFile(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	var opml OPML
+	err = xml.Unmarshal(data, &opml)
+	if err != nil {
+		return fmt.Errorf("couldn't parse OPML: %w", err)
+	}
+
+	imported := 0
+	for _, outline := range flattenOutlines(opml.Body.Outlines, nil) {
+		err := importOutline(ctx, s, user, outline.OPMLOutline, outline.tags)
+		if err != nil {
+			fmt.Printf("Skipping %v: %v\n", outline.XMLUrl, err)
+			continue
+		}
+		imported++
+	}
+
+	fmt.Printf("Imported %d feeds\n", imported)
+	return nil
+}
+
+type taggedOutline struct {
+	OPMLOutline
+	tags []string
+}
+
+// flattenOutlines returns the feed outlines nested anywhere below outlines.
+// Feeds are tagged with their categories and the names of the folders they
+// sit in, so files from readers that use folders instead of categories keep
+// their organization.
+func flattenOutlines(outlines []OPMLOutline, folders []string) []taggedOutline {
+	var feeds []taggedOutline
+	for _, outline := range outlines {
+		if outline.XMLUrl == "" {
+			name := outline.Title
+			if name == "" {
+				name = outline.Text
+			}
+			nested := folders
+			if tag, err := normalizeTag(name); err == nil {
+				nested = append(append([]string{}, folders...), tag)
+			}
+			feeds = append(feeds, flattenOutlines(outline.Outlines, nested)...)
+			continue
+		}
+
+		tags := append([]string{}, folders...)
+		for _, category := range strings.Split(outline.Category, ",") {
+			// OPML categories may be slash delimited paths like "/work".
+			tag, err := normalizeTag(strings.Trim(category, "/ "))
+			if err == nil {
+				tags = append(tags, tag)
+			}
+		}
+		feeds = append(feeds, taggedOutline{OPMLOutline: outline, tags: tags})
+	}
+	return feeds
+}
+
+func importOutline(ctx context.Context, s *state, user database.User, outline OPMLOutline, tags []string) error {
+	feedURL, err := canonicalFeedURL(outline.XMLUrl)
+	if err != nil {
+		return err
+	}
+
+	feed, err := lookupFeed(ctx, s, feedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		name := outline.Title
+		if name == "" {
+			name = outline.Text
+		}
+		if name == "" {
+			name = outline.XMLUrl
+		}
+		feed, err = s.db.CreateFeed(ctx, database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			Name:      name,
+			Url:       feedURL,
+			UserID:    user.ID,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		UserID: user.ID,
+		FeedID: feed.ID,
+	})
+	if err != nil && !isDuplicateKey(err) {
+		return err
+	}
+
+	for _, tag := range tags {
+		_, err = tagFollow(ctx, s, user, feed, tag)
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Imported %v\n", feed.Name)
+	return nil
+}
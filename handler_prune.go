@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+const (
+	// pruneBatchSize bounds how many posts one DELETE removes, so pruning a
+	// large backlog doesn't hold locks on the posts table for long.
+	pruneBatchSize = 10000
+	// prunedPostMemory is how long the urls of pruned posts are remembered.
+	// Feeds rarely keep listing an item for longer than this.
+	prunedPostMemory = 90 * 24 * time.Hour
+)
+
+// globalRetention returns the configured retention limits, which apply to
+// feeds without their own.
+func globalRetention(s *state) (maxAge sql.NullInt32, maxPosts sql.NullInt32, err error) {
+	if s.cfg.Retention == nil {
+		return maxAge, maxPosts, nil
+	}
+	if s.cfg.Retention.MaxAge != "" {
+		seconds, err := parseWindowSeconds(s.cfg.Retention.MaxAge)
+		if err != nil {
+			return maxAge, maxPosts, fmt.Errorf("invalid retention max_age in config: %w", err)
+		}
+		maxAge = sql.NullInt32{Int32: seconds, Valid: true}
+	}
+	if s.cfg.Retention.MaxPostsPerFeed > math.MaxInt32 {
+		return maxAge, maxPosts, fmt.Errorf("invalid retention max_posts_per_feed in config: at most %d", math.MaxInt32)
+	}
+	if s.cfg.Retention.MaxPostsPerFeed > 0 {
+		maxPosts = sql.NullInt32{Int32: int32(s.cfg.Retention.MaxPostsPerFeed), Valid: true}
+	}
+	return maxAge, maxPosts, nil
+}
+
+func handlerPrune(ctx context.Context, s *state, cmd command, admin database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only count the posts that would be deleted")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--dry-run]", cmd.Name)
+	}
+
+	return prunePosts(ctx, s, *dryRun)
+}
+
+// prunePosts deletes posts past their feed's retention limits, keeping any
+// post a user has starred.
+func prunePosts(ctx context.Context, s *state, dryRun bool) error {
+	maxAge, maxPosts, err := globalRetention(s)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		count, err := s.db.CountPrunablePosts(ctx, database.CountPrunablePostsParams{
+			MaxAgeSeconds: maxAge,
+			MaxPosts:      maxPosts,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't count posts to prune: %w", err)
+		}
+		fmt.Printf("Would prune %d posts\n", count)
+		return nil
+	}
+
+	var total int64
+	for {
+		pruned, err := s.db.PrunePosts(ctx, database.PrunePostsParams{
+			MaxAgeSeconds: maxAge,
+			MaxPosts:      maxPosts,
+			Limit:         pruneBatchSize,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't prune posts: %w", err)
+		}
+		total += pruned
+		if pruned < pruneBatchSize {
+			break
+		}
+	}
+
+	err = s.db.DeletePrunedPostsBefore(ctx, time.Now().UTC().Add(-prunedPostMemory))
+	if err != nil {
+		return fmt.Errorf("couldn't forget old pruned posts: %w", err)
+	}
+
+	fmt.Printf("Pruned %d posts\n", total)
+	return nil
+}
+
+// pruneEvery prunes posts on interval until ctx is done.
+func pruneEvery(ctx context.Context, s *state, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := prunePosts(ctx, s, false)
+		if err != nil && ctx.Err() == nil {
+			fmt.Println("Encountered an error pruning posts:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func handlerFeedRetention(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	maxAge := fs.String("max-age", "", "prune posts older than this window, e.g. 30d")
+	maxPosts := fs.Int("max-posts", 0, "keep only this many of the newest posts")
+	reset := fs.Bool("clear", false, "go back to the retention set in the config")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %v <url> [--max-age <window>] [--max-posts <n>] [--clear]", cmd.Name)
+	}
+
+	feed, err := lookupFeed(ctx, s, args[0])
+	if err != nil {
+		return err
+	}
+
+	if *maxAge == "" && *maxPosts == 0 && !*reset {
+		fmt.Printf("Retention for %v:\n", feed.Name)
+		fmt.Printf("  max age:   %v\n", describeRetention(feed.RetentionMaxAgeSeconds, formatWindow))
+		fmt.Printf("  max posts: %v\n", describeRetention(feed.RetentionMaxPosts, func(n int32) string {
+			return strconv.Itoa(int(n))
+		}))
+		return nil
+	}
+	err = checkFeedOwner(user, feed)
+	if err != nil {
+		return err
+	}
+	if *reset && (*maxAge != "" || *maxPosts != 0) {
+		return fmt.Errorf("--clear can't be combined with other settings")
+	}
+	if *maxPosts < 0 || *maxPosts > math.MaxInt32 {
+		return fmt.Errorf("max posts must be a positive integer up to %d, got: %d", math.MaxInt32, *maxPosts)
+	}
+
+	params := database.SetFeedRetentionParams{
+		ID:                     feed.ID,
+		RetentionMaxAgeSeconds: feed.RetentionMaxAgeSeconds,
+		RetentionMaxPosts:      feed.RetentionMaxPosts,
+	}
+	if *reset {
+		params.RetentionMaxAgeSeconds = sql.NullInt32{}
+		params.RetentionMaxPosts = sql.NullInt32{}
+	}
+	if *maxAge != "" {
+		seconds, err := parseWindowSeconds(*maxAge)
+		if err != nil {
+			return err
+		}
+		params.RetentionMaxAgeSeconds = sql.NullInt32{Int32: seconds, Valid: true}
+	}
+	if *maxPosts > 0 {
+		params.RetentionMaxPosts = sql.NullInt32{Int32: int32(*maxPosts), Valid: true}
+	}
+
+	err = s.db.SetFeedRetention(ctx, params)
+	if err != nil {
+		return fmt.Errorf("couldn't set retention: %w", err)
+	}
+
+	fmt.Printf("Updated retention for %v\n", feed.Name)
+	return nil
+}
+
+func describeRetention(value sql.NullInt32, format func(int32) string) string {
+	if !value.Valid {
+		return "from config"
+	}
+	return format(value.Int32)
+}
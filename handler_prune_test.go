@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPruneIsAdminOnly(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	registerUser(t, s, "bob")
+	prune := middlewareAdmin(handlerPrune)
+
+	err := runCommand(s, prune, "prune", "--dry-run")
+	if err == nil {
+		t.Error("a member pruned posts")
+	}
+	err = runCommand(s, handlerAgg, "agg", "1h", "--prune-every", "1h")
+	if err == nil {
+		t.Error("agg pruned posts with a member's config")
+	}
+
+	loginAs(t, s, "alice")
+	err = runCommand(s, prune, "prune", "--dry-run")
+	if err != nil {
+		t.Errorf("an admin couldn't prune posts: %v", err)
+	}
+}
+
+func TestFeedRetentionRefusesWindowsThatDontFit(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	feed := addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+	retention := middlewareLoggedIn(handlerFeedRetention)
+
+	for _, args := range [][]string{
+		{"--max-age", "500ms"},
+		{"--max-age", "30000d"},
+		{"--max-posts", "3000000000"},
+	} {
+		err := runCommand(s, retention, "feed retention", append([]string{feed.Url}, args...)...)
+		if err == nil {
+			t.Errorf("feed retention %v succeeded", args)
+		}
+	}
+	feed, err := s.db.GetFeedByUrl(context.Background(), feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.RetentionMaxAgeSeconds.Valid || feed.RetentionMaxPosts.Valid {
+		t.Errorf("feed has retention %v, %v, want none", feed.RetentionMaxAgeSeconds, feed.RetentionMaxPosts)
+	}
+
+	err = runCommand(s, retention, "feed retention", feed.Url, "--max-age", "30d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err = s.db.GetFeedByUrl(context.Background(), feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.RetentionMaxAgeSeconds.Int32 != 30*24*60*60 {
+		t.Errorf("feed keeps posts for %v seconds, want 30 days", feed.RetentionMaxAgeSeconds.Int32)
+	}
+}
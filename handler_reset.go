@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/config"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// confirm asks a yes or no question, defaulting to no. It fails if stdin
+// ends before anything was typed, as it does when gator runs from a script,
+// rather than take that for an answer.
+func confirm(question string) (bool, error) {
+	fmt.Fprintf(os.Stderr, "%v [y/N] ", question)
+	answer, err := stdin.ReadString('\n')
+	if errors.Is(err, io.EOF) && answer == "" {
+		return false, errors.New("no answer on stdin, pass --yes to go ahead without being asked")
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+func handlerReset(ctx context.Context, s *state, cmd command, admin database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	posts := fs.Bool("posts", false, "only delete posts, which agg fetches again")
+	feeds := fs.Bool("feeds", false, "only delete feeds, with their posts and follows")
+	userName := fs.String("user", "", "only delete this user's follows, tags, read and starred posts, rules, views and webhooks")
+	yes := fs.Bool("yes", false, "don't ask for confirmation")
+	backupDir := fs.String("backup-dir", "", "export follows here before deleting them, instead of under $XDG_STATE_HOME/gator/backups")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %v [--posts | --feeds | --user <name>] [--yes] [--backup-dir <dir>]", cmd.Name)
+	}
+	scopes := 0
+	for _, set := range []bool{*posts, *feeds, *userName != ""} {
+		if set {
+			scopes++
+		}
+	}
+	if scopes > 1 {
+		return fmt.Errorf("--posts, --feeds and --user can't be used together")
+	}
+
+	// Work out what will be deleted, and whose follows to export first.
+	var users []database.User
+	var summary string
+	switch {
+	case *posts:
+		count, err := s.db.CountPosts(ctx)
+		if err != nil {
+			return err
+		}
+		summary = fmt.Sprintf("all %d posts", count)
+	case *userName != "":
+		user, err := s.db.GetUser(ctx, *userName)
+		if err != nil {
+			return fmt.Errorf("couldn't find user %v: %w", *userName, err)
+		}
+		counts, err := s.db.CountUserData(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		users = []database.User{user}
+		summary = fmt.Sprintf("%v's %d follows, %d read and %d starred posts, and their tags, rules, views and webhooks", user.Name, counts.Follows, counts.Reads, counts.Stars)
+	default:
+		users, err = s.db.GetUsers(ctx)
+		if err != nil {
+			return err
+		}
+		allFeeds, err := s.db.GetFeeds(ctx)
+		if err != nil {
+			return err
+		}
+		count, err := s.db.CountPosts(ctx)
+		if err != nil {
+			return err
+		}
+		summary = fmt.Sprintf("all %d feeds and %d posts, and everyone's follows", len(allFeeds), count)
+		if !*feeds {
+			summary = fmt.Sprintf("all %d users, %d feeds and %d posts", len(users), len(allFeeds), count)
+		}
+	}
+
+	if !*yes {
+		ok, err := confirm(fmt.Sprintf("This deletes %v. Continue?", summary))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Nothing was deleted.")
+			return nil
+		}
+	}
+
+	if len(users) > 0 {
+		dir := *backupDir
+		if dir == "" {
+			stateDir, err := config.StateDir()
+			if err != nil {
+				return fmt.Errorf("couldn't find where to back up follows, pass --backup-dir: %w", err)
+			}
+			dir = filepath.Join(stateDir, "backups", time.Now().UTC().Format("20060102-150405"))
+		}
+		err = backupFollows(ctx, s, dir, users)
+		if err != nil {
+			return fmt.Errorf("couldn't back up follows, nothing was deleted: %w", err)
+		}
+		fmt.Printf("Exported the follows of %d users to %v\n", len(users), dir)
+	}
+
+	switch {
+	case *posts:
+		deleted, err := s.db.DeletePosts(ctx)
+		if err != nil {
+			return fmt.Errorf("couldn't delete posts: %w", err)
+		}
+		fmt.Printf("Deleted %d posts\n", deleted)
+	case *userName != "":
+		deleted, err := deleteUserData(ctx, s, users[0].ID)
+		if err != nil {
+			return fmt.Errorf("couldn't reset %v: %w", users[0].Name, err)
+		}
+		fmt.Printf("Deleted %v's %d follows, %d tags, %d read and %d starred posts, %d rules, %d views and %d webhooks\n",
+			users[0].Name, deleted.Follows, deleted.Tags, deleted.Reads, deleted.Stars, deleted.Rules, deleted.Views, deleted.Webhooks)
+	default:
+		deletedFeeds, err := s.db.DeleteFeeds(ctx)
+		if err != nil {
+			return fmt.Errorf("couldn't delete feeds: %w", err)
+		}
+		if *feeds {
+			fmt.Printf("Deleted %d feeds with their posts and follows\n", deletedFeeds)
+			return nil
+		}
+		deletedUsers, err := s.db.DeleteUsers(ctx)
+		if err != nil {
+			return fmt.Errorf("couldn't delete users: %w", err)
+		}
+		err = s.db.DeletePrunedPosts(ctx)
+		if err != nil {
+			return fmt.Errorf("couldn't forget pruned posts: %w", err)
+		}
+		fmt.Printf("Deleted %d users and %d feeds with their posts\n", deletedUsers, deletedFeeds)
+	}
+
+	return nil
+}
+
+// deleteUserData deletes what user has besides their account and feeds, and
+// returns how much of each it deleted. Each delete can be rerun, so a reset
+// that fails halfway is finished by the next.
+func deleteUserData(ctx context.Context, s *state, userID uuid.UUID) (database.CountUserDataRow, error) {
+	var deleted database.CountUserDataRow
+	for _, del := range []struct {
+		count  *int64
+		delete func(context.Context, uuid.UUID) (int64, error)
+	}{
+		{&deleted.Follows, s.db.DeleteUserFollows},
+		{&deleted.Tags, s.db.DeleteUserTags},
+		{&deleted.Reads, s.db.DeleteUserReads},
+		{&deleted.Stars, s.db.DeleteUserStars},
+		{&deleted.Rules, s.db.DeleteUserRules},
+		{&deleted.Views, s.db.DeleteUserViews},
+		{&deleted.Webhooks, s.db.DeleteUserWebhooks},
+	} {
+		n, err := del.delete(ctx, userID)
+		if err != nil {
+			return deleted, err
+		}
+		*del.count = n
+	}
+	return deleted, nil
+}
+
+// backupFollows exports the follows of each of users to <dir>/<name>.opml,
+// so they can be restored with gator import.
+func backupFollows(ctx context.Context, s *state, dir string, users []database.User) error {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		feedFollows, err := s.db.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{
+			UserID: user.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		name := strings.NewReplacer("/", "_", `\`, "_").Replace(user.Name)
+		file, err := os.OpenFile(filepath.Join(dir, name+".opml"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return err
+		}
+		err = writeOPML(file, user, feedFollows)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+func TestResetNeedsAnAnswer(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	feed := addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+	addPost(t, s, feed, "alice-post")
+	reset := middlewareAdmin(handlerReset)
+
+	// Nothing to read, as when stdin isn't a terminal.
+	old := stdin
+	stdin = bufio.NewReader(strings.NewReader(""))
+	t.Cleanup(func() { stdin = old })
+	err := runCommand(s, reset, "reset", "--posts")
+	if err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Errorf("reset without an answer returned %v, want an error pointing to --yes", err)
+	}
+
+	withStdin(t, "n")
+	err = runCommand(s, reset, "reset", "--posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := s.db.CountPosts(context.Background())
+	if err != nil || count != 1 {
+		t.Errorf("%d posts left, %v, want 1", count, err)
+	}
+
+	err = runCommand(s, reset, "reset", "--posts", "--yes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err = s.db.CountPosts(context.Background())
+	if err != nil || count != 0 {
+		t.Errorf("%d posts left, %v, want 0", count, err)
+	}
+}
+
+func TestResetBacksUpFollows(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+
+	err := runCommand(s, middlewareAdmin(handlerReset), "reset", "--feeds", "--yes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups, err := filepath.Glob(filepath.Join(os.Getenv("XDG_STATE_HOME"), "gator", "backups", "*", "alice.opml"))
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("found backups %v, %v, want one under $XDG_STATE_HOME", backups, err)
+	}
+	opml, err := os.ReadFile(backups[0])
+	if err != nil || !strings.Contains(string(opml), "https://alice.example/feed") {
+		t.Errorf("backup has %s, %v, want alice's feed", opml, err)
+	}
+}
+
+func TestDeleteUserDataCountsWhatItDeletes(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := registerUser(t, s, "alice")
+	bob := registerUser(t, s, "bob")
+	blog := addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+	addFeed(t, s, "alice", "News", "https://news.example/feed")
+	addFeed(t, s, "bob", "Bob's blog", "https://bob.example/feed")
+	addPost(t, s, blog, "alice-post")
+	_, err := s.db.SaveView(ctx, database.SaveViewParams{UserID: alice.ID, Name: "everything"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleted, err := deleteUserData(ctx, s, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := database.CountUserDataRow{Follows: 2, Views: 1}
+	if deleted != want {
+		t.Errorf("deleted %+v, want %+v", deleted, want)
+	}
+
+	// Nothing is left to delete, and bob keeps their follow.
+	deleted, err = deleteUserData(ctx, s, alice.ID)
+	if err != nil || deleted != (database.CountUserDataRow{}) {
+		t.Errorf("deleting again deleted %+v, %v", deleted, err)
+	}
+	counts, err := s.db.CountUserData(ctx, bob.ID)
+	if err != nil || counts.Follows != 1 {
+		t.Errorf("bob has %+v, %v left, want 1 follow", counts, err)
+	}
+}
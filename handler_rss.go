@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/xml"
+	"errors"
+	"flag"
 	"fmt"
 	"html"
 	"io"
@@ -11,6 +13,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -43,62 +46,201 @@ func parseTime(dateStr string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("unable to parse date: %s", dateStr)
 }
 
-func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
-	return func(s *state, cmd command) error {
-		currentUser, err := s.db.GetUser(context.Background(), s.cfg.CurrentUserName)
+func middlewareLoggedIn(handler func(ctx context.Context, s *state, cmd command, user database.User) error) func(context.Context, *state, command) error {
+	return func(ctx context.Context, s *state, cmd command) error {
+		currentUser, err := currentDatabaseUser(ctx, s, cmd.Name)
 		if err != nil {
 			return err
 		}
-		return handler(s, cmd, currentUser)
+		return handler(ctx, s, cmd, currentUser)
 	}
 }
 
-func handlerAgg(s *state, cmd command) error {
-	if len(cmd.Args) != 1 {
-		return fmt.Errorf("usage: agg <interval>")
+// currentDatabaseUser returns the user the config is logged in to the
+// database as. After `login --server` the name is that of a user on the
+// server, who mustn't be trusted with the local database.
+func currentDatabaseUser(ctx context.Context, s *state, cmdName string) (database.User, error) {
+	if s.cfg.ServerURL != "" {
+		return database.User{}, fmt.Errorf("%v only works on the database, but you're logged in to %v; log in to the database with: gator login <name>", cmdName, s.cfg.ServerURL)
+	}
+	return s.db.GetUser(ctx, s.cfg.CurrentUserName)
+}
+
+// middlewareAdmin is middlewareLoggedIn for commands only admins may run.
+func middlewareAdmin(handler func(ctx context.Context, s *state, cmd command, user database.User) error) func(context.Context, *state, command) error {
+	return middlewareLoggedIn(func(ctx context.Context, s *state, cmd command, user database.User) error {
+		if user.Role != roleAdmin {
+			return fmt.Errorf("only admins can run %v", cmd.Name)
+		}
+		return handler(ctx, s, cmd, user)
+	})
+}
+
+// checkFeedOwner returns an error unless user added feed or is an admin.
+func checkFeedOwner(user database.User, feed database.Feed) error {
+	if feed.UserID != user.ID && user.Role != roleAdmin {
+		return fmt.Errorf("only the user who added %v or an admin can change it", feed.Name)
+	}
+	return nil
+}
+
+func handlerAgg(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	digestAt := fs.String("digest-at", "", "also email digests every day at this local time, e.g. 07:00")
+	metricsAddr := fs.String("metrics-addr", "", "serve Prometheus metrics on this address, e.g. :9090")
+	pruneInterval := fs.Duration("prune-every", 0, "prune old posts on this interval, as an admin")
+	disableAfter := fs.Int("disable-after", 10, "disable feeds after this many failed fetches in a row, or 0 to never disable")
+	redirectAfter := fs.Int("redirect-after", 3, "move feeds to the url they permanently redirect to after this many fetches in a row, or 0 to never move them")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: agg <interval> [--digest-at <HH:MM>] [--metrics-addr <addr>] [--prune-every <interval>] [--disable-after <failures>] [--redirect-after <fetches>]")
 	}
 
-	timeBetweenRequests, err := time.ParseDuration(cmd.Args[0])
+	timeBetweenRequests, err := time.ParseDuration(args[0])
 	if err != nil {
 		return err
 	}
 
+	if *pruneInterval > 0 {
+		// Pruning uses the retention in the current user's config, so only
+		// an admin's config may decide what everyone loses.
+		user, err := currentDatabaseUser(ctx, s, "agg --prune-every")
+		if err != nil {
+			return err
+		}
+		if user.Role != roleAdmin {
+			return errors.New("only admins can prune posts, run agg without --prune-every")
+		}
+	}
+
+	// Everything agg runs next to the fetch loop stops with ctx, and agg
+	// waits for it before returning.
+	var wg sync.WaitGroup
+	background := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	if *digestAt != "" {
+		at, err := time.Parse("15:04", *digestAt)
+		if err != nil {
+			return fmt.Errorf("digest time must be formatted as HH:MM, got: %s", *digestAt)
+		}
+		if s.cfg.SMTP == nil {
+			return errors.New("smtp is not configured, add an \"smtp\" section to your config")
+		}
+		fmt.Println("Sending digests daily at", *digestAt)
+		background(func() { mailDigestsDaily(ctx, s, at.Hour(), at.Minute()) })
+	}
+
+	if *metricsAddr != "" {
+		background(func() { serveMetrics(ctx, s, *metricsAddr) })
+	}
+
+	if *pruneInterval > 0 {
+		background(func() { pruneEvery(ctx, s, *pruneInterval) })
+	}
+
 	fmt.Println("Collecting feeds every", timeBetweenRequests)
 
+	background(func() { deliverWebhooks(ctx, s) })
+	background(func() { fetchFullTexts(ctx, s) })
+
+	// Once ctx is done, e.g. on SIGINT or SIGTERM, the fetch in flight is
+	// aborted, and what has already been fetched is still saved.
+	summary := aggSummary{start: time.Now()}
 	ticker := time.NewTicker(timeBetweenRequests)
-	for ; ; <-ticker.C {
-		err = scrapeFeeds(s)
-		if err != nil {
+	defer ticker.Stop()
+	for ctx.Err() == nil {
+		err = scrapeFeeds(ctx, s, *disableAfter, *redirectAfter, &summary)
+		if err != nil && ctx.Err() == nil {
 			fmt.Println("Encountered an error scraping feeds:", err)
 		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
 	}
 
+	fmt.Println("Stopping, waiting for work in flight to finish")
+	wg.Wait()
+	summary.print()
+	return nil
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+// aggSummary counts what agg did, to report when it stops.
+type aggSummary struct {
+	start   time.Time
+	fetched int
+	failed  int
+	posts   int
+}
+
+func (summary *aggSummary) print() {
+	fmt.Printf("Fetched %d feeds in %v, %d failed, saving %d new posts\n",
+		summary.fetched, time.Since(summary.start).Round(time.Second), summary.failed, summary.posts)
+}
+
+// fetchFeed downloads and parses the feed at feedURL. If the request was
+// answered by one or more permanent redirects (301 or 308) in a row, movedTo
+// is the url they lead to; redirects after a temporary one don't count.
+func fetchFeed(ctx context.Context, feedURL string) (feed *RSSFeed, movedTo string, err error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	req.Header.Set("User-Agent", "gator")
 
+	permanent := true
 	client := &http.Client{
 		Timeout: 30 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			code := req.Response.StatusCode
+			if permanent && (code == http.StatusMovedPermanently || code == http.StatusPermanentRedirect) {
+				movedTo = req.URL.String()
+			} else {
+				permanent = false
+			}
+			return nil
+		},
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 400 {
+		return nil, "", &fetchStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
 	data, err := io.ReadAll(resp.Body)
+	feedBytesDownloadedTotal.Add(float64(len(data)))
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
+	feed, err = parseFeed(data)
+	if err != nil {
+		return nil, "", err
+	}
+	return feed, movedTo, nil
+}
+
+func parseFeed(data []byte) (*RSSFeed, error) {
 	var feed RSSFeed
-	err = xml.Unmarshal(data, &feed)
+	err := xml.Unmarshal(data, &feed)
 	if err != nil {
 		return nil, err
 	}
@@ -114,20 +256,47 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	return &feed, nil
 }
 
-func handlerAddFeed(s *state, cmd command, user database.User) error {
+func handlerAddFeed(ctx context.Context, s *state, cmd command, user database.User) error {
 
-	if len(cmd.Args) != 2 {
-		return fmt.Errorf("usage: addfeed <name> <url>")
+	if len(cmd.Args) != 1 && len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: addfeed [name] <url>")
 	}
 
-	ctx := context.Background()
+	url, err := canonicalFeedURL(cmd.Args[len(cmd.Args)-1])
+	if err != nil {
+		return err
+	}
+
+	existing, err := lookupFeed(ctx, s, url)
+	if err == nil {
+		return fmt.Errorf("feed already exists as %v, follow it with: gator follow %v", existing.Name, existing.Url)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	var name string
+	var rss *RSSFeed
+	if len(cmd.Args) == 2 {
+		name = cmd.Args[0]
+	} else {
+		// Without a name, use the title the feed gives itself.
+		rss, _, err = fetchFeed(ctx, url)
+		if err != nil {
+			return fmt.Errorf("couldn't fetch feed to get its title: %w", err)
+		}
+		name = strings.TrimSpace(rss.Channel.Title)
+		if name == "" {
+			return fmt.Errorf("feed has no title, give it a name: addfeed <name> <url>")
+		}
+	}
 
 	feed, err := s.db.CreateFeed(ctx, database.CreateFeedParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
-		Name:      cmd.Args[0],
-		Url:       cmd.Args[1],
+		Name:      name,
+		Url:       url,
 		UserID:    user.ID,
 	})
 
@@ -135,6 +304,15 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		return err
 	}
 
+	// The feed was already fetched for its title, so keep what else it
+	// says about itself instead of waiting for agg.
+	if rss != nil {
+		err = saveFeedMetadata(ctx, s, feed, rss)
+		if err != nil {
+			return fmt.Errorf("couldn't save feed metadata: %w", err)
+		}
+	}
+
 	_, err = s.db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		UserID: user.ID,
 		FeedID: feed.ID,
@@ -148,13 +326,21 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handlerFeeds(s *state, cmd command) error {
+func handlerFeeds(ctx context.Context, s *state, cmd command) error {
 
-	if len(cmd.Args) != 0 {
-		return fmt.Errorf("usage: feeds")
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	health := fs.Bool("health", false, "show fetch status instead of owners")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: feeds [--health]")
 	}
 
-	ctx := context.Background()
+	if *health {
+		return printFeedsHealth(ctx, s)
+	}
 
 	results, err := s.db.GetFeedsByUser(ctx)
 	if err != nil {
@@ -175,15 +361,234 @@ func handlerFeeds(s *state, cmd command) error {
 	return nil
 }
 
-func handlerFollow(s *state, cmd command, user database.User) error {
+func printFeedsHealth(ctx context.Context, s *state) error {
+	feeds, err := s.db.GetFeedsHealth(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-20s %-55s %-9s %-6s %-8s %-16s\n", "Feed Name", "Feed URL", "State", "Status", "Failures", "Last Success")
+
+	for _, feed := range feeds {
+		state := "ok"
+		switch {
+		case feed.DisabledAt.Valid:
+			state = "disabled"
+		case feed.ConsecutiveFailures > 0:
+			state = "failing"
+		case !feed.LastFetchedAt.Valid:
+			state = "new"
+		}
+		status := "-"
+		if feed.LastStatusCode.Valid {
+			status = strconv.Itoa(int(feed.LastStatusCode.Int32))
+		}
+		lastSuccess := "never"
+		if feed.LastSucceededAt.Valid {
+			lastSuccess = feed.LastSucceededAt.Time.Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%-20s %-55s %-9s %-6s %-8d %-16s\n", feed.Name, feed.Url, state, status, feed.ConsecutiveFailures, lastSuccess)
+		if feed.LastError.Valid {
+			fmt.Printf("  error: %s\n", feed.LastError.String)
+		}
+	}
+
+	return nil
+}
 
+func handlerFeedEnable(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.Args) != 1 {
-		return fmt.Errorf("usage: follow <url>")
+		return fmt.Errorf("usage: %v <url>", cmd.Name)
+	}
+
+	feed, err := lookupFeed(ctx, s, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("couldn't find feed %v: %w", cmd.Args[0], err)
+	}
+	err = checkFeedOwner(user, feed)
+	if err != nil {
+		return err
+	}
+
+	enabled, err := s.db.EnableFeed(ctx, feed.Url)
+	if err != nil {
+		return fmt.Errorf("couldn't enable feed: %w", err)
+	}
+	if enabled == 0 {
+		return fmt.Errorf("no feed with url %v", feed.Url)
+	}
+
+	fmt.Printf("Enabled %v, agg will fetch it again\n", feed.Url)
+	return nil
+}
+
+// saveFeedMetadata stores what the feed says about itself in its channel.
+func saveFeedMetadata(ctx context.Context, s *state, feed database.Feed, rss *RSSFeed) error {
+	text := func(value string) sql.NullString {
+		value = strings.TrimSpace(value)
+		return sql.NullString{String: value, Valid: value != ""}
+	}
+
+	return s.db.UpdateFeedMetadata(ctx, database.UpdateFeedMetadataParams{
+		ID:          feed.ID,
+		Title:       text(rss.Channel.Title),
+		SiteUrl:     text(rss.Channel.Link),
+		Description: text(rss.Channel.Description),
+		Language:    text(rss.Channel.Language),
+		ImageUrl:    text(rss.imageURL()),
+		Generator:   text(rss.Channel.Generator),
+	})
+}
+
+func handlerFeedInfo(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v <url>", cmd.Name)
+	}
+
+	feed, err := lookupFeed(ctx, s, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("couldn't find feed %v: %w", cmd.Args[0], err)
+	}
+
+	followers, err := s.db.CountFeedFollowers(ctx, feed.ID)
+	if err != nil {
+		return err
+	}
+
+	optional := func(value sql.NullString) string {
+		if !value.Valid {
+			return "-"
+		}
+		return value.String
+	}
+	lastFetched := "never"
+	if feed.LastFetchedAt.Valid {
+		lastFetched = feed.LastFetchedAt.Time.Format("2006-01-02 15:04")
+	}
+
+	fmt.Printf("Name:         %v\n", feed.Name)
+	fmt.Printf("URL:          %v\n", feed.Url)
+	fmt.Printf("Title:        %v\n", optional(feed.Title))
+	fmt.Printf("Site:         %v\n", optional(feed.SiteUrl))
+	fmt.Printf("Description:  %v\n", optional(feed.Description))
+	fmt.Printf("Language:     %v\n", optional(feed.Language))
+	fmt.Printf("Image:        %v\n", optional(feed.ImageUrl))
+	fmt.Printf("Generator:    %v\n", optional(feed.Generator))
+	fmt.Printf("Followers:    %d\n", followers)
+	fmt.Printf("Last fetched: %v\n", lastFetched)
+	if feed.DisabledAt.Valid {
+		fmt.Printf("Disabled:     %v\n", feed.DisabledAt.Time.Format("2006-01-02 15:04"))
+	}
+
+	return nil
+}
+
+func handlerFeedEdit(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	name := fs.String("name", "", "rename the feed for everyone")
+	newURL := fs.String("url", "", "move the feed to this url, which must serve a valid feed")
+	interval := fs.String("interval", "", "fetch the feed at most this often, e.g. 6h or 1d, or 0 to fetch whenever it is due")
+	fullText := fs.Bool("fetch-full-text", false, "download the page each new post links to")
+	disabled := fs.Bool("disabled", false, "stop fetching the feed")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %v <url> [--name <name>] [--url <url>] [--interval <interval>] [--fetch-full-text[=false]] [--disabled[=false]]", cmd.Name)
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	if len(set) == 0 {
+		return fmt.Errorf("nothing to change, see: %v --help", cmd.Name)
+	}
+
+	feed, err := lookupFeed(ctx, s, args[0])
+	if err != nil {
+		return fmt.Errorf("couldn't find feed %v: %w", args[0], err)
+	}
+	err = checkFeedOwner(user, feed)
+	if err != nil {
+		return err
+	}
+
+	params := database.UpdateFeedParams{
+		ID:                   feed.ID,
+		Name:                 feed.Name,
+		Url:                  feed.Url,
+		FetchIntervalSeconds: feed.FetchIntervalSeconds,
+		FetchFullText:        feed.FetchFullText,
+		DisabledAt:           feed.DisabledAt,
+	}
+
+	if set["name"] {
+		params.Name = strings.TrimSpace(*name)
+		if params.Name == "" {
+			return fmt.Errorf("name can't be empty")
+		}
+	}
+
+	if set["url"] {
+		params.Url, err = canonicalFeedURL(*newURL)
+		if err != nil {
+			return err
+		}
+		existing, err := lookupFeed(ctx, s, params.Url)
+		if err == nil && existing.ID != feed.ID {
+			return fmt.Errorf("%v is already the url of %v", existing.Url, existing.Name)
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		_, _, err = fetchFeed(ctx, params.Url)
+		if err != nil {
+			return fmt.Errorf("couldn't fetch feed from %v: %w", params.Url, err)
+		}
 	}
 
-	ctx := context.Background()
+	if set["interval"] {
+		params.FetchIntervalSeconds = sql.NullInt32{}
+		if *interval != "0" {
+			seconds, err := parseWindowSeconds(*interval)
+			if err != nil {
+				return err
+			}
+			params.FetchIntervalSeconds = sql.NullInt32{Int32: seconds, Valid: true}
+		}
+	}
+
+	if set["fetch-full-text"] {
+		params.FetchFullText = *fullText
+	}
 
-	feed, err := s.db.GetFeedByUrl(ctx, cmd.Args[0])
+	if set["disabled"] {
+		switch {
+		case !*disabled:
+			params.DisabledAt = sql.NullTime{}
+		case !feed.DisabledAt.Valid:
+			params.DisabledAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+		}
+	}
+
+	updated, err := s.db.UpdateFeed(ctx, params)
+	if err != nil {
+		return fmt.Errorf("couldn't update feed: %w", err)
+	}
+
+	fmt.Printf("Updated %v (%v)\n", updated.Name, updated.Url)
+	return nil
+}
+
+func handlerFollow(ctx context.Context, s *state, cmd command, user database.User) error {
+
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: follow <url>")
+	}
+
+	feed, err := lookupFeed(ctx, s, cmd.Args[0])
 	if err != nil {
 		return err
 	}
@@ -202,33 +607,42 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func handlerFollowing(s *state, cmd command, user database.User) error {
-	if len(cmd.Args) != 0 {
-		return fmt.Errorf("usage: following")
+func handlerFollowing(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	tag := fs.String("tag", "", "only list feeds with this tag")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return fmt.Errorf("usage: following [--tag <tag>]")
 	}
 
-	ctx := context.Background()
-
-	feedFollows, err := s.db.GetFeedFollowsForUser(ctx, user.ID)
+	feedFollows, err := s.db.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{
+		UserID: user.ID,
+		Tag:    sql.NullString{String: *tag, Valid: *tag != ""},
+	})
 	if err != nil {
 		return err
 	}
 
 	for _, row := range feedFollows {
-		fmt.Printf("%-20s %-55s\n", row.FeedName, row.FeedUrl)
+		printFollowedFeed(row.FeedName, row.FeedUrl, splitTags(row.Tags))
 	}
 
 	return nil
 }
 
-func handlerUnfollow(s *state, cmd command, user database.User) error {
+func printFollowedFeed(name, url string, tags []string) {
+	fmt.Printf("%-20s %-55s %s\n", name, url, strings.Join(tags, ", "))
+}
+
+func handlerUnfollow(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.Args) != 1 {
 		return fmt.Errorf("usage: unfollow <url>")
 	}
 
-	ctx := context.Background()
-
-	feed, err := s.db.GetFeedByUrl(ctx, cmd.Args[0])
+	feed, err := lookupFeed(ctx, s, cmd.Args[0])
 	if err != nil {
 		return err
 	}
@@ -247,11 +661,59 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 	return nil
 }
 
-func scrapeFeeds(s *state) error {
+func handlerRename(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 && len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %v <url> [name]", cmd.Name)
+	}
+
+	feed, err := lookupFeed(ctx, s, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	// Leaving out the name goes back to the feed's shared name.
+	var displayName sql.NullString
+	if len(cmd.Args) == 2 {
+		name := strings.TrimSpace(cmd.Args[1])
+		if name == "" {
+			return fmt.Errorf("name can't be empty")
+		}
+		displayName = sql.NullString{String: name, Valid: true}
+	}
 
-	ctx := context.Background()
+	updated, err := s.db.SetFeedFollowDisplayName(ctx, database.SetFeedFollowDisplayNameParams{
+		UserID:      user.ID,
+		FeedID:      feed.ID,
+		DisplayName: displayName,
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("you don't follow %v", feed.Url)
+	}
 
+	if displayName.Valid {
+		fmt.Printf("Renamed %v to %v\n", feed.Name, displayName.String)
+	} else {
+		fmt.Printf("Restored the name of %v\n", feed.Name)
+	}
+
+	return nil
+}
+
+// scrapeFeeds fetches the feed most in need of it, counting it in summary.
+// Failures are recorded on the feed, which is disabled once it fails
+// maxFailures times in a row or is gone for good. A feed that permanently
+// redirects on redirectsToMove fetches in a row has its url updated. A
+// fetch aborted because ctx is done isn't a failure, and a fetched feed is
+// saved even if ctx is done meanwhile.
+func scrapeFeeds(ctx context.Context, s *state, maxFailures, redirectsToMove int, summary *aggSummary) error {
 	nextFeed, err := s.db.GetNextFeedToFetch(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Nothing is due: there are no feeds, or WebSub keeps them current.
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to identify next feed to fetch: %w", err)
 	}
@@ -263,12 +725,84 @@ func scrapeFeeds(s *state) error {
 
 	fmt.Printf("Fetching feed: %s\n", nextFeed.Url)
 
-	rss, err := fetchFeed(ctx, nextFeed.Url)
+	start := time.Now()
+	rss, movedTo, err := fetchFeed(ctx, nextFeed.Url)
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("aborted fetching feed %v: %w", nextFeed.Name, ctx.Err())
+	}
+	feedFetchDuration.Observe(time.Since(start).Seconds())
 	if err != nil {
+		summary.failed++
+		feedFetchesTotal.WithLabelValues("failure").Inc()
+		feedFetchFailuresTotal.WithLabelValues(nextFeed.Url, fetchErrorClass(err)).Inc()
+
+		var statusCode sql.NullInt32
+		var statusErr *fetchStatusError
+		if errors.As(err, &statusErr) {
+			statusCode = sql.NullInt32{Int32: int32(statusErr.StatusCode), Valid: true}
+			if statusErr.StatusCode == http.StatusGone {
+				maxFailures = 1
+			}
+		}
+		feed, recordErr := s.db.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+			LastStatusCode: statusCode,
+			LastError:      sql.NullString{String: err.Error(), Valid: true},
+			MaxFailures:    int32(maxFailures),
+			ID:             nextFeed.ID,
+		})
+		if recordErr != nil {
+			log.Printf("Failed to record fetch failure for feed %s: %v", nextFeed.Name, recordErr)
+		} else if feed.DisabledAt.Valid {
+			log.Printf("Disabled feed %s after %d failed fetches, run `gator feed enable %s` to fetch it again", feed.Name, feed.ConsecutiveFailures, feed.Url)
+		}
+
 		return fmt.Errorf("failed to fetch feed %v from %v: %w", nextFeed.Name, nextFeed.Url, err)
 	}
+	summary.fetched++
+	feedFetchesTotal.WithLabelValues("success").Inc()
+	lastSuccessfulFetch.SetToCurrentTime()
+
+	saveCtx := context.WithoutCancel(ctx)
 
-	for _, item := range rss.Channel.Item {
+	// fetchFeed follows redirects and fails on error statuses, so a feed it
+	// returns came with a 200.
+	err = s.db.RecordFeedSuccess(saveCtx, database.RecordFeedSuccessParams{
+		ID:             nextFeed.ID,
+		LastStatusCode: sql.NullInt32{Int32: http.StatusOK, Valid: true},
+	})
+	if err != nil {
+		log.Printf("Failed to record fetch for feed %s: %v", nextFeed.Name, err)
+	}
+
+	nextFeed, err = followFeedRedirect(saveCtx, s, nextFeed, movedTo, redirectsToMove)
+	if err != nil {
+		log.Printf("Failed to follow redirect for feed %s: %v", nextFeed.Name, err)
+	}
+
+	err = saveFeedMetadata(saveCtx, s, nextFeed, rss)
+	if err != nil {
+		log.Printf("Failed to save metadata for feed %s: %v", nextFeed.Name, err)
+	}
+
+	summary.posts += savePosts(ctx, s, nextFeed, rss.Channel.Item)
+
+	err = subscribeWebsub(ctx, s, nextFeed, rss)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("Failed to subscribe to hub for feed %s: %v", nextFeed.Name, err)
+	}
+
+	return nil
+}
+
+// savePosts stores items as posts of feed, skipping any already saved, and
+// queues webhook deliveries and, if the feed wants them, full-text fetches
+// for the new ones. It returns how many posts it saved. Both polling and
+// WebSub pushes go through here. Once ctx is done the remaining posts are
+// still saved.
+func savePosts(ctx context.Context, s *state, feed database.Feed, items []RSSItem) int {
+	saveCtx := context.WithoutCancel(ctx)
+	saved := 0
+	for _, item := range items {
 
 		publishedAt, err := parseTime(item.PubDate)
 		if err != nil {
@@ -280,93 +814,227 @@ func scrapeFeeds(s *state) error {
 			Url:         item.Link,
 			Description: sql.NullString{String: item.Description, Valid: item.Description != ""},
 			PublishedAt: sql.NullTime{Time: publishedAt, Valid: !publishedAt.IsZero()},
-			FeedID:      nextFeed.ID,
+			FeedID:      feed.ID,
+			Author:      sql.NullString{String: item.author(), Valid: item.author() != ""},
 		}
 
-		_, err = s.db.CreatePost(ctx, params)
+		post, err := s.db.CreatePost(saveCtx, params)
 
 		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "already exists") {
+			if isDuplicateKey(err) || strings.Contains(err.Error(), "already exists") {
+				continue
+			}
+			// The post was pruned, but the feed still lists it.
+			if errors.Is(err, sql.ErrNoRows) {
 				continue
 			}
 
 			log.Printf("Failed to create post %s: %v", item.Title, err)
+			continue
+		}
+		postsInsertedTotal.Inc()
+		saved++
+
+		if feed.FetchFullText {
+			err = s.db.EnqueueFullTextFetch(saveCtx, database.EnqueueFullTextFetchParams{
+				PostID: post.ID,
+				Url:    post.Url,
+			})
+			if err != nil {
+				log.Printf("Failed to queue full text of post %s: %v", item.Title, err)
+			}
+		}
+
+		_, err = s.db.EnqueueWebhookDeliveries(saveCtx, post.ID)
+		if err != nil {
+			log.Printf("Failed to queue webhooks for post %s: %v", item.Title, err)
 		}
 	}
+	return saved
+}
 
-	return nil
+// browsedPost is a post shown by browse, with whether a rule highlights it.
+type browsedPost struct {
+	database.GetPostsForUserRow
+	Highlight bool
 }
 
-func handlerBrowse(s *state, cmd command, user database.User) error {
+// parseBrowseArgs parses the arguments of browse, which are the same
+// whether posts come from the database or a gator server.
+func parseBrowseArgs(cmd command) (tag, viewName string, limit int, err error) {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.StringVar(&tag, "tag", "", "only show posts from feeds with this tag")
+	fs.StringVar(&viewName, "view", "", "only show posts matching this saved view")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return "", "", 0, err
+	}
+	if tag != "" && viewName != "" {
+		return "", "", 0, fmt.Errorf("--tag and --view can't be used together, add the tag to the view instead")
+	}
 
 	// Parse and validate the limit argument
-	limit := 2 // default value
-	if len(cmd.Args) > 0 {
-		parsedLimit, err := strconv.Atoi(cmd.Args[0])
+	limit = 2 // default value
+	if len(args) > 0 {
+		parsedLimit, err := strconv.Atoi(args[0])
 		if err != nil {
-			return fmt.Errorf("limit must be a valid integer, got: %s", cmd.Args[0])
+			return "", "", 0, fmt.Errorf("limit must be a valid integer, got: %s", args[0])
 		}
 		if parsedLimit <= 0 {
-			return fmt.Errorf("limit must be a positive integer, got: %d", parsedLimit)
+			return "", "", 0, fmt.Errorf("limit must be a positive integer, got: %d", parsedLimit)
 		}
 		limit = parsedLimit
 	}
 
 	// Also check for too many arguments
-	if len(cmd.Args) > 1 {
-		return fmt.Errorf("browse command takes at most 1 argument (limit), got %d", len(cmd.Args))
+	if len(args) > 1 {
+		return "", "", 0, fmt.Errorf("browse command takes at most 1 argument (limit), got %d", len(args))
 	}
 
-	ctx := context.Background()
+	return tag, viewName, limit, nil
+}
 
-	params := database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
+func handlerBrowse(ctx context.Context, s *state, cmd command, user database.User) error {
+	tag, viewName, limit, err := parseBrowseArgs(cmd)
+	if err != nil {
+		return err
 	}
 
-	posts, err := s.db.GetPostsForUser(ctx, params)
+	posts, err := browsePosts(ctx, s, user, tag, viewName, limit)
 	if err != nil {
 		return err
 	}
 
+	printBrowsedPosts(posts)
+	return nil
+}
+
+// browsePosts returns the newest limit posts for user that no rule mutes,
+// optionally only from feeds with tag or matching the saved view viewName.
+func browsePosts(ctx context.Context, s *state, user database.User, tag, viewName string, limit int) ([]browsedPost, error) {
+	rules, err := loadPostRules(ctx, s, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var view database.View
+	if viewName != "" {
+		view, err = s.db.GetViewByName(ctx, database.GetViewByNameParams{
+			UserID: user.ID,
+			Name:   viewName,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("no view named %v, see gator views", viewName)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Muted posts are dropped after the query, so keep reading pages until
+	// there are enough posts to show or none are left.
+	var posts []browsedPost
+	for offset := 0; len(posts) < limit; offset += limit {
+		var page []database.GetPostsForUserRow
+		if viewName != "" {
+			rows, err := s.db.GetPostsForView(ctx, database.GetPostsForViewParams{
+				ViewID: view.ID,
+				Limit:  int32(limit),
+				Offset: int32(offset),
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, row := range rows {
+				page = append(page, database.GetPostsForUserRow(row))
+			}
+		} else {
+			page, err = s.db.GetPostsForUser(ctx, database.GetPostsForUserParams{
+				UserID: user.ID,
+				Tag:    sql.NullString{String: tag, Valid: tag != ""},
+				Limit:  int32(limit),
+				Offset: int32(offset),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, post := range page {
+			verdict := rules.apply(post.FeedID, post.Title, post.Url, post.Description, post.Author)
+			if verdict.Mute {
+				continue
+			}
+			if verdict.MarkRead {
+				err = s.db.MarkPostRead(ctx, database.MarkPostReadParams{UserID: user.ID, PostID: post.ID})
+				if err != nil {
+					return nil, err
+				}
+			}
+			if len(posts) < limit {
+				posts = append(posts, browsedPost{GetPostsForUserRow: post, Highlight: verdict.Highlight})
+			}
+		}
+
+		if len(page) < limit {
+			break
+		}
+	}
+
+	return posts, nil
+}
+
+func printBrowsedPosts(posts []browsedPost) {
 	if len(posts) == 0 {
 		fmt.Println("No posts found. Try following some feeds first!")
-		return nil
+		return
 	}
 
 	fmt.Printf("Found %d posts:\n\n", len(posts))
 
 	for _, post := range posts {
-		fmt.Printf("Title: %s\n", post.Title)
+		if post.Highlight {
+			fmt.Printf("Title: ★ %s\n", post.Title)
+		} else {
+			fmt.Printf("Title: %s\n", post.Title)
+		}
 		fmt.Printf("URL: %s\n", post.Url)
 		if post.Description.Valid && post.Description.String != "" {
 			fmt.Printf("Description: %s\n", post.Description.String)
 		}
+		if post.Author.Valid && post.Author.String != "" {
+			fmt.Printf("Author: %s\n", post.Author.String)
+		}
 		fmt.Printf("Feed: %s\n", post.FeedName)
 		if post.PublishedAt.Valid {
 			fmt.Printf("Published: %s\n", post.PublishedAt.Time.Format("2006-01-02 15:04"))
 		}
 		fmt.Println("---")
 	}
-
-	return nil
-
 }
 
-func handlerRemoveFeed(s *state, cmd command) error {
+func handlerRemoveFeed(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.Args) != 1 {
-		return fmt.Errorf("removefeed command takes at most 1 argument (limit), got %d", len(cmd.Args))
+		return fmt.Errorf("usage: %v <url>", cmd.Name)
+	}
+
+	feed, err := lookupFeed(ctx, s, cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("couldn't find feed: %v", err)
+	}
+
+	err = checkFeedOwner(user, feed)
+	if err != nil {
+		return err
 	}
 
-	url := cmd.Args[0]
-	ctx := context.Background()
-	err := s.db.DeleteFeed(ctx, url)
+	err = s.db.DeleteFeedByID(ctx, feed.ID)
 
 	if err != nil {
 		return fmt.Errorf("couldn't delete feed: %v", err)
 	}
 
-	fmt.Printf("Successfully deleted feed with url %v\n", url)
+	fmt.Printf("Successfully deleted feed with url %v\n", feed.Url)
 
 	return nil
 
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// addFeed adds a feed named name at url as userName, who then follows it.
+func addFeed(t *testing.T, s *state, userName, name, url string) database.Feed {
+	t.Helper()
+	loginAs(t, s, userName)
+	err := runCommand(s, middlewareLoggedIn(handlerAddFeed), "addfeed", name, url)
+	if err != nil {
+		t.Fatalf("addfeed %v: %v", url, err)
+	}
+	feed, err := s.db.GetFeedByUrl(context.Background(), url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+func TestAddFeed(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := registerUser(t, s, "alice")
+	addFeed := middlewareLoggedIn(handlerAddFeed)
+
+	// Without a name, the feed is named after its title.
+	url := serveFeed(t, "Alice's blog")
+	err := runCommand(s, addFeed, "addfeed", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err := s.db.GetFeedByUrl(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Name != "Alice's blog" {
+		t.Errorf("feed is named %q, want its title", feed.Name)
+	}
+	if feed.Description.String != "About Alice's blog" || feed.Language.String != "en" {
+		t.Errorf("feed metadata is %+v, want the channel's description and language", feed)
+	}
+	if feed.UserID != alice.ID {
+		t.Errorf("feed belongs to %v, want alice (%v)", feed.UserID, alice.ID)
+	}
+	follows, err := s.db.GetFollowedFeeds(ctx, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 1 || follows[0].ID != feed.ID {
+		t.Errorf("alice follows %v, want only the feed she added", follows)
+	}
+
+	// The same feed spelled differently is refused.
+	err = runCommand(s, addFeed, "addfeed", "Again", url+"/?utm_source=x")
+	if err == nil {
+		t.Error("added the same feed twice")
+	}
+
+	err = runCommand(s, addFeed, "addfeed", "Broken", "ftp://example.com/feed")
+	if err == nil {
+		t.Error("added a feed with an ftp url")
+	}
+}
+
+func TestFollowAndUnfollow(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	bob := registerUser(t, s, "bob")
+	feed := addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+	loginAs(t, s, "bob")
+
+	err := runCommand(s, middlewareLoggedIn(handlerFollow), "follow", "https://ALICE.example/feed/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = runCommand(s, middlewareLoggedIn(handlerFollow), "follow", feed.Url)
+	if err == nil {
+		t.Error("followed a feed twice")
+	}
+
+	err = runCommand(s, middlewareLoggedIn(handlerRename), "rename", feed.Url, "Alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	follows, err := s.db.GetFollowedFeeds(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 1 || follows[0].DisplayName.String != "Alice" {
+		t.Errorf("bob follows %+v, want Alice's blog renamed to Alice", follows)
+	}
+
+	err = runCommand(s, middlewareLoggedIn(handlerUnfollow), "unfollow", feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follows, err = s.db.GetFollowedFeeds(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 0 {
+		t.Errorf("bob still follows %d feeds", len(follows))
+	}
+
+	err = runCommand(s, middlewareLoggedIn(handlerRename), "rename", feed.Url, "Alice")
+	if err == nil {
+		t.Error("renamed a feed bob doesn't follow")
+	}
+}
+
+func TestRemoveFeed(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	registerUser(t, s, "bob")
+	feed := addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+	removeFeed := middlewareLoggedIn(handlerRemoveFeed)
+
+	loginAs(t, s, "bob")
+	err := runCommand(s, removeFeed, "removefeed", feed.Url)
+	if err == nil {
+		t.Error("a member removed someone else's feed")
+	}
+
+	loginAs(t, s, "alice")
+	err = runCommand(s, removeFeed, "removefeed")
+	if err == nil || err.Error() != "usage: removefeed <url>" {
+		t.Errorf("removefeed without a url returned %v, want its usage", err)
+	}
+	err = runCommand(s, removeFeed, "removefeed", feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.db.GetFeedByUrl(ctx, feed.Url)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("feed wasn't removed: %v", err)
+	}
+}
+
+func TestFeedEdit(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	registerUser(t, s, "bob")
+	feed := addFeed(t, s, "bob", "Bob's blog", "https://bob.example/feed")
+	feedEdit := middlewareLoggedIn(handlerFeedEdit)
+
+	err := runCommand(s, feedEdit, "feed edit", feed.Url)
+	if err == nil {
+		t.Error("edited a feed without changing anything")
+	}
+
+	err = runCommand(s, feedEdit, "feed edit", feed.Url, "--name", "Bob", "--interval", "6h", "--disabled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err = s.db.GetFeedByUrl(ctx, feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Name != "Bob" || feed.FetchIntervalSeconds.Int32 != 6*60*60 || !feed.DisabledAt.Valid {
+		t.Errorf("edited feed is %+v, want it named Bob, fetched every 6h and disabled", feed)
+	}
+
+	registerUser(t, s, "carol")
+	err = runCommand(s, feedEdit, "feed edit", feed.Url, "--disabled=false")
+	if err == nil {
+		t.Error("a member edited someone else's feed")
+	}
+}
+
+func TestFeedEnable(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	registerUser(t, s, "bob")
+	feed := addFeed(t, s, "bob", "Bob's blog", "https://bob.example/feed")
+	err := runCommand(s, middlewareLoggedIn(handlerFeedEdit), "feed edit", feed.Url, "--disabled")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feedEnable := middlewareLoggedIn(handlerFeedEnable)
+
+	registerUser(t, s, "carol")
+	err = runCommand(s, feedEnable, "feed enable", feed.Url)
+	if err == nil {
+		t.Error("a member enabled someone else's feed")
+	}
+
+	loginAs(t, s, "bob")
+	err = runCommand(s, feedEnable, "feed enable", feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed, err = s.db.GetFeedByUrl(ctx, feed.Url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.DisabledAt.Valid {
+		t.Error("feed is still disabled")
+	}
+}
+
+func TestScrapeFeeds(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := registerUser(t, s, "alice")
+	url := serveFeed(t, "Alice's blog",
+		RSSItem{Title: "First", Link: "https://alice.example/1", PubDate: "Mon, 02 Jan 2006 15:04:05 MST"},
+		RSSItem{Title: "Second", Link: "https://alice.example/2", PubDate: "Tue, 03 Jan 2006 15:04:05 MST"},
+	)
+	addFeed(t, s, "alice", "Alice's blog", url)
+
+	var summary aggSummary
+	err := scrapeFeeds(ctx, s, 10, 3, &summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.fetched != 1 || summary.failed != 0 || summary.posts != 2 {
+		t.Errorf("summary is %+v, want 1 feed fetched and 2 posts saved", summary)
+	}
+
+	posts, err := browsePosts(ctx, s, alice, "", "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 || posts[0].Title != "Second" || posts[1].Title != "First" {
+		t.Errorf("browsed %+v, want Second then First", posts)
+	}
+
+	feed, err := s.db.GetFeedByUrl(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Title.String != "Alice's blog" || feed.Language.String != "en" {
+		t.Errorf("feed metadata is %+v, want the channel's title and language", feed)
+	}
+}
+
+func TestScrapeFeedsDisablesFailingFeeds(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+	addFeed(t, s, "alice", "Broken", srv.URL+"/feed")
+
+	var summary aggSummary
+	for i := 0; i < 2; i++ {
+		err := scrapeFeeds(ctx, s, 2, 3, &summary)
+		if err == nil {
+			t.Fatal("scraping a broken feed succeeded")
+		}
+	}
+	if summary.failed != 2 {
+		t.Errorf("summary counts %d failures, want 2", summary.failed)
+	}
+
+	feed, err := s.db.GetFeedByUrl(ctx, srv.URL+"/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !feed.DisabledAt.Valid || feed.ConsecutiveFailures != 2 {
+		t.Errorf("feed has %d failures and disabled_at %v, want 2 and disabled", feed.ConsecutiveFailures, feed.DisabledAt)
+	}
+	if feed.LastStatusCode.Int32 != http.StatusInternalServerError {
+		t.Errorf("last status is %v, want 500", feed.LastStatusCode)
+	}
+
+	err = scrapeFeeds(ctx, s, 2, 3, &summary)
+	if err != nil {
+		t.Errorf("scraping with only a disabled feed: %v", err)
+	}
+}
+
+func TestScrapeFeedsAbortIsNotAFailure(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Stop agg while the fetch is in flight.
+		cancel()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	addFeed(t, s, "alice", "Slow", srv.URL+"/feed")
+
+	var summary aggSummary
+	err := scrapeFeeds(ctx, s, 1, 3, &summary)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("aborted scrape returned %v, want context.Canceled", err)
+	}
+	if summary.failed != 0 || summary.fetched != 0 {
+		t.Errorf("summary is %+v, want nothing counted", summary)
+	}
+
+	feed, err := s.db.GetFeedByUrl(context.Background(), srv.URL+"/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.ConsecutiveFailures != 0 || feed.DisabledAt.Valid {
+		t.Errorf("aborted fetch was recorded as a failure: %+v", feed)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+var (
+	ruleKinds   = []string{"keyword", "regex", "author", "domain", "feed"}
+	ruleActions = []string{"mute", "highlight", "mark-read"}
+)
+
+// postRule is a rule with its pattern prepared for matching.
+type postRule struct {
+	database.PostRule
+	re *regexp.Regexp
+}
+
+// postRules are a user's rules, applied whenever their posts are listed.
+type postRules []postRule
+
+// ruleVerdict is the combined effect of every rule matching a post. Muted
+// posts are left out entirely, so the other actions only matter for posts
+// no mute rule matches.
+type ruleVerdict struct {
+	Mute      bool
+	Highlight bool
+	MarkRead  bool
+}
+
+func loadPostRules(ctx context.Context, s *state, userID uuid.UUID) (postRules, error) {
+	rows, err := s.db.GetPostRulesForUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get rules: %w", err)
+	}
+
+	rules := make(postRules, 0, len(rows))
+	for _, row := range rows {
+		rule := postRule{PostRule: row}
+		if row.Kind == "regex" {
+			// Patterns are checked when the rule is added, so this only fails
+			// if the rule was written some other way.
+			rule.re, err = regexp.Compile(row.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern in rule %v: %w", row.ID, err)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// apply matches a post against every rule. The arguments are the post
+// columns rules look at, since each listing query returns its own row type.
+func (rules postRules) apply(feedID uuid.UUID, title, link string, description, author sql.NullString) ruleVerdict {
+	var verdict ruleVerdict
+	for _, rule := range rules {
+		if !rule.matches(feedID, title, link, description, author) {
+			continue
+		}
+		switch rule.Action {
+		case "mute":
+			verdict.Mute = true
+		case "highlight":
+			verdict.Highlight = true
+		case "mark-read":
+			verdict.MarkRead = true
+		}
+	}
+	return verdict
+}
+
+func (rule postRule) matches(feedID uuid.UUID, title, link string, description, author sql.NullString) bool {
+	switch rule.Kind {
+	case "keyword":
+		keyword := strings.ToLower(rule.Pattern)
+		return strings.Contains(strings.ToLower(title), keyword) ||
+			strings.Contains(strings.ToLower(description.String), keyword)
+	case "regex":
+		return rule.re.MatchString(title) || rule.re.MatchString(description.String)
+	case "author":
+		return author.Valid && strings.Contains(strings.ToLower(author.String), strings.ToLower(rule.Pattern))
+	case "domain":
+		u, err := url.Parse(link)
+		if err != nil {
+			return false
+		}
+		host := strings.ToLower(u.Hostname())
+		return host == rule.Pattern || strings.HasSuffix(host, "."+rule.Pattern)
+	case "feed":
+		return rule.FeedID.Valid && rule.FeedID.UUID == feedID
+	}
+	return false
+}
+
+func handlerRuleAdd(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 3 {
+		return fmt.Errorf("usage: %v <%v> <%v> <pattern>", cmd.Name, strings.Join(ruleActions, "|"), strings.Join(ruleKinds, "|"))
+	}
+
+	action, kind, pattern := cmd.Args[0], cmd.Args[1], strings.TrimSpace(cmd.Args[2])
+	if !slices.Contains(ruleActions, action) {
+		return fmt.Errorf("unknown action %q, expected one of: %v", action, strings.Join(ruleActions, ", "))
+	}
+	if !slices.Contains(ruleKinds, kind) {
+		return fmt.Errorf("unknown rule kind %q, expected one of: %v", kind, strings.Join(ruleKinds, ", "))
+	}
+	if pattern == "" {
+		return fmt.Errorf("pattern can't be empty")
+	}
+
+	var feedID uuid.NullUUID
+	switch kind {
+	case "regex":
+		_, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	case "domain":
+		pattern = strings.TrimPrefix(strings.ToLower(pattern), ".")
+	case "feed":
+		feed, err := lookupFeed(ctx, s, pattern)
+		if err != nil {
+			return fmt.Errorf("couldn't find feed %v: %w", pattern, err)
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	rule, err := s.db.CreatePostRule(ctx, database.CreatePostRuleParams{
+		UserID:  user.ID,
+		Kind:    kind,
+		Pattern: pattern,
+		FeedID:  feedID,
+		Action:  action,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't create rule: %w", err)
+	}
+
+	fmt.Printf("Rule created: %v %v %v %q\n", rule.ID, rule.Action, rule.Kind, rule.Pattern)
+	return nil
+}
+
+func handlerRuleList(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	rules, err := s.db.GetPostRulesForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get rules: %w", err)
+	}
+
+	if len(rules) == 0 {
+		fmt.Println("No rules defined.")
+		return nil
+	}
+
+	for _, rule := range rules {
+		fmt.Printf("%v %-9s %-7s %q\n", rule.ID, rule.Action, rule.Kind, rule.Pattern)
+	}
+
+	return nil
+}
+
+func handlerRuleRemove(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v <id>", cmd.Name)
+	}
+
+	id, err := uuid.Parse(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("invalid rule id: %s", cmd.Args[0])
+	}
+
+	deleted, err := s.db.DeletePostRule(ctx, database.DeletePostRuleParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't delete rule: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("no rule with id %v", id)
+	}
+
+	fmt.Println("Rule removed!")
+	return nil
+}
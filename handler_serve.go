@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const defaultServeAddr = "localhost:8080"
+
+// shutdownTimeout is how long serve and agg wait for work in flight when
+// they are stopped.
+const shutdownTimeout = 30 * time.Second
+
+func handlerServe(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.Args) > 1 {
+		return fmt.Errorf("usage: %v [addr]", cmd.Name)
+	}
+
+	addr := defaultServeAddr
+	if len(cmd.Args) == 1 {
+		addr = cmd.Args[0]
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newServeMux(s),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Printf("Serving Fever API on http://%s/fever/ and gator API on http://%s/api/\n", addr, addr)
+	return serveUntilDone(ctx, srv)
+}
+
+// newServeMux routes the Fever API, WebSub callbacks and the gator API.
+func newServeMux(s *state) *http.ServeMux {
+	mux := http.NewServeMux()
+	// Fever clients are configured with a bare URL and append "?api", so
+	// serve both forms rather than letting the mux redirect a POST.
+	mux.HandleFunc("/fever", feverHandler(s))
+	mux.HandleFunc("/fever/", feverHandler(s))
+	mux.HandleFunc("GET /websub/{feedID}", websubVerifyHandler(s))
+	mux.HandleFunc("POST /websub/{feedID}", websubNotifyHandler(s))
+	mux.HandleFunc("POST /api/login", apiLoginHandler(s))
+	mux.HandleFunc("GET /api/me", apiMeHandler(s))
+	mux.HandleFunc("GET /api/following", apiFollowingHandler(s))
+	mux.HandleFunc("GET /api/posts", apiPostsHandler(s))
+	return mux
+}
+
+// serveUntilDone runs srv until ctx is done, then shuts it down, giving the
+// requests in flight up to shutdownTimeout to finish.
+func serveUntilDone(ctx context.Context, srv *http.Server) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshalling JSON: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string, err error) {
+	if err != nil {
+		log.Println(err)
+	}
+	respondWithJSON(w, code, map[string]string{"error": msg})
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// normalizeTag trims a tag name and rejects ones that can't round-trip
+// through the comma separated lists used by `following` and OPML.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return "", fmt.Errorf("tag can't be empty")
+	}
+	if strings.Contains(tag, ",") {
+		return "", fmt.Errorf("tag can't contain a comma, got: %s", tag)
+	}
+	return tag, nil
+}
+
+// tagFollow adds tag to the user's follow of feed. It reports whether the
+// follow was already tagged.
+func tagFollow(ctx context.Context, s *state, user database.User, feed database.Feed, tag string) (bool, error) {
+	t, err := s.db.UpsertTag(ctx, database.UpsertTagParams{
+		UserID: user.ID,
+		Name:   tag,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	tagged, err := s.db.TagFeedFollow(ctx, database.TagFeedFollowParams{
+		TagID:  t.ID,
+		UserID: user.ID,
+		FeedID: feed.ID,
+	})
+	if err != nil {
+		if isDuplicateKey(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if tagged == 0 {
+		err = s.db.DeleteUnusedTags(ctx, user.ID)
+		if err != nil {
+			return false, err
+		}
+		return false, fmt.Errorf("you don't follow %v", feed.Url)
+	}
+
+	return false, nil
+}
+
+func handlerTag(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %v <url> <tag>", cmd.Name)
+	}
+
+	tag, err := normalizeTag(cmd.Args[1])
+	if err != nil {
+		return err
+	}
+
+	feed, err := lookupFeed(ctx, s, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	already, err := tagFollow(ctx, s, user, feed, tag)
+	if err != nil {
+		return err
+	}
+
+	if already {
+		fmt.Printf("%v is already tagged %v\n", feed.Name, tag)
+		return nil
+	}
+
+	fmt.Printf("Tagged %v as %v\n", feed.Name, tag)
+	return nil
+}
+
+func handlerUntag(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %v <url> <tag>", cmd.Name)
+	}
+
+	feed, err := lookupFeed(ctx, s, cmd.Args[0])
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.db.UntagFeedFollow(ctx, database.UntagFeedFollowParams{
+		UserID: user.ID,
+		FeedID: feed.ID,
+		Name:   strings.TrimSpace(cmd.Args[1]),
+	})
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return fmt.Errorf("%v isn't tagged %v", feed.Name, cmd.Args[1])
+	}
+
+	err = s.db.DeleteUnusedTags(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Removed tag %v from %v\n", cmd.Args[1], feed.Name)
+	return nil
+}
+
+// splitTags splits the comma separated tag list returned by
+// GetFeedFollowsForUser.
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, ",")
+}
@@ -2,21 +2,37 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/inscrutabletaco/gator/internal/database"
 )
 
-func handlerRegister(s *state, cmd command) error {
+// User roles. Admins can manage users, reset the database and change any
+// feed; members can only change the feeds they added.
+const (
+	roleAdmin  = "admin"
+	roleMember = "member"
+)
+
+func handlerRegister(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.Args) != 1 {
 		return fmt.Errorf("usage: %v <name>", cmd.Name)
 	}
 
 	name := cmd.Args[0]
 
-	user, err := s.db.CreateUser(context.Background(), database.CreateUserParams{
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+
+	user, err := s.db.CreateUser(ctx, database.CreateUserParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
@@ -26,6 +42,11 @@ func handlerRegister(s *state, cmd command) error {
 		return fmt.Errorf("couldn't create user: %w", err)
 	}
 
+	err = setPassword(ctx, s, user, password)
+	if err != nil {
+		return fmt.Errorf("couldn't set password: %w", err)
+	}
+
 	err = s.cfg.SetUser(user.Name)
 	if err != nil {
 		return fmt.Errorf("couldn't set current user: %w", err)
@@ -36,17 +57,39 @@ func handlerRegister(s *state, cmd command) error {
 	return nil
 }
 
-func handlerLogin(s *state, cmd command) error {
-	if len(cmd.Args) != 1 {
-		return fmt.Errorf("usage: %s <name>", cmd.Name)
+func handlerLogin(ctx context.Context, s *state, cmd command) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	server := fs.String("server", "", "log in to the gator server at this url instead of the database")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
 	}
-	name := cmd.Args[0]
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s [--server <url>] <name>", cmd.Name)
+	}
+	name := args[0]
 
-	_, err := s.db.GetUser(context.Background(), name)
+	if *server != "" {
+		return remoteLogin(ctx, s, *server, name)
+	}
+
+	user, err := s.db.GetUser(ctx, name)
 	if err != nil {
 		return fmt.Errorf("couldn't find user: %w", err)
 	}
 
+	if !user.PasswordHash.Valid {
+		return noPasswordError(user)
+	}
+	password, err := readPassword("Password: ")
+	if err != nil {
+		return err
+	}
+	err = checkPassword(user, password)
+	if err != nil {
+		return err
+	}
+
 	err = s.cfg.SetUser(name)
 	if err != nil {
 		return fmt.Errorf("couldn't set current user: %w", err)
@@ -59,40 +102,273 @@ func handlerLogin(s *state, cmd command) error {
 func printUser(user database.User) {
 	fmt.Printf(" * ID:      %v\n", user.ID)
 	fmt.Printf(" * Name:    %v\n", user.Name)
+	fmt.Printf(" * Role:    %v\n", user.Role)
 }
 
-func handlerReset(s *state, cmd command) error {
+func handlerGetUsers(ctx context.Context, s *state, cmd command) error {
 	if len(cmd.Args) != 0 {
 		return fmt.Errorf("usage: %v", cmd.Name)
 	}
+	users, err := s.db.GetUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't get users: %w", err)
+	}
+
+	for i := range users {
+		name := users[i].Name
+		if users[i].Role == roleAdmin {
+			name += " [admin]"
+		}
+		if users[i].Name == s.cfg.CurrentUserName {
+			fmt.Println("*", name, "(current)")
+		} else {
+			fmt.Println("*", name)
+		}
+	}
+
+	return nil
+
+}
+
+func handlerUserRole(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %v <name> <%v|%v>", cmd.Name, roleAdmin, roleMember)
+	}
+	name, role := cmd.Args[0], cmd.Args[1]
+	if role != roleAdmin && role != roleMember {
+		return fmt.Errorf("role must be %v or %v, got: %v", roleAdmin, roleMember, role)
+	}
+
+	target, err := s.db.GetUser(ctx, name)
+	if err != nil {
+		return fmt.Errorf("couldn't find user %v: %w", name, err)
+	}
+	if target.Role == roleAdmin && role != roleAdmin {
+		admins, err := s.db.CountAdmins(ctx)
+		if err != nil {
+			return fmt.Errorf("couldn't count admins: %w", err)
+		}
+		if admins <= 1 {
+			return fmt.Errorf("%v is the only admin, make someone else an admin first", name)
+		}
+	}
+
+	_, err = s.db.SetUserRole(ctx, database.SetUserRoleParams{
+		Name: name,
+		Role: role,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't set role: %w", err)
+	}
+
+	fmt.Printf("Set role of %v to %v\n", name, role)
+	return nil
+}
+
+// handlerUserPasswd gives a user registered before passwords existed their
+// first password. Users change their own with passwd.
+func handlerUserPasswd(ctx context.Context, s *state, cmd command, admin database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v <name>", cmd.Name)
+	}
+	name := cmd.Args[0]
+
+	target, err := s.db.GetUser(ctx, name)
+	if err != nil {
+		return fmt.Errorf("couldn't find user %v: %w", name, err)
+	}
+	if target.PasswordHash.Valid {
+		return fmt.Errorf("%v already has a password, they can change it with: gator passwd", name)
+	}
+	err = confirmPassword(admin, fmt.Sprintf("Password for %v: ", admin.Name))
+	if err != nil {
+		return err
+	}
 
-	err := s.db.DeleteUsers(context.Background())
+	password, err := readNewPassword()
+	if err != nil {
+		return err
+	}
+	err = setPassword(ctx, s, target, password)
 	if err != nil {
-		return fmt.Errorf("couldn't delete users: %w", err)
+		return fmt.Errorf("couldn't set password: %w", err)
 	}
 
-	fmt.Println("Deleted users successfully!")
+	fmt.Printf("Set password for %v\n", name)
 	return nil
+}
+
+func handlerUserDelete(ctx context.Context, s *state, cmd command, admin database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	transferTo := fs.String("transfer-to", "", "give the feeds the user added to this user")
+	cascade := fs.Bool("cascade", false, "delete the feeds the user added, with their posts, for every follower")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %v <name> [--transfer-to <name> | --cascade]", cmd.Name)
+	}
+	if *transferTo != "" && *cascade {
+		return fmt.Errorf("--transfer-to and --cascade can't be used together")
+	}
+
+	user, err := s.db.GetUser(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("couldn't find user %v: %w", args[0], err)
+	}
+	if user.ID == admin.ID {
+		return fmt.Errorf("you can't delete yourself, log in as another admin first")
+	}
+	if user.Role == roleAdmin {
+		admins, err := s.db.CountAdmins(ctx)
+		if err != nil {
+			return fmt.Errorf("couldn't count admins: %w", err)
+		}
+		if admins <= 1 {
+			return fmt.Errorf("%v is the only admin, make someone else an admin first", user.Name)
+		}
+	}
+
+	stats, err := s.db.GetUserStats(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't count %v's feeds: %w", user.Name, err)
+	}
 
+	if *transferTo != "" {
+		heir, err := s.db.GetUser(ctx, *transferTo)
+		if err != nil {
+			return fmt.Errorf("couldn't find user %v: %w", *transferTo, err)
+		}
+		if heir.ID == user.ID {
+			return fmt.Errorf("can't transfer %v's feeds to themselves", user.Name)
+		}
+		// One query, so the user is never deleted with feeds that weren't
+		// transferred, nor left behind without them.
+		_, err = s.db.TransferFeedsAndDeleteUser(ctx, database.TransferFeedsAndDeleteUserParams{
+			ID:       user.ID,
+			ToUserID: heir.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("couldn't transfer feeds and delete user: %w", err)
+		}
+		fmt.Printf("Transferred %d feeds to %v\n", stats.FeedCount, heir.Name)
+	} else {
+		if stats.FeedCount > 0 && !*cascade {
+			return fmt.Errorf("%v added %d feeds, keep them with --transfer-to <name> or delete them with --cascade", user.Name, stats.FeedCount)
+		}
+		_, err = s.db.DeleteUser(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("couldn't delete user: %w", err)
+		}
+	}
+
+	fmt.Printf("Deleted %v", user.Name)
+	if *cascade && stats.FeedCount > 0 {
+		fmt.Printf(" and the %d feeds they added", stats.FeedCount)
+	}
+	fmt.Println()
+	return nil
 }
 
-func handlerGetUsers(s *state, cmd command) error {
-	if len(cmd.Args) != 0 {
-		return fmt.Errorf("usage: %v", cmd.Name)
+func handlerUserRename(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 2 {
+		return fmt.Errorf("usage: %v <name> <new name>", cmd.Name)
 	}
-	users, err := s.db.GetUsers(context.Background())
+	oldName, newName := cmd.Args[0], strings.TrimSpace(cmd.Args[1])
+	if newName == "" {
+		return fmt.Errorf("name can't be empty")
+	}
+
+	target, err := s.db.GetUser(ctx, oldName)
 	if err != nil {
-		return fmt.Errorf("couldn't get users: %w", err)
+		return fmt.Errorf("couldn't find user %v: %w", oldName, err)
+	}
+	if target.ID != user.ID && user.Role != roleAdmin {
+		return fmt.Errorf("only admins can rename other users")
 	}
 
-	for i := range users {
-		if users[i].Name == s.cfg.CurrentUserName {
-			fmt.Println("*", users[i].Name, "(current)")
-		} else {
-			fmt.Println("*", users[i].Name)
+	_, err = s.db.RenameUser(ctx, database.RenameUserParams{
+		ID:   target.ID,
+		Name: newName,
+	})
+	if err != nil && isDuplicateKey(err) {
+		return fmt.Errorf("there is already a user named %v", newName)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't rename user: %w", err)
+	}
+
+	if s.cfg.CurrentUserName == oldName {
+		err = s.cfg.SetUser(newName)
+		if err != nil {
+			return fmt.Errorf("couldn't set current user: %w", err)
 		}
 	}
 
+	fmt.Printf("Renamed %v to %v\n", oldName, newName)
+	if target.FeverApiKey.Valid {
+		// Fever api keys are derived from the user name.
+		fmt.Println("Their Fever password was reset, set it again with: gator fever-password")
+	}
 	return nil
+}
+
+func handlerUserInfo(ctx context.Context, s *state, cmd command, current database.User) error {
+	if len(cmd.Args) > 1 {
+		return fmt.Errorf("usage: %v [name]", cmd.Name)
+	}
+	user := current
+	if len(cmd.Args) == 1 && cmd.Args[0] != current.Name {
+		// Checked before looking the user up, so members can't find out
+		// who is registered.
+		if current.Role != roleAdmin {
+			return fmt.Errorf("only admins can see other users' info")
+		}
+		var err error
+		user, err = s.db.GetUser(ctx, cmd.Args[0])
+		if err != nil {
+			return fmt.Errorf("couldn't find user %v: %w", cmd.Args[0], err)
+		}
+	}
+	stats, err := s.db.GetUserStats(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get stats: %w", err)
+	}
+
+	lastRead := "never"
+	readAt, err := s.db.GetLastPostReadAt(ctx, user.ID)
+	if err == nil {
+		lastRead = readAt.Format("2006-01-02 15:04")
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("couldn't get last read post: %w", err)
+	}
 
+	tokens, err := s.db.GetApiTokensForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get tokens: %w", err)
+	}
+	lastAPIUse := "never"
+	var lastUsed time.Time
+	for _, token := range tokens {
+		if token.LastUsedAt.Valid && token.LastUsedAt.Time.After(lastUsed) {
+			lastUsed = token.LastUsedAt.Time
+			lastAPIUse = lastUsed.Format("2006-01-02 15:04")
+		}
+	}
+
+	fmt.Printf(" * ID:           %v\n", user.ID)
+	fmt.Printf(" * Name:         %v\n", user.Name)
+	fmt.Printf(" * Role:         %v\n", user.Role)
+	fmt.Printf(" * Created:      %v\n", user.CreatedAt.Format("2006-01-02 15:04"))
+	fmt.Printf(" * Feeds added:  %d\n", stats.FeedCount)
+	fmt.Printf(" * Following:    %d\n", stats.FollowCount)
+	fmt.Printf(" * Posts read:   %d\n", stats.ReadCount)
+	fmt.Printf(" * Starred:      %d\n", stats.StarCount)
+	fmt.Printf(" * Last read:    %v\n", lastRead)
+	fmt.Printf(" * Last API use: %v\n", lastAPIUse)
+	if user.LastDigestAt.Valid {
+		fmt.Printf(" * Last digest:  %v\n", user.LastDigestAt.Time.Format("2006-01-02 15:04"))
+	}
+	return nil
 }
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+func TestRegister(t *testing.T) {
+	s := newTestState(t)
+
+	alice := registerUser(t, s, "alice")
+	bob := registerUser(t, s, "bob")
+	if alice.Role != roleAdmin {
+		t.Errorf("first user has role %v, want %v", alice.Role, roleAdmin)
+	}
+	if bob.Role != roleMember {
+		t.Errorf("second user has role %v, want %v", bob.Role, roleMember)
+	}
+	if !bob.PasswordHash.Valid {
+		t.Error("register didn't set a password")
+	}
+	if s.cfg.CurrentUserName != "bob" {
+		t.Errorf("current user is %q, want bob", s.cfg.CurrentUserName)
+	}
+
+	withStdin(t, testPassword)
+	err := runCommand(s, handlerRegister, "register", "bob")
+	if err == nil {
+		t.Error("registering a taken name succeeded")
+	}
+
+	withStdin(t, "short")
+	err = runCommand(s, handlerRegister, "register", "carol")
+	if err == nil {
+		t.Error("registering with a short password succeeded")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	registerUser(t, s, "bob")
+
+	withStdin(t, "wrong password")
+	err := runCommand(s, handlerLogin, "login", "alice")
+	if err == nil {
+		t.Error("login with a wrong password succeeded")
+	}
+	if s.cfg.CurrentUserName != "bob" {
+		t.Errorf("failed login switched the current user to %q", s.cfg.CurrentUserName)
+	}
+
+	withStdin(t, testPassword)
+	err = runCommand(s, handlerLogin, "login", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.cfg.CurrentUserName != "alice" {
+		t.Errorf("current user is %q, want alice", s.cfg.CurrentUserName)
+	}
+
+	err = runCommand(s, handlerLogin, "login", "nobody")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("login as an unknown user returned %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestUserWithoutPassword(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	registerUser(t, s, "bob")
+	// Users registered before gator had passwords have none.
+	now := time.Now().UTC()
+	_, err := s.db.CreateUser(ctx, database.CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	withStdin(t, "")
+	err = runCommand(s, handlerLogin, "login", "carol")
+	if err == nil || s.cfg.CurrentUserName == "carol" {
+		t.Error("logged in as a user without a password")
+	}
+
+	loginAs(t, s, "carol")
+	withStdin(t, testPassword, testPassword)
+	err = runCommand(s, middlewareLoggedIn(handlerPasswd), "passwd")
+	if err == nil {
+		t.Error("a user without a password set one themselves")
+	}
+
+	userPasswd := middlewareAdmin(handlerUserPasswd)
+	loginAs(t, s, "bob")
+	withStdin(t, testPassword, testPassword)
+	err = runCommand(s, userPasswd, "user passwd", "carol")
+	if err == nil {
+		t.Error("a member set someone's password")
+	}
+
+	loginAs(t, s, "alice")
+	withStdin(t, "wrong password", testPassword)
+	err = runCommand(s, userPasswd, "user passwd", "carol")
+	if err == nil {
+		t.Error("an admin set a password without giving their own")
+	}
+	withStdin(t, testPassword, testPassword)
+	err = runCommand(s, userPasswd, "user passwd", "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withStdin(t, testPassword, testPassword)
+	err = runCommand(s, userPasswd, "user passwd", "carol")
+	if err == nil {
+		t.Error("an admin replaced a password carol already has")
+	}
+
+	withStdin(t, testPassword)
+	err = runCommand(s, handlerLogin, "login", "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.cfg.CurrentUserName != "carol" {
+		t.Errorf("current user is %q, want carol", s.cfg.CurrentUserName)
+	}
+}
+
+func TestUserRole(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	registerUser(t, s, "bob")
+	userRole := middlewareAdmin(handlerUserRole)
+
+	err := runCommand(s, userRole, "user role", "bob", roleAdmin)
+	if err == nil {
+		t.Error("a member made themselves an admin")
+	}
+
+	loginAs(t, s, "alice")
+	err = runCommand(s, userRole, "user role", "alice", roleMember)
+	if err == nil {
+		t.Error("the only admin demoted themselves")
+	}
+	err = runCommand(s, userRole, "user role", "bob", "owner")
+	if err == nil {
+		t.Error("set an unknown role")
+	}
+
+	err = runCommand(s, userRole, "user role", "bob", roleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = runCommand(s, userRole, "user role", "alice", roleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := s.db.GetUser(context.Background(), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alice.Role != roleMember {
+		t.Errorf("alice has role %v, want %v", alice.Role, roleMember)
+	}
+}
+
+func TestUserDelete(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	alice := registerUser(t, s, "alice")
+	bob := registerUser(t, s, "bob")
+	registerUser(t, s, "carol")
+	userDelete := middlewareAdmin(handlerUserDelete)
+
+	addFeed(t, s, "bob", "Bob's blog", "https://bob.example/feed")
+	addFeed(t, s, "carol", "Carol's blog", "https://carol.example/feed")
+	loginAs(t, s, "alice")
+
+	err := runCommand(s, userDelete, "user delete", "alice")
+	if err == nil {
+		t.Error("an admin deleted themselves")
+	}
+	err = runCommand(s, userDelete, "user delete", "bob")
+	if err == nil {
+		t.Error("deleted a user who added feeds without --transfer-to or --cascade")
+	}
+	err = runCommand(s, userDelete, "user delete", "bob", "--transfer-to", "bob")
+	if err == nil {
+		t.Error("transferred a user's feeds to themselves")
+	}
+
+	err = runCommand(s, userDelete, "user delete", "bob", "--transfer-to", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.db.GetUser(ctx, "bob")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("bob still exists: %v", err)
+	}
+	feed, err := s.db.GetFeedByUrl(ctx, "https://bob.example/feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.UserID != alice.ID {
+		t.Errorf("bob's feed belongs to %v, want alice (%v)", feed.UserID, alice.ID)
+	}
+	follows, err := s.db.GetFollowedFeeds(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(follows) != 0 {
+		t.Errorf("bob still follows %d feeds", len(follows))
+	}
+
+	err = runCommand(s, userDelete, "user delete", "carol", "--cascade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.db.GetFeedByUrl(ctx, "https://carol.example/feed")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("carol's feed wasn't deleted: %v", err)
+	}
+}
+
+func TestUserRename(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	registerUser(t, s, "bob")
+	userRename := middlewareLoggedIn(handlerUserRename)
+
+	err := runCommand(s, userRename, "user rename", "alice", "mallory")
+	if err == nil {
+		t.Error("a member renamed someone else")
+	}
+	err = runCommand(s, userRename, "user rename", "bob", "alice")
+	if err == nil {
+		t.Error("renamed a user to a taken name")
+	}
+
+	err = runCommand(s, userRename, "user rename", "bob", "robert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.cfg.CurrentUserName != "robert" {
+		t.Errorf("current user is %q, want robert", s.cfg.CurrentUserName)
+	}
+	_, err = s.db.GetUser(ctx, "robert")
+	if err != nil {
+		t.Errorf("couldn't find renamed user: %v", err)
+	}
+
+	loginAs(t, s, "alice")
+	err = runCommand(s, userRename, "user rename", "robert", "bob")
+	if err != nil {
+		t.Errorf("an admin couldn't rename someone else: %v", err)
+	}
+}
+
+func TestUserInfo(t *testing.T) {
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	registerUser(t, s, "bob")
+	info := middlewareLoggedIn(handlerUserInfo)
+
+	for _, args := range [][]string{{}, {"bob"}} {
+		err := runCommand(s, info, "user info", args...)
+		if err != nil {
+			t.Errorf("bob asking for info on %q: %v", args, err)
+		}
+	}
+	for _, name := range []string{"alice", "nobody"} {
+		err := runCommand(s, info, "user info", name)
+		if err == nil || errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("a member asking for info on %v returned %v, want a refusal", name, err)
+		}
+	}
+
+	loginAs(t, s, "alice")
+	err := runCommand(s, info, "user info", "bob")
+	if err != nil {
+		t.Errorf("an admin asking for info on bob: %v", err)
+	}
+	err = runCommand(s, info, "user info", "nobody")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("info on an unknown user returned %v, want sql.ErrNoRows", err)
+	}
+
+	// Logged out, nobody's info is shown.
+	err = s.cfg.SetUser("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = runCommand(s, info, "user info", "alice")
+	if err == nil {
+		t.Error("showed info without being logged in")
+	}
+}
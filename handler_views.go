@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// stringsFlag is a flag that may be given more than once.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// parseWindowSeconds parses a date window such as "7d" or "36h" into the
+// whole seconds it is stored as. Days aren't a time.Duration unit, so they
+// are handled here. Windows that don't fit, or would be stored as 0, are
+// refused rather than truncated, since a retention of 0 prunes everything.
+func parseWindowSeconds(s string) (int32, error) {
+	const day = 24 * 60 * 60
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseInt(days, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid window %q", s)
+		}
+		if n > math.MaxInt32/day {
+			return 0, fmt.Errorf("window %q is too long, the longest is %dd", s, math.MaxInt32/day)
+		}
+		return int32(n * day), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q", s)
+	}
+	if d%time.Second != 0 {
+		return 0, fmt.Errorf("window %q must be a whole number of seconds", s)
+	}
+	if d/time.Second > math.MaxInt32 {
+		return 0, fmt.Errorf("window %q is too long, the longest is %dd", s, math.MaxInt32/day)
+	}
+	return int32(d / time.Second), nil
+}
+
+// formatWindow prints a window the way it is usually given to parseWindowSeconds.
+func formatWindow(seconds int32) string {
+	d := time.Duration(seconds) * time.Second
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+func handlerViewSave(ctx context.Context, s *state, cmd command, user database.User) error {
+	var feedURLs, tags stringsFlag
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	fs.Var(&feedURLs, "feed", "only posts from the feed with this url (repeatable)")
+	fs.Var(&tags, "tag", "only posts from feeds with this tag (repeatable)")
+	keyword := fs.String("match", "", "only posts whose title or description contains this text")
+	since := fs.String("since", "", "only posts published within this window, e.g. 7d or 12h")
+	unread := fs.Bool("unread", false, "only unread posts")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %v <name> [--feed <url>]... [--tag <tag>]... [--match <keyword>] [--since <window>] [--unread]", cmd.Name)
+	}
+
+	name := strings.TrimSpace(args[0])
+	if name == "" {
+		return fmt.Errorf("view name can't be empty")
+	}
+
+	var feedIDs []uuid.UUID
+	for _, feedURL := range feedURLs {
+		feed, err := lookupFeed(ctx, s, feedURL)
+		if err != nil {
+			return fmt.Errorf("couldn't find feed %v: %w", feedURL, err)
+		}
+		feedIDs = append(feedIDs, feed.ID)
+	}
+
+	var tagNames []string
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return err
+		}
+		tagNames = append(tagNames, tag)
+	}
+
+	var maxAge sql.NullInt32
+	if *since != "" {
+		seconds, err := parseWindowSeconds(*since)
+		if err != nil {
+			return err
+		}
+		maxAge = sql.NullInt32{Int32: seconds, Valid: true}
+	}
+
+	view, err := s.db.SaveView(ctx, database.SaveViewParams{
+		UserID:        user.ID,
+		Name:          name,
+		FeedIds:       feedIDs,
+		Tags:          tagNames,
+		Keyword:       sql.NullString{String: *keyword, Valid: *keyword != ""},
+		MaxAgeSeconds: maxAge,
+		UnreadOnly:    *unread,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't save view: %w", err)
+	}
+
+	fmt.Printf("Saved view %v, run it with: gator browse --view %q\n", view.Name, view.Name)
+	return nil
+}
+
+func handlerViewRemove(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v <name>", cmd.Name)
+	}
+
+	deleted, err := s.db.DeleteView(ctx, database.DeleteViewParams{
+		UserID: user.ID,
+		Name:   cmd.Args[0],
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't delete view: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("no view named %v", cmd.Args[0])
+	}
+
+	fmt.Println("View removed!")
+	return nil
+}
+
+func handlerViews(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	views, err := s.db.GetViewsForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get views: %w", err)
+	}
+
+	if len(views) == 0 {
+		fmt.Println("No views saved.")
+		return nil
+	}
+
+	for _, view := range views {
+		fmt.Printf("%v\n", view.Name)
+		for _, feedID := range view.FeedIds {
+			feed, err := s.db.GetFeedByID(ctx, feedID)
+			if errors.Is(err, sql.ErrNoRows) {
+				fmt.Printf("  feed:   %v (removed)\n", feedID)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("  feed:   %v\n", feed.Url)
+		}
+		if len(view.Tags) > 0 {
+			fmt.Printf("  tags:   %v\n", strings.Join(view.Tags, ", "))
+		}
+		if view.Keyword.Valid {
+			fmt.Printf("  match:  %v\n", view.Keyword.String)
+		}
+		if view.MaxAgeSeconds.Valid {
+			fmt.Printf("  since:  %v\n", formatWindow(view.MaxAgeSeconds.Int32))
+		}
+		if view.UnreadOnly {
+			fmt.Println("  unread only")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import "testing"
+
+func TestParseWindowSeconds(t *testing.T) {
+	for _, test := range []struct {
+		window string
+		want   int32
+	}{
+		{"1s", 1},
+		{"90m", 90 * 60},
+		{"7d", 7 * 24 * 60 * 60},
+		{"24855d", 24855 * 24 * 60 * 60},
+		{"596523h14m7s", 1<<31 - 1},
+	} {
+		got, err := parseWindowSeconds(test.window)
+		if err != nil || got != test.want {
+			t.Errorf("parseWindowSeconds(%q) = %v, %v, want %v", test.window, got, err, test.want)
+		}
+	}
+
+	for _, window := range []string{
+		"", "0", "0d", "-1d", "-5m", "d", "7 days",
+		// Shorter than a second, or not whole seconds, would be stored as
+		// something else.
+		"500ms", "1500ms",
+		// Longer than an int32 of seconds, or than a time.Duration.
+		"24856d", "30000d", "596523h14m8s", "200000d", "3000000h",
+	} {
+		got, err := parseWindowSeconds(window)
+		if err == nil {
+			t.Errorf("parseWindowSeconds(%q) = %v, want an error", window, got)
+		}
+	}
+}
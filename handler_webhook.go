@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+const (
+	webhookBatchSize    = 20
+	webhookMaxAttempts  = 8
+	webhookPollInterval = 10 * time.Second
+	webhookTimeout      = 15 * time.Second
+	webhookMaxBackoff   = time.Hour
+)
+
+type webhookPayload struct {
+	Event      string      `json:"event"`
+	DeliveryID uuid.UUID   `json:"delivery_id"`
+	Feed       webhookFeed `json:"feed"`
+	Post       webhookPost `json:"post"`
+}
+
+type webhookFeed struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+type webhookPost struct {
+	Title       string     `json:"title"`
+	Url         string     `json:"url"`
+	Description string     `json:"description,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+func handlerWebhookAdd(ctx context.Context, s *state, cmd command, user database.User) error {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	secret := fs.String("secret", "", "shared secret used to sign payloads")
+	feedURL := fs.String("feed", "", "only deliver posts from the feed with this url")
+	keyword := fs.String("match", "", "only deliver posts whose title or description contains this text")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %v <url> [--secret <secret>] [--feed <url>] [--match <keyword>]", cmd.Name)
+	}
+
+	target, err := url.Parse(args[0])
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("webhook url must be an absolute http(s) url, got: %s", args[0])
+	}
+
+	var feedID uuid.NullUUID
+	if *feedURL != "" {
+		feed, err := lookupFeed(ctx, s, *feedURL)
+		if err != nil {
+			return fmt.Errorf("couldn't find feed %v: %w", *feedURL, err)
+		}
+		feedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	webhook, err := s.db.CreateWebhook(ctx, database.CreateWebhookParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now().UTC(),
+		UpdatedAt: time.Now().UTC(),
+		UserID:    user.ID,
+		Url:       target.String(),
+		Secret:    sql.NullString{String: *secret, Valid: *secret != ""},
+		FeedID:    feedID,
+		Keyword:   sql.NullString{String: *keyword, Valid: *keyword != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't create webhook: %w", err)
+	}
+
+	fmt.Printf("Webhook created: %v -> %v\n", webhook.ID, webhook.Url)
+	return nil
+}
+
+func handlerWebhookList(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 0 {
+		return fmt.Errorf("usage: %v", cmd.Name)
+	}
+
+	webhooks, err := s.db.GetWebhooksForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get webhooks: %w", err)
+	}
+
+	if len(webhooks) == 0 {
+		fmt.Println("No webhooks registered.")
+		return nil
+	}
+
+	for _, webhook := range webhooks {
+		fmt.Printf("%v %v\n", webhook.ID, webhook.Url)
+		if webhook.FeedUrl.Valid {
+			fmt.Printf("  feed:   %v\n", webhook.FeedUrl.String)
+		}
+		if webhook.Keyword.Valid {
+			fmt.Printf("  match:  %v\n", webhook.Keyword.String)
+		}
+		fmt.Printf("  signed: %v\n", webhook.Secret.Valid)
+	}
+
+	return nil
+}
+
+func handlerWebhookRemove(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %v <id>", cmd.Name)
+	}
+
+	id, err := uuid.Parse(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("invalid webhook id: %s", cmd.Args[0])
+	}
+
+	deleted, err := s.db.DeleteWebhook(ctx, database.DeleteWebhookParams{
+		ID:     id,
+		UserID: user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't delete webhook: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("no webhook with id %v", id)
+	}
+
+	fmt.Println("Webhook removed!")
+	return nil
+}
+
+func handlerWebhookLog(ctx context.Context, s *state, cmd command, user database.User) error {
+	limit := 20
+	if len(cmd.Args) > 1 {
+		return fmt.Errorf("usage: %v [limit]", cmd.Name)
+	}
+	if len(cmd.Args) == 1 {
+		parsedLimit, err := strconv.Atoi(cmd.Args[0])
+		if err != nil || parsedLimit <= 0 {
+			return fmt.Errorf("limit must be a positive integer, got: %s", cmd.Args[0])
+		}
+		limit = parsedLimit
+	}
+
+	deliveries, err := s.db.GetWebhookDeliveriesForUser(ctx, database.GetWebhookDeliveriesForUserParams{
+		UserID: user.ID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't get webhook deliveries: %w", err)
+	}
+
+	for _, d := range deliveries {
+		code := "-"
+		if d.LastStatusCode.Valid {
+			code = strconv.Itoa(int(d.LastStatusCode.Int32))
+		}
+		fmt.Printf("%s %-9s %3s attempts=%d %v\n", d.UpdatedAt.Format("2006-01-02 15:04"), d.Status, code, d.Attempts, d.WebhookUrl)
+		fmt.Printf("  post:  %v\n", d.PostTitle)
+		if d.LastError.Valid {
+			fmt.Printf("  error: %v\n", d.LastError.String)
+		}
+	}
+
+	return nil
+}
+
+// deliverWebhooks sends queued deliveries until ctx is done. It runs on its
+// own goroutine next to the agg loop so a slow endpoint never holds up
+// fetching.
+func deliverWebhooks(ctx context.Context, s *state) {
+	client := &http.Client{
+		Timeout: webhookTimeout,
+	}
+
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		err := deliverPendingWebhooks(ctx, s, client)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to deliver webhooks: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverPendingWebhooks sends deliveries in batches until none are due or
+// ctx is done. A batch that was claimed is sent in full even if ctx is done
+// meanwhile, since its deliveries wouldn't be retried for minutes.
+func deliverPendingWebhooks(ctx context.Context, s *state, client *http.Client) error {
+	for ctx.Err() == nil {
+		deliveries, err := s.db.ClaimWebhookDeliveries(ctx, webhookBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(deliveries) == 0 {
+			return nil
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func(delivery database.WebhookDelivery) {
+				defer wg.Done()
+				deliverWebhook(context.WithoutCancel(ctx), s, client, delivery)
+			}(delivery)
+		}
+		wg.Wait()
+	}
+	return nil
+}
+
+func deliverWebhook(ctx context.Context, s *state, client *http.Client, delivery database.WebhookDelivery) {
+	statusCode, err := sendWebhook(ctx, s, client, delivery)
+	if err == nil {
+		err = s.db.MarkWebhookDeliverySucceeded(ctx, database.MarkWebhookDeliverySucceededParams{
+			ID:             delivery.ID,
+			LastStatusCode: sql.NullInt32{Int32: int32(statusCode), Valid: true},
+		})
+		if err != nil {
+			log.Printf("Failed to record webhook delivery %v: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	status := "pending"
+	if delivery.Attempts >= webhookMaxAttempts {
+		status = "failed"
+	}
+
+	err = s.db.MarkWebhookDeliveryFailed(ctx, database.MarkWebhookDeliveryFailedParams{
+		Status:         status,
+		RetrySeconds:   int32(webhookBackoff(delivery.Attempts).Seconds()),
+		LastStatusCode: sql.NullInt32{Int32: int32(statusCode), Valid: statusCode != 0},
+		LastError:      sql.NullString{String: err.Error(), Valid: true},
+		ID:             delivery.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to record webhook delivery %v: %v", delivery.ID, err)
+	}
+}
+
+// sendWebhook POSTs the delivery's payload and returns the response status
+// code, which is zero if no response was received.
+func sendWebhook(ctx context.Context, s *state, client *http.Client, delivery database.WebhookDelivery) (int, error) {
+	target, err := s.db.GetWebhookDeliveryPayload(ctx, delivery.ID)
+	if err != nil {
+		return 0, fmt.Errorf("couldn't load payload: %w", err)
+	}
+
+	payload := webhookPayload{
+		Event:      "post.created",
+		DeliveryID: delivery.ID,
+		Feed: webhookFeed{
+			Name: target.FeedName,
+			Url:  target.FeedUrl,
+		},
+		Post: webhookPost{
+			Title:       target.Title,
+			Url:         target.Url,
+			Description: target.Description.String,
+		},
+	}
+	if target.PublishedAt.Valid {
+		payload.Post.PublishedAt = &target.PublishedAt.Time
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", target.WebhookUrl, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gator")
+	req.Header.Set("X-Gator-Event", payload.Event)
+	req.Header.Set("X-Gator-Delivery", delivery.ID.String())
+	if target.Secret.Valid {
+		req.Header.Set("X-Gator-Signature", "sha256="+signWebhook(target.Secret.String, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status: %v", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// signWebhook returns the hex encoded HMAC-SHA256 of body, which receivers
+// can recompute with their copy of the secret to verify a delivery.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff doubles the wait after each failed attempt, starting at
+// thirty seconds and capped at webhookMaxBackoff.
+func webhookBackoff(attempts int32) time.Duration {
+	backoff := 30 * time.Second
+	for i := int32(1); i < attempts && backoff < webhookMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, webhookMaxBackoff)
+}
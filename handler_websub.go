@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// WebSub (https://www.w3.org/TR/websub/) lets a feed's hub push new content
+// to us instead of waiting for agg to poll it. agg subscribes when it sees a
+// hub link in a fetched feed, and `gator serve` answers the hub's callbacks.
+
+const (
+	websubLeaseSeconds = 7 * 24 * 60 * 60
+	websubRenewBefore  = 24 * time.Hour
+	websubRetryAfter   = time.Hour
+	websubMaxBodySize  = 10 << 20
+)
+
+// subscribeWebsub asks the hub advertised by rss, if any, to push updates for
+// feed to gator serve. It does nothing when no callback URL is configured or
+// the existing subscription is still good.
+func subscribeWebsub(ctx context.Context, s *state, feed database.Feed, rss *RSSFeed) error {
+	if s.cfg.WebsubCallbackURL == "" {
+		return nil
+	}
+
+	hub := feedLink(rss, "hub")
+	if hub == "" {
+		return nil
+	}
+	topic := feedLink(rss, "self")
+	if topic == "" {
+		topic = feed.Url
+	}
+
+	sub, err := s.db.GetWebsubSubscription(ctx, feed.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil && !websubNeedsRenewal(sub, hub, topic) {
+		return nil
+	}
+
+	secret, err := randomSecret()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.UpsertWebsubSubscription(ctx, database.UpsertWebsubSubscriptionParams{
+		FeedID:   feed.ID,
+		HubUrl:   hub,
+		TopicUrl: topic,
+		Secret:   secret,
+	})
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{}
+	form.Set("hub.mode", "subscribe")
+	form.Set("hub.topic", topic)
+	form.Set("hub.callback", strings.TrimSuffix(s.cfg.WebsubCallbackURL, "/")+"/websub/"+feed.ID.String())
+	form.Set("hub.secret", secret)
+	form.Set("hub.lease_seconds", strconv.Itoa(websubLeaseSeconds))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", hub, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "gator")
+
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hub %v refused subscription: %v", hub, resp.Status)
+	}
+
+	fmt.Printf("Requested WebSub subscription from %s for feed %s\n", hub, feed.Name)
+	return nil
+}
+
+func websubNeedsRenewal(sub database.WebsubSubscription, hub, topic string) bool {
+	if sub.HubUrl != hub || sub.TopicUrl != topic {
+		return true
+	}
+	if sub.State == "verified" {
+		return !sub.LeaseExpiresAt.Valid || time.Until(sub.LeaseExpiresAt.Time) < websubRenewBefore
+	}
+	// Pending and denied subscriptions are retried now and then in case the
+	// hub lost our request or has changed its mind.
+	return time.Since(sub.UpdatedAt) > websubRetryAfter
+}
+
+func feedLink(rss *RSSFeed, rel string) string {
+	for _, link := range rss.Channel.AtomLinks {
+		if strings.EqualFold(link.Rel, rel) && link.Href != "" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+func randomSecret() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// websubVerifyHandler answers the hub's intent verification for a
+// subscription agg requested, or records that the hub denied it.
+func websubVerifyHandler(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub, ok := websubSubscriptionForRequest(s, w, r)
+		if !ok {
+			return
+		}
+
+		query := r.URL.Query()
+		if query.Get("hub.topic") != sub.TopicUrl {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch query.Get("hub.mode") {
+		case "subscribe":
+			// Only a subscription agg asked for and the hub hasn't
+			// answered yet can be verified, so that a replayed or forged
+			// verification can't revive one that was denied.
+			if sub.State != "pending" {
+				http.NotFound(w, r)
+				return
+			}
+			leaseSeconds, err := strconv.Atoi(query.Get("hub.lease_seconds"))
+			if err != nil || leaseSeconds <= 0 {
+				leaseSeconds = websubLeaseSeconds
+			}
+			err = s.db.VerifyWebsubSubscription(r.Context(), database.VerifyWebsubSubscriptionParams{
+				FeedID:         sub.FeedID,
+				LeaseExpiresAt: sql.NullTime{Time: time.Now().UTC().Add(time.Duration(leaseSeconds) * time.Second), Valid: true},
+			})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "couldn't verify subscription", err)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusOK)
+			io.WriteString(w, query.Get("hub.challenge"))
+		case "denied":
+			err := s.db.DenyWebsubSubscription(r.Context(), sub.FeedID)
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "couldn't record denial", err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			// gator never unsubscribes on its own, so refuse anyone else's
+			// attempt to do it for us.
+			http.NotFound(w, r)
+		}
+	}
+}
+
+// websubNotifyHandler ingests content pushed by a hub through the same path
+// agg uses for polled feeds.
+func websubNotifyHandler(s *state) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub, ok := websubSubscriptionForRequest(s, w, r)
+		if !ok {
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, websubMaxBodySize))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "couldn't read body", err)
+			return
+		}
+
+		if !validWebsubSignature(sub.Secret, r.Header.Get("X-Hub-Signature"), body) {
+			// The spec asks subscribers to acknowledge forged or stale
+			// notifications but otherwise ignore them.
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		rss, err := parseFeed(body)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "couldn't parse feed", err)
+			return
+		}
+
+		feed, err := s.db.GetFeedByID(r.Context(), sub.FeedID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "couldn't get feed", err)
+			return
+		}
+
+		savePosts(r.Context(), s, feed, rss.Channel.Item)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func websubSubscriptionForRequest(s *state, w http.ResponseWriter, r *http.Request) (database.WebsubSubscription, bool) {
+	feedID, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return database.WebsubSubscription{}, false
+	}
+
+	sub, err := s.db.GetWebsubSubscription(r.Context(), feedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return database.WebsubSubscription{}, false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "couldn't get subscription", err)
+		return database.WebsubSubscription{}, false
+	}
+
+	return sub, true
+}
+
+// validWebsubSignature checks an X-Hub-Signature header of the form
+// "<algorithm>=<hex hmac of body>".
+func validWebsubSignature(secret, header string, body []byte) bool {
+	method, signature, ok := strings.Cut(header, "=")
+	if !ok {
+		return false
+	}
+
+	var newHash func() hash.Hash
+	switch method {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
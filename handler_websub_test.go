@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+func TestWebsubVerify(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	registerUser(t, s, "alice")
+	feed := addFeed(t, s, "alice", "Alice's blog", "https://alice.example/feed")
+	topic := "https://alice.example/feed.xml"
+	_, err := s.db.UpsertWebsubSubscription(ctx, database.UpsertWebsubSubscriptionParams{
+		FeedID:   feed.ID,
+		HubUrl:   "https://hub.example/",
+		TopicUrl: topic,
+		Secret:   "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verify := func(mode, topic string) *httptest.ResponseRecorder {
+		query := url.Values{
+			"hub.mode":          {mode},
+			"hub.topic":         {topic},
+			"hub.challenge":     {"challenge"},
+			"hub.lease_seconds": {"3600"},
+		}
+		req := httptest.NewRequest(http.MethodGet, "/websub/"+feed.ID.String()+"?"+query.Encode(), nil)
+		req.SetPathValue("feedID", feed.ID.String())
+		w := httptest.NewRecorder()
+		websubVerifyHandler(s)(w, req)
+		return w
+	}
+	subscriptionState := func() string {
+		sub, err := s.db.GetWebsubSubscription(ctx, feed.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sub.State
+	}
+
+	w := verify("subscribe", "https://mallory.example/feed")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("verifying another topic answered %v, want 404", w.Code)
+	}
+
+	w = verify("subscribe", topic)
+	if w.Code != http.StatusOK || w.Body.String() != "challenge" {
+		t.Errorf("verifying a pending subscription answered %v %q, want the challenge", w.Code, w.Body)
+	}
+	if state := subscriptionState(); state != "verified" {
+		t.Errorf("subscription is %v, want verified", state)
+	}
+
+	w = verify("subscribe", topic)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("verifying a verified subscription again answered %v, want 404", w.Code)
+	}
+
+	w = verify("denied", topic)
+	if w.Code != http.StatusOK {
+		t.Errorf("denial answered %v, want 200", w.Code)
+	}
+	w = verify("subscribe", topic)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("verifying a denied subscription answered %v, want 404", w.Code)
+	}
+	if state := subscriptionState(); state != "denied" {
+		t.Errorf("subscription is %v, want denied", state)
+	}
+
+	w = verify("unsubscribe", topic)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unsubscribing answered %v, want 404", w.Code)
+	}
+}
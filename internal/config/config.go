@@ -2,29 +2,233 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 )
 
-const configFileName = ".gatorconfig.json"
+const (
+	configFileName = "config.json"
+	// legacyConfigFileName is the config in the home directory that gator
+	// used before it followed the XDG base directory spec.
+	legacyConfigFileName = ".gatorconfig.json"
+)
+
+// Environment variables that override the config file. They are never
+// written back to it.
+const (
+	EnvDBURL   = "GATOR_DB_URL"
+	EnvUser    = "GATOR_USER"
+	EnvProfile = "GATOR_PROFILE"
+)
 
 type Config struct {
 	DBURL           string `json:"db_url"`
 	CurrentUserName string `json:"current_user_name"`
+	// WebsubCallbackURL is the public base URL of `gator serve`, which hubs
+	// deliver WebSub pushes to. Feeds are only polled when it is empty.
+	WebsubCallbackURL string `json:"websub_callback_url,omitempty"`
+	// SMTP is the server digests are sent through; nil disables email.
+	SMTP *SMTPConfig `json:"smtp,omitempty"`
+	// Retention limits how many posts `prune` keeps for feeds without their
+	// own retention settings; nil keeps everything.
+	Retention *RetentionConfig `json:"retention,omitempty"`
+	// ServerURL and APIToken are set by `login --server`; commands that
+	// support it then talk to that gator server instead of the database.
+	ServerURL string `json:"server_url,omitempty"`
+	APIToken  string `json:"api_token,omitempty"`
+	// Profiles are named configs, e.g. "work" and "personal", selected with
+	// --profile instead of the settings above. Only read at the top level.
+	Profiles map[string]Config `json:"profiles,omitempty"`
+
+	// path and profile say where the config was read from, so changes are
+	// written back to the same place.
+	path    string
+	profile string
+	extra   extraFields
+}
+
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from"`
+	// TLS is "starttls" to require STARTTLS, "tls" for implicit TLS (usually
+	// port 465) or "none" for plain text. Left empty, STARTTLS is used
+	// whenever the server offers it.
+	TLS string `json:"tls,omitempty"`
+
+	extra extraFields
+}
+
+type RetentionConfig struct {
+	// MaxAge is a window such as "90d" or "720h"; older posts are pruned.
+	MaxAge string `json:"max_age,omitempty"`
+	// MaxPostsPerFeed keeps only the newest posts of each feed.
+	MaxPostsPerFeed int `json:"max_posts_per_feed,omitempty"`
+
+	extra extraFields
+}
+
+// The config types keep settings they don't know about, so a config written
+// by an older gator doesn't lose what a newer one added.
+
+func (cfg *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	return unmarshalWithExtra(data, (*plain)(cfg), &cfg.extra)
+}
+
+func (cfg Config) MarshalJSON() ([]byte, error) {
+	type plain Config
+	return marshalWithExtra(plain(cfg), cfg.extra)
+}
+
+func (cfg *SMTPConfig) UnmarshalJSON(data []byte) error {
+	type plain SMTPConfig
+	return unmarshalWithExtra(data, (*plain)(cfg), &cfg.extra)
+}
+
+func (cfg SMTPConfig) MarshalJSON() ([]byte, error) {
+	type plain SMTPConfig
+	return marshalWithExtra(plain(cfg), cfg.extra)
+}
+
+func (cfg *RetentionConfig) UnmarshalJSON(data []byte) error {
+	type plain RetentionConfig
+	return unmarshalWithExtra(data, (*plain)(cfg), &cfg.extra)
 }
 
+func (cfg RetentionConfig) MarshalJSON() ([]byte, error) {
+	type plain RetentionConfig
+	return marshalWithExtra(plain(cfg), cfg.extra)
+}
+
+// Path is the config file the config was read from, and is written to.
+func (cfg *Config) Path() string {
+	return cfg.path
+}
+
+// Profile is the name of the profile in use, or "" for the default one.
+func (cfg *Config) Profile() string {
+	return cfg.profile
+}
+
+// SetUser logs in to the database as userName, leaving any gator server.
 func (cfg *Config) SetUser(userName string) error {
-	cfg.CurrentUserName = userName
-	return write(*cfg)
+	return cfg.update(func(c *Config) {
+		c.CurrentUserName = userName
+		c.ServerURL = ""
+		c.APIToken = ""
+	})
+}
+
+// SetServerUser logs in to the gator server at serverURL as userName.
+func (cfg *Config) SetServerUser(serverURL, apiToken, userName string) error {
+	return cfg.update(func(c *Config) {
+		c.CurrentUserName = userName
+		c.ServerURL = serverURL
+		c.APIToken = apiToken
+	})
 }
 
-func Read() (Config, error) {
-	fullPath, err := getConfigFilePath()
+// Read reads the config file at path, or finds it when path is empty, and
+// selects the named profile, or the default one when profile is empty. The
+// GATOR_PROFILE, GATOR_DB_URL and GATOR_USER environment variables override
+// what the file says. A missing config file is an empty config.
+func Read(path, profile string) (Config, error) {
+	if path == "" {
+		var err error
+		path, err = getConfigFilePath()
+		if err != nil {
+			return Config{}, err
+		}
+	}
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+
+	file, err := readFile(path)
 	if err != nil {
 		return Config{}, err
 	}
+	cfg, err := file.selectProfile(profile)
+	if err != nil {
+		return Config{}, fmt.Errorf("%w in %v", err, path)
+	}
+	cfg.path = path
+	cfg.profile = profile
+
+	if dbURL := os.Getenv(EnvDBURL); dbURL != "" {
+		cfg.DBURL = dbURL
+	}
+	if user := os.Getenv(EnvUser); user != "" {
+		cfg.CurrentUserName = user
+	}
+
+	return cfg, nil
+}
+
+func (file Config) selectProfile(profile string) (Config, error) {
+	if profile == "" {
+		file.Profiles = nil
+		return file, nil
+	}
+	cfg, ok := file.Profiles[profile]
+	if !ok {
+		return Config{}, fmt.Errorf("no profile named %q", profile)
+	}
+	cfg.Profiles = nil
+	return cfg, nil
+}
 
-	file, err := os.Open(fullPath)
+// getConfigFilePath returns $XDG_CONFIG_HOME/gator/config.json, falling back
+// to ~/.config, unless only the legacy ~/.gatorconfig.json exists.
+func getConfigFilePath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	xdgPath := filepath.Join(configDir, "gator", configFileName)
+	if _, err := os.Stat(xdgPath); err == nil {
+		return xdgPath, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return xdgPath, nil
+	}
+	legacyPath := filepath.Join(home, legacyConfigFileName)
+	if _, err := os.Stat(legacyPath); err == nil {
+		return legacyPath, nil
+	}
+
+	return xdgPath, nil
+}
+
+// StateDir returns $XDG_STATE_HOME/gator, falling back to
+// ~/.local/state/gator, where gator keeps files it makes for the user, such
+// as backups, that don't belong next to the config.
+func StateDir() (string, error) {
+	stateDir := os.Getenv("XDG_STATE_HOME")
+	// The spec says to ignore relative paths.
+	if !filepath.IsAbs(stateDir) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		stateDir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateDir, "gator"), nil
+}
+
+func readFile(path string) (Config, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Config{}, nil
+	}
 	if err != nil {
 		return Config{}, err
 	}
@@ -34,38 +238,82 @@ func Read() (Config, error) {
 	cfg := Config{}
 	err = decoder.Decode(&cfg)
 	if err != nil {
-		return Config{}, err
+		return Config{}, fmt.Errorf("couldn't parse %v: %w", path, err)
 	}
 
 	return cfg, nil
 }
 
-func getConfigFilePath() (string, error) {
-	home, err := os.UserHomeDir()
+// update applies change to cfg and to the profile it was read from in the
+// config file. The file is read again first, so environment overrides in
+// cfg aren't written to it, and locked until it is written, so concurrent
+// updates don't undo each other.
+func (cfg *Config) update(change func(*Config)) error {
+	change(cfg)
+
+	err := os.MkdirAll(filepath.Dir(cfg.path), 0o700)
 	if err != nil {
-		return "", err
+		return err
+	}
+	unlock, err := lockFile(cfg.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("couldn't lock config: %w", err)
+	}
+	defer unlock()
+
+	file, err := readFile(cfg.path)
+	if err != nil {
+		return err
+	}
+	if cfg.profile == "" {
+		change(&file)
+	} else {
+		profile, ok := file.Profiles[cfg.profile]
+		if !ok {
+			return fmt.Errorf("no profile named %q in %v", cfg.profile, cfg.path)
+		}
+		change(&profile)
+		file.Profiles[cfg.profile] = profile
 	}
-	fullPath := filepath.Join(home, configFileName)
-	return fullPath, nil
+
+	return write(cfg.path, file)
 }
 
-func write(cfg Config) error {
-	fullPath, err := getConfigFilePath()
+// write replaces the config file at path with cfg atomically: a crash leaves
+// either the old file or the new one, never a partial one.
+func write(path string, cfg Config) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return err
 	}
+	data = append(data, '\n')
+
+	// Keep the permissions of the file being replaced. New configs are
+	// private, since they hold database passwords and API tokens.
+	mode := fs.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
 
-	file, err := os.Create(fullPath)
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	defer os.Remove(tmp.Name())
 
-	encoder := json.NewEncoder(file)
-	err = encoder.Encode(cfg)
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Chmod(mode)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return os.Rename(tmp.Name(), path)
 }
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// writeConfig writes contents to a config file in a new directory and
+// returns its path.
+func writeConfig(t *testing.T, contents string, mode os.FileMode) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), configFileName)
+	err := os.WriteFile(path, []byte(contents), mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// WriteFile is subject to the umask.
+	err = os.Chmod(path, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readJSON reads the config file at path without the config types, so
+// fields they don't know about show up too.
+func readJSON(t *testing.T, path string) map[string]any {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file map[string]any
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		t.Fatalf("couldn't parse %v: %v", path, err)
+	}
+	return file
+}
+
+func TestSetUserInProfile(t *testing.T) {
+	path := writeConfig(t, `{
+  "db_url": "postgres://default",
+  "current_user_name": "alice",
+  "profiles": {
+    "work": {"db_url": "postgres://work", "current_user_name": "bob"},
+    "personal": {"db_url": "postgres://personal", "current_user_name": "carol"}
+  }
+}`, 0o600)
+
+	cfg, err := Read(path, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBURL != "postgres://work" || cfg.CurrentUserName != "bob" {
+		t.Fatalf("read %+v, want the work profile", cfg)
+	}
+	err = cfg.SetUser("dave")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"db_url":            "postgres://default",
+		"current_user_name": "alice",
+		"profiles": map[string]any{
+			"work":     map[string]any{"db_url": "postgres://work", "current_user_name": "dave"},
+			"personal": map[string]any{"db_url": "postgres://personal", "current_user_name": "carol"},
+		},
+	}
+	if got := readJSON(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("after setting the work user the file is %v, want %v", got, want)
+	}
+}
+
+func TestEnvironmentNotWrittenBack(t *testing.T) {
+	path := writeConfig(t, `{"db_url": "postgres://file", "current_user_name": "alice"}`, 0o600)
+	t.Setenv(EnvDBURL, "postgres://env")
+	t.Setenv(EnvUser, "bob")
+
+	cfg, err := Read(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DBURL != "postgres://env" || cfg.CurrentUserName != "bob" {
+		t.Fatalf("read %+v, want the environment to override the file", cfg)
+	}
+
+	err = cfg.update(func(c *Config) {
+		c.WebsubCallbackURL = "https://gator.example.com"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"db_url":              "postgres://file",
+		"current_user_name":   "alice",
+		"websub_callback_url": "https://gator.example.com",
+	}
+	if got := readJSON(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("after an update the file is %v, want %v", got, want)
+	}
+
+	err = cfg.SetUser("carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readJSON(t, path)
+	if got["db_url"] != "postgres://file" {
+		t.Errorf("db_url is %v after login, want postgres://file", got["db_url"])
+	}
+	if got["current_user_name"] != "carol" {
+		t.Errorf("current_user_name is %v after login, want carol", got["current_user_name"])
+	}
+}
+
+func TestUnknownFieldsSurviveWrite(t *testing.T) {
+	path := writeConfig(t, `{
+  "db_url": "postgres://default",
+  "current_user_name": "alice",
+  "theme": "dark",
+  "smtp": {"host": "smtp.example.com", "from": "gator@example.com", "dkim": {"selector": "s1"}},
+  "retention": {"max_age": "90d", "keep_starred": true},
+  "profiles": {
+    "work": {"db_url": "postgres://work", "editor": "vim", "smtp": {"host": "smtp.work", "from": "me@work", "pool": 4}}
+  }
+}`, 0o600)
+	before := readJSON(t, path)
+
+	for _, profile := range []string{"", "work"} {
+		cfg, err := Read(path, profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = cfg.SetUser("bob")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before["current_user_name"] = "bob"
+	before["profiles"].(map[string]any)["work"].(map[string]any)["current_user_name"] = "bob"
+	if got := readJSON(t, path); !reflect.DeepEqual(got, before) {
+		t.Errorf("after setting the user the file is %v, want %v", got, before)
+	}
+}
+
+func TestWriteKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on windows")
+	}
+
+	path := writeConfig(t, `{"db_url": "postgres://file"}`, 0o640)
+	cfg, err := Read(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cfg.SetUser("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o640 {
+		t.Errorf("existing config has mode %v after a write, want %v", mode, os.FileMode(0o640))
+	}
+
+	path = filepath.Join(t.TempDir(), "gator", configFileName)
+	cfg, err = Read(path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cfg.SetUser("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err = os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("new config has mode %v, want %v", mode, os.FileMode(0o600))
+	}
+}
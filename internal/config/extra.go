@@ -0,0 +1,88 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// extraFields holds the members of a JSON object that its struct has no
+// field for.
+type extraFields map[string]json.RawMessage
+
+// unmarshalWithExtra decodes data into v, a pointer to a struct, and stores
+// the members v has no field for in extra.
+func unmarshalWithExtra(data []byte, v interface{}, extra *extraFields) error {
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		return err
+	}
+
+	var members map[string]json.RawMessage
+	err = json.Unmarshal(data, &members)
+	if err != nil {
+		return err
+	}
+	for name := range jsonFieldNames(reflect.TypeOf(v).Elem()) {
+		delete(members, name)
+	}
+
+	*extra = nil
+	if len(members) > 0 {
+		*extra = members
+	}
+	return nil
+}
+
+// marshalWithExtra encodes v, a struct, followed by the members in extra.
+func marshalWithExtra(v interface{}, extra extraFields) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1])
+	for i, name := range names {
+		if i > 0 || len(data) > 2 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(extra[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonFieldNames returns the JSON member names of the fields of struct t.
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		names[name] = true
+	}
+	return names
+}
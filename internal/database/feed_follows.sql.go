@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,10 +20,10 @@ WITH inserted_feed_follow AS (
         $1,
         $2
     )
-    RETURNING id, created_at, updated_at, user_id, feed_id
+    RETURNING id, created_at, updated_at, user_id, feed_id, display_name
 )
     SELECT
-    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+    inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.display_name,
     feeds.name AS feed_name,
     users.name AS user_name
 FROM inserted_feed_follow
@@ -36,13 +37,14 @@ type CreateFeedFollowParams struct {
 }
 
 type CreateFeedFollowRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	DisplayName sql.NullString
+	FeedName    string
+	UserName    string
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -54,6 +56,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.DisplayName,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -76,21 +79,39 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT users.name AS follower, feeds.name AS feed_name, feeds.url AS feed_url
+SELECT users.name AS follower, COALESCE(feed_follows.display_name, feeds.name) AS feed_name, feeds.url AS feed_url,
+    COALESCE((
+        SELECT string_agg(tags.name, ',' ORDER BY tags.name)
+        FROM feed_follow_tags
+        INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+        WHERE feed_follow_tags.feed_follow_id = feed_follows.id
+    ), '')::text AS tags
 FROM feed_follows
 INNER JOIN users ON users.id = feed_follows.user_id
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
 WHERE feed_follows.user_id = $1
+AND ($2::text IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_tags
+    INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+    WHERE feed_follow_tags.feed_follow_id = feed_follows.id AND tags.name = $2
+))
+ORDER BY feed_name
 `
 
+type GetFeedFollowsForUserParams struct {
+	UserID uuid.UUID
+	Tag    sql.NullString
+}
+
 type GetFeedFollowsForUserRow struct {
 	Follower string
 	FeedName string
 	FeedUrl  string
+	Tags     string
 }
 
-func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, userID)
+func (q *Queries) GetFeedFollowsForUser(ctx context.Context, arg GetFeedFollowsForUserParams) ([]GetFeedFollowsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForUser, arg.UserID, arg.Tag)
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +119,12 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	var items []GetFeedFollowsForUserRow
 	for rows.Next() {
 		var i GetFeedFollowsForUserRow
-		if err := rows.Scan(&i.Follower, &i.FeedName, &i.FeedUrl); err != nil {
+		if err := rows.Scan(
+			&i.Follower,
+			&i.FeedName,
+			&i.FeedUrl,
+			&i.Tags,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -111,3 +137,108 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const getFollowedFeeds = `-- name: GetFollowedFeeds :many
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.short_id, feeds.retention_max_age_seconds, feeds.retention_max_posts, feeds.last_status_code, feeds.last_error, feeds.consecutive_failures, feeds.last_succeeded_at, feeds.disabled_at, feeds.redirect_url, feeds.redirect_count, feeds.title, feeds.site_url, feeds.description, feeds.language, feeds.image_url, feeds.generator, feeds.fetch_interval_seconds, feeds.fetch_full_text, feed_follows.display_name FROM feeds
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY COALESCE(feed_follows.display_name, feeds.name)
+`
+
+type GetFollowedFeedsRow struct {
+	ID                     uuid.UUID
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	Name                   string
+	Url                    string
+	UserID                 uuid.UUID
+	LastFetchedAt          sql.NullTime
+	ShortID                int64
+	RetentionMaxAgeSeconds sql.NullInt32
+	RetentionMaxPosts      sql.NullInt32
+	LastStatusCode         sql.NullInt32
+	LastError              sql.NullString
+	ConsecutiveFailures    int32
+	LastSucceededAt        sql.NullTime
+	DisabledAt             sql.NullTime
+	RedirectUrl            sql.NullString
+	RedirectCount          int32
+	Title                  sql.NullString
+	SiteUrl                sql.NullString
+	Description            sql.NullString
+	Language               sql.NullString
+	ImageUrl               sql.NullString
+	Generator              sql.NullString
+	FetchIntervalSeconds   sql.NullInt32
+	FetchFullText          bool
+	DisplayName            sql.NullString
+}
+
+func (q *Queries) GetFollowedFeeds(ctx context.Context, userID uuid.UUID) ([]GetFollowedFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedFeeds, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowedFeedsRow
+	for rows.Next() {
+		var i GetFollowedFeedsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.ShortID,
+			&i.RetentionMaxAgeSeconds,
+			&i.RetentionMaxPosts,
+			&i.LastStatusCode,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.LastSucceededAt,
+			&i.DisabledAt,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.Title,
+			&i.SiteUrl,
+			&i.Description,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.FetchIntervalSeconds,
+			&i.FetchFullText,
+			&i.DisplayName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setFeedFollowDisplayName = `-- name: SetFeedFollowDisplayName :execrows
+UPDATE feed_follows SET display_name = $3, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowDisplayNameParams struct {
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	DisplayName sql.NullString
+}
+
+func (q *Queries) SetFeedFollowDisplayName(ctx context.Context, arg SetFeedFollowDisplayNameParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowDisplayName, arg.UserID, arg.FeedID, arg.DisplayName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
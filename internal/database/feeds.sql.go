@@ -11,8 +11,31 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const clearFeedRedirect = `-- name: ClearFeedRedirect :exec
+UPDATE feeds
+SET redirect_url = NULL, redirect_count = 0, updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) ClearFeedRedirect(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, clearFeedRedirect, id)
+	return err
+}
+
+const countFeedFollowers = `-- name: CountFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows WHERE feed_id = $1
+`
+
+func (q *Queries) CountFeedFollowers(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeedFollowers, feedID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES (
@@ -23,7 +46,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text
 `
 
 type CreateFeedParams struct {
@@ -53,6 +76,24 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.ShortID,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.FetchIntervalSeconds,
+		&i.FetchFullText,
 	)
 	return i, err
 }
@@ -66,17 +107,43 @@ func (q *Queries) DeleteFeed(ctx context.Context, url string) error {
 	return err
 }
 
-const deleteFeeds = `-- name: DeleteFeeds :exec
-DELETE FROM feeds
+const deleteFeedByID = `-- name: DeleteFeedByID :exec
+DELETE FROM feeds WHERE id = $1
 `
 
-func (q *Queries) DeleteFeeds(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteFeeds)
+func (q *Queries) DeleteFeedByID(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteFeedByID, id)
 	return err
 }
 
+const deleteFeeds = `-- name: DeleteFeeds :execrows
+DELETE FROM feeds
+`
+
+func (q *Queries) DeleteFeeds(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeeds)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const enableFeed = `-- name: EnableFeed :execrows
+UPDATE feeds
+SET disabled_at = NULL, consecutive_failures = 0, updated_at = NOW()
+WHERE url = $1
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, url string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, enableFeed, url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getFeed = `-- name: GetFeed :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE name = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds WHERE name = $1
 `
 
 func (q *Queries) GetFeed(ctx context.Context, name string) (Feed, error) {
@@ -90,12 +157,67 @@ func (q *Queries) GetFeed(ctx context.Context, name string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.ShortID,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.FetchIntervalSeconds,
+		&i.FetchFullText,
+	)
+	return i, err
+}
+
+const getFeedByID = `-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds WHERE id = $1
+`
+
+func (q *Queries) GetFeedByID(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByID, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.ShortID,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.FetchIntervalSeconds,
+		&i.FetchFullText,
 	)
 	return i, err
 }
 
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
@@ -109,12 +231,88 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.ShortID,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.FetchIntervalSeconds,
+		&i.FetchFullText,
 	)
 	return i, err
 }
 
+const getFeedByUrls = `-- name: GetFeedByUrls :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds
+WHERE url = ANY($1::text[])
+ORDER BY array_position($1::text[], url)
+LIMIT 1
+`
+
+func (q *Queries) GetFeedByUrls(ctx context.Context, urls []string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedByUrls, pq.Array(urls))
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.ShortID,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.FetchIntervalSeconds,
+		&i.FetchFullText,
+	)
+	return i, err
+}
+
+const getFeedQueueLag = `-- name: GetFeedQueueLag :one
+SELECT COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(COALESCE(feeds.last_fetched_at, feeds.created_at))), 0)::float8 AS lag_seconds
+FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+WHERE feeds.disabled_at IS NULL
+AND (
+    websub_subscriptions.state IS DISTINCT FROM 'verified'
+    OR websub_subscriptions.lease_expires_at < NOW()
+)
+`
+
+func (q *Queries) GetFeedQueueLag(ctx context.Context) (float64, error) {
+	row := q.db.QueryRowContext(ctx, getFeedQueueLag)
+	var lag_seconds float64
+	err := row.Scan(&lag_seconds)
+	return lag_seconds, err
+}
+
 const getFeeds = `-- name: GetFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds
 `
 
 func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
@@ -134,6 +332,24 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.ShortID,
+			&i.RetentionMaxAgeSeconds,
+			&i.RetentionMaxPosts,
+			&i.LastStatusCode,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.LastSucceededAt,
+			&i.DisabledAt,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.Title,
+			&i.SiteUrl,
+			&i.Description,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.FetchIntervalSeconds,
+			&i.FetchFullText,
 		); err != nil {
 			return nil, err
 		}
@@ -184,9 +400,76 @@ func (q *Queries) GetFeedsByUser(ctx context.Context) ([]GetFeedsByUserRow, erro
 	return items, nil
 }
 
+const getFeedsHealth = `-- name: GetFeedsHealth :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds
+ORDER BY disabled_at NULLS LAST, consecutive_failures DESC, name
+`
+
+func (q *Queries) GetFeedsHealth(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedsHealth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.ShortID,
+			&i.RetentionMaxAgeSeconds,
+			&i.RetentionMaxPosts,
+			&i.LastStatusCode,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.LastSucceededAt,
+			&i.DisabledAt,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.Title,
+			&i.SiteUrl,
+			&i.Description,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.FetchIntervalSeconds,
+			&i.FetchFullText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds
-ORDER BY last_fetched_at NULLS FIRST, id
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.short_id, feeds.retention_max_age_seconds, feeds.retention_max_posts, feeds.last_status_code, feeds.last_error, feeds.consecutive_failures, feeds.last_succeeded_at, feeds.disabled_at, feeds.redirect_url, feeds.redirect_count, feeds.title, feeds.site_url, feeds.description, feeds.language, feeds.image_url, feeds.generator, feeds.fetch_interval_seconds, feeds.fetch_full_text FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+WHERE feeds.disabled_at IS NULL
+AND (
+    websub_subscriptions.state IS DISTINCT FROM 'verified'
+    OR websub_subscriptions.lease_expires_at < NOW()
+    OR feeds.last_fetched_at IS NULL
+    OR feeds.last_fetched_at < NOW() - INTERVAL '1 day'
+)
+AND (
+    feeds.fetch_interval_seconds IS NULL
+    OR feeds.last_fetched_at IS NULL
+    OR feeds.last_fetched_at < NOW() - make_interval(secs => feeds.fetch_interval_seconds)
+)
+ORDER BY feeds.last_fetched_at NULLS FIRST, feeds.id
 LIMIT 1
 `
 
@@ -201,12 +484,30 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.ShortID,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.FetchIntervalSeconds,
+		&i.FetchFullText,
 	)
 	return i, err
 }
 
 const getUserFeeds = `-- name: GetUserFeeds :many
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE user_id = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds WHERE user_id = $1
 `
 
 func (q *Queries) GetUserFeeds(ctx context.Context, userID uuid.UUID) ([]Feed, error) {
@@ -226,6 +527,24 @@ func (q *Queries) GetUserFeeds(ctx context.Context, userID uuid.UUID) ([]Feed, e
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.ShortID,
+			&i.RetentionMaxAgeSeconds,
+			&i.RetentionMaxPosts,
+			&i.LastStatusCode,
+			&i.LastError,
+			&i.ConsecutiveFailures,
+			&i.LastSucceededAt,
+			&i.DisabledAt,
+			&i.RedirectUrl,
+			&i.RedirectCount,
+			&i.Title,
+			&i.SiteUrl,
+			&i.Description,
+			&i.Language,
+			&i.ImageUrl,
+			&i.Generator,
+			&i.FetchIntervalSeconds,
+			&i.FetchFullText,
 		); err != nil {
 			return nil, err
 		}
@@ -250,3 +569,261 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
+
+const recordFeedFailure = `-- name: RecordFeedFailure :one
+UPDATE feeds
+SET last_status_code = $1,
+    last_error = $2,
+    consecutive_failures = consecutive_failures + 1,
+    disabled_at = CASE
+        WHEN $3::integer > 0 AND consecutive_failures + 1 >= $3::integer THEN NOW()
+        ELSE disabled_at
+    END,
+    updated_at = NOW()
+WHERE id = $4
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text
+`
+
+type RecordFeedFailureParams struct {
+	LastStatusCode sql.NullInt32
+	LastError      sql.NullString
+	MaxFailures    int32
+	ID             uuid.UUID
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFailure,
+		arg.LastStatusCode,
+		arg.LastError,
+		arg.MaxFailures,
+		arg.ID,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.ShortID,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.FetchIntervalSeconds,
+		&i.FetchFullText,
+	)
+	return i, err
+}
+
+const recordFeedRedirect = `-- name: RecordFeedRedirect :one
+UPDATE feeds
+SET redirect_count = CASE WHEN redirect_url = $1 THEN redirect_count + 1 ELSE 1 END,
+    redirect_url = $1,
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text
+`
+
+type RecordFeedRedirectParams struct {
+	RedirectUrl sql.NullString
+	ID          uuid.UUID
+}
+
+func (q *Queries) RecordFeedRedirect(ctx context.Context, arg RecordFeedRedirectParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedRedirect, arg.RedirectUrl, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.ShortID,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.FetchIntervalSeconds,
+		&i.FetchFullText,
+	)
+	return i, err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET last_status_code = $2,
+    last_error = NULL,
+    consecutive_failures = 0,
+    last_succeeded_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type RecordFeedSuccessParams struct {
+	ID             uuid.UUID
+	LastStatusCode sql.NullInt32
+}
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, arg.ID, arg.LastStatusCode)
+	return err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_max_age_seconds = $2, retention_max_posts = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedRetentionParams struct {
+	ID                     uuid.UUID
+	RetentionMaxAgeSeconds sql.NullInt32
+	RetentionMaxPosts      sql.NullInt32
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRetention, arg.ID, arg.RetentionMaxAgeSeconds, arg.RetentionMaxPosts)
+	return err
+}
+
+const setFeedUrl = `-- name: SetFeedUrl :exec
+UPDATE feeds
+SET url = $2, redirect_url = NULL, redirect_count = 0, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedUrlParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) SetFeedUrl(ctx context.Context, arg SetFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedUrl, arg.ID, arg.Url)
+	return err
+}
+
+const updateFeed = `-- name: UpdateFeed :one
+UPDATE feeds
+SET name = $1,
+    url = $2,
+    redirect_url = CASE WHEN url = $2 THEN redirect_url END,
+    redirect_count = CASE WHEN url = $2 THEN redirect_count ELSE 0 END,
+    fetch_interval_seconds = $3,
+    fetch_full_text = $4,
+    disabled_at = $5,
+    consecutive_failures = CASE WHEN $5::timestamp IS NULL THEN 0 ELSE consecutive_failures END,
+    updated_at = NOW()
+WHERE id = $6
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text
+`
+
+type UpdateFeedParams struct {
+	Name                 string
+	Url                  string
+	FetchIntervalSeconds sql.NullInt32
+	FetchFullText        bool
+	DisabledAt           sql.NullTime
+	ID                   uuid.UUID
+}
+
+func (q *Queries) UpdateFeed(ctx context.Context, arg UpdateFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, updateFeed,
+		arg.Name,
+		arg.Url,
+		arg.FetchIntervalSeconds,
+		arg.FetchFullText,
+		arg.DisabledAt,
+		arg.ID,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.ShortID,
+		&i.RetentionMaxAgeSeconds,
+		&i.RetentionMaxPosts,
+		&i.LastStatusCode,
+		&i.LastError,
+		&i.ConsecutiveFailures,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.RedirectUrl,
+		&i.RedirectCount,
+		&i.Title,
+		&i.SiteUrl,
+		&i.Description,
+		&i.Language,
+		&i.ImageUrl,
+		&i.Generator,
+		&i.FetchIntervalSeconds,
+		&i.FetchFullText,
+	)
+	return i, err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2,
+    site_url = $3,
+    description = $4,
+    language = $5,
+    image_url = $6,
+    generator = $7,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	Title       sql.NullString
+	SiteUrl     sql.NullString
+	Description sql.NullString
+	Language    sql.NullString
+	ImageUrl    sql.NullString
+	Generator   sql.NullString
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.Title,
+		arg.SiteUrl,
+		arg.Description,
+		arg.Language,
+		arg.ImageUrl,
+		arg.Generator,
+	)
+	return err
+}
@@ -11,22 +11,63 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                     uuid.UUID
+	CreatedAt              time.Time
+	UpdatedAt              time.Time
+	Name                   string
+	Url                    string
+	UserID                 uuid.UUID
+	LastFetchedAt          sql.NullTime
+	ShortID                int64
+	RetentionMaxAgeSeconds sql.NullInt32
+	RetentionMaxPosts      sql.NullInt32
+	LastStatusCode         sql.NullInt32
+	LastError              sql.NullString
+	ConsecutiveFailures    int32
+	LastSucceededAt        sql.NullTime
+	DisabledAt             sql.NullTime
+	RedirectUrl            sql.NullString
+	RedirectCount          int32
+	Title                  sql.NullString
+	SiteUrl                sql.NullString
+	Description            sql.NullString
+	Language               sql.NullString
+	ImageUrl               sql.NullString
+	Generator              sql.NullString
+	FetchIntervalSeconds   sql.NullInt32
+	FetchFullText          bool
 }
 
 type FeedFollow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	DisplayName sql.NullString
+}
+
+type FeedFollowTag struct {
+	FeedFollowID uuid.UUID
+	TagID        uuid.UUID
+}
+
+type FullTextFetch struct {
+	PostID        uuid.UUID
+	CreatedAt     time.Time
+	Url           string
+	Attempts      int32
+	NextAttemptAt time.Time
 }
 
 type Post struct {
@@ -38,11 +79,103 @@ type Post struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	ShortID     int64
+	Author      sql.NullString
+	Content     sql.NullString
+}
+
+type PostRead struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type PostRule struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Kind      string
+	Pattern   string
+	FeedID    uuid.NullUUID
+	Action    string
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type PrunedPost struct {
+	Url      string
+	PrunedAt time.Time
+}
+
+type Tag struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	ShortID   int64
 }
 
 type User struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Name         string
+	FeverApiKey  sql.NullString
+	Email        sql.NullString
+	LastDigestAt sql.NullTime
+	Role         string
+	PasswordHash sql.NullString
+}
+
+type View struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	Name          string
+	FeedIds       []uuid.UUID
+	Tags          []string
+	Keyword       sql.NullString
+	MaxAgeSeconds sql.NullInt32
+	UnreadOnly    bool
+}
+
+type Webhook struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
-	Name      string
+	UserID    uuid.UUID
+	Url       string
+	Secret    sql.NullString
+	FeedID    uuid.NullUUID
+	Keyword   sql.NullString
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	WebhookID      uuid.UUID
+	PostID         uuid.UUID
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastStatusCode sql.NullInt32
+	LastError      sql.NullString
+	DeliveredAt    sql.NullTime
+}
+
+type WebsubSubscription struct {
+	FeedID         uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	HubUrl         string
+	TopicUrl       string
+	Secret         string
+	State          string
+	LeaseExpiresAt sql.NullTime
 }
@@ -11,12 +11,73 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countPosts = `-- name: CountPosts :one
+SELECT COUNT(*) FROM posts
+`
+
+func (q *Queries) CountPosts(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPosts)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPostsForUser = `-- name: CountPostsForUser :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+`
+
+func (q *Queries) CountPostsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPostsForUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPrunablePosts = `-- name: CountPrunablePosts :one
+WITH ranked AS (
+    SELECT posts.id,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+        ) AS position,
+        COALESCE(feeds.retention_max_age_seconds, $1::integer) AS max_age_seconds,
+        COALESCE(feeds.retention_max_posts, $2::integer) AS max_posts
+    FROM posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+), prunable AS (
+    SELECT ranked.id FROM ranked
+    WHERE (
+        ranked.posted_at < NOW() - make_interval(secs => ranked.max_age_seconds)
+        OR ranked.position > ranked.max_posts
+    )
+    AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id)
+)
+SELECT COUNT(*) FROM prunable
+`
+
+type CountPrunablePostsParams struct {
+	MaxAgeSeconds sql.NullInt32
+	MaxPosts      sql.NullInt32
+}
+
+func (q *Queries) CountPrunablePosts(ctx context.Context, arg CountPrunablePostsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countPrunablePosts, arg.MaxAgeSeconds, arg.MaxPosts)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+INSERT INTO posts (title, url, description, published_at, feed_id, author)
+SELECT $1, $2, $3, $4, $5, $6
+WHERE NOT EXISTS (SELECT 1 FROM pruned_posts WHERE pruned_posts.url = $2)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, short_id, author, content
 `
 
 type CreatePostParams struct {
@@ -25,6 +86,7 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Author      sql.NullString
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -34,6 +96,7 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
 	)
 	var i Post
 	err := row.Scan(
@@ -45,23 +108,391 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.ShortID,
+		&i.Author,
+		&i.Content,
 	)
 	return i, err
 }
 
+const deletePosts = `-- name: DeletePosts :execrows
+DELETE FROM posts
+`
+
+func (q *Queries) DeletePosts(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePosts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deletePrunedPosts = `-- name: DeletePrunedPosts :exec
+DELETE FROM pruned_posts
+`
+
+func (q *Queries) DeletePrunedPosts(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, deletePrunedPosts)
+	return err
+}
+
+const deletePrunedPostsBefore = `-- name: DeletePrunedPostsBefore :exec
+DELETE FROM pruned_posts
+WHERE pruned_at < $1
+`
+
+func (q *Queries) DeletePrunedPostsBefore(ctx context.Context, prunedAt time.Time) error {
+	_, err := q.db.ExecContext(ctx, deletePrunedPostsBefore, prunedAt)
+	return err
+}
+
+const getDigestPostsForUser = `-- name: GetDigestPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+INNER JOIN users ON users.id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND posts.created_at > COALESCE(users.last_digest_at, NOW() - INTERVAL '1 day')
+ORDER BY feed_name, posts.published_at DESC NULLS LAST, posts.created_at DESC
+`
+
+type GetDigestPostsForUserRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	ShortID     int64
+	Author      sql.NullString
+	Content     sql.NullString
+	FeedName    string
+}
+
+func (q *Queries) GetDigestPostsForUser(ctx context.Context, userID uuid.UUID) ([]GetDigestPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDigestPostsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDigestPostsForUserRow
+	for rows.Next() {
+		var i GetDigestPostsForUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.ShortID,
+			&i.Author,
+			&i.Content,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostByShortID = `-- name: GetPostByShortID :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.short_id = $2
+`
+
+type GetPostByShortIDParams struct {
+	UserID  uuid.UUID
+	ShortID int64
+}
+
+func (q *Queries) GetPostByShortID(ctx context.Context, arg GetPostByShortIDParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByShortID, arg.UserID, arg.ShortID)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.ShortID,
+		&i.Author,
+		&i.Content,
+	)
+	return i, err
+}
+
+const getPostItemsBefore = `-- name: GetPostItemsBefore :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, feeds.short_id AS feed_short_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+    ) AS is_starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.short_id < $2
+ORDER BY posts.short_id DESC
+LIMIT $3
+`
+
+type GetPostItemsBeforeParams struct {
+	UserID  uuid.UUID
+	ShortID int64
+	Limit   int32
+}
+
+type GetPostItemsBeforeRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	ShortID     int64
+	Author      sql.NullString
+	Content     sql.NullString
+	FeedShortID int64
+	IsRead      bool
+	IsStarred   bool
+}
+
+func (q *Queries) GetPostItemsBefore(ctx context.Context, arg GetPostItemsBeforeParams) ([]GetPostItemsBeforeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostItemsBefore, arg.UserID, arg.ShortID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostItemsBeforeRow
+	for rows.Next() {
+		var i GetPostItemsBeforeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.ShortID,
+			&i.Author,
+			&i.Content,
+			&i.FeedShortID,
+			&i.IsRead,
+			&i.IsStarred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostItemsByShortIDs = `-- name: GetPostItemsByShortIDs :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, feeds.short_id AS feed_short_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+    ) AS is_starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.short_id = ANY($2::bigint[])
+ORDER BY posts.short_id
+`
+
+type GetPostItemsByShortIDsParams struct {
+	UserID   uuid.UUID
+	ShortIds []int64
+}
+
+type GetPostItemsByShortIDsRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	ShortID     int64
+	Author      sql.NullString
+	Content     sql.NullString
+	FeedShortID int64
+	IsRead      bool
+	IsStarred   bool
+}
+
+func (q *Queries) GetPostItemsByShortIDs(ctx context.Context, arg GetPostItemsByShortIDsParams) ([]GetPostItemsByShortIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostItemsByShortIDs, arg.UserID, pq.Array(arg.ShortIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostItemsByShortIDsRow
+	for rows.Next() {
+		var i GetPostItemsByShortIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.ShortID,
+			&i.Author,
+			&i.Content,
+			&i.FeedShortID,
+			&i.IsRead,
+			&i.IsStarred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostItemsSince = `-- name: GetPostItemsSince :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, feeds.short_id AS feed_short_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+    ) AS is_starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.short_id > $2
+ORDER BY posts.short_id
+LIMIT $3
+`
+
+type GetPostItemsSinceParams struct {
+	UserID  uuid.UUID
+	ShortID int64
+	Limit   int32
+}
+
+type GetPostItemsSinceRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	ShortID     int64
+	Author      sql.NullString
+	Content     sql.NullString
+	FeedShortID int64
+	IsRead      bool
+	IsStarred   bool
+}
+
+func (q *Queries) GetPostItemsSince(ctx context.Context, arg GetPostItemsSinceParams) ([]GetPostItemsSinceRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostItemsSince, arg.UserID, arg.ShortID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostItemsSinceRow
+	for rows.Next() {
+		var i GetPostItemsSinceRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.ShortID,
+			&i.Author,
+			&i.Content,
+			&i.FeedShortID,
+			&i.IsRead,
+			&i.IsStarred,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, feeds.name as feed_name
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
 FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
 INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
 WHERE feed_follows.user_id = $1
+AND ($2::text IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_tags
+    INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+    WHERE feed_follow_tags.feed_follow_id = feed_follows.id AND tags.name = $2
+))
 ORDER BY posts.published_at DESC, posts.updated_at DESC, posts.created_at DESC
-LIMIT $2
+LIMIT $3 OFFSET $4
 `
 
 type GetPostsForUserParams struct {
 	UserID uuid.UUID
+	Tag    sql.NullString
 	Limit  int32
+	Offset int32
 }
 
 type GetPostsForUserRow struct {
@@ -73,11 +504,19 @@ type GetPostsForUserRow struct {
 	Description sql.NullString
 	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	ShortID     int64
+	Author      sql.NullString
+	Content     sql.NullString
 	FeedName    string
 }
 
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Tag,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -94,6 +533,9 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.ShortID,
+			&i.Author,
+			&i.Content,
 			&i.FeedName,
 		); err != nil {
 			return nil, err
@@ -108,3 +550,150 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getPostsForView = `-- name: GetPostsForView :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM views
+INNER JOIN feed_follows ON feed_follows.user_id = views.user_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+INNER JOIN posts ON posts.feed_id = feeds.id
+WHERE views.id = $1
+AND (views.feed_ids IS NULL OR feeds.id = ANY(views.feed_ids))
+AND (views.tags IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_tags
+    INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+    WHERE feed_follow_tags.feed_follow_id = feed_follows.id AND tags.name = ANY(views.tags)
+))
+AND (
+    views.keyword IS NULL
+    OR strpos(lower(posts.title), lower(views.keyword)) > 0
+    OR strpos(lower(posts.description), lower(views.keyword)) > 0
+)
+AND (
+    views.max_age_seconds IS NULL
+    OR COALESCE(posts.published_at, posts.created_at) > NOW() - make_interval(secs => views.max_age_seconds)
+)
+AND (NOT views.unread_only OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.user_id = views.user_id AND post_reads.post_id = posts.id
+))
+ORDER BY posts.published_at DESC, posts.updated_at DESC, posts.created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type GetPostsForViewParams struct {
+	ViewID uuid.UUID
+	Limit  int32
+	Offset int32
+}
+
+type GetPostsForViewRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	ShortID     int64
+	Author      sql.NullString
+	Content     sql.NullString
+	FeedName    string
+}
+
+func (q *Queries) GetPostsForView(ctx context.Context, arg GetPostsForViewParams) ([]GetPostsForViewRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForView, arg.ViewID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsForViewRow
+	for rows.Next() {
+		var i GetPostsForViewRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.ShortID,
+			&i.Author,
+			&i.Content,
+			&i.FeedName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const prunePosts = `-- name: PrunePosts :execrows
+WITH ranked AS (
+    SELECT posts.id,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+        ) AS position,
+        COALESCE(feeds.retention_max_age_seconds, $1::integer) AS max_age_seconds,
+        COALESCE(feeds.retention_max_posts, $2::integer) AS max_posts
+    FROM posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+), prunable AS (
+    SELECT ranked.id FROM ranked
+    WHERE (
+        ranked.posted_at < NOW() - make_interval(secs => ranked.max_age_seconds)
+        OR ranked.position > ranked.max_posts
+    )
+    AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id)
+    LIMIT $3
+), deleted AS (
+    DELETE FROM posts
+    WHERE posts.id IN (SELECT prunable.id FROM prunable)
+    RETURNING posts.url
+)
+INSERT INTO pruned_posts (url)
+SELECT deleted.url FROM deleted
+ON CONFLICT (url) DO UPDATE SET pruned_at = NOW()
+`
+
+type PrunePostsParams struct {
+	MaxAgeSeconds sql.NullInt32
+	MaxPosts      sql.NullInt32
+	Limit         int32
+}
+
+func (q *Queries) PrunePosts(ctx context.Context, arg PrunePostsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, prunePosts, arg.MaxAgeSeconds, arg.MaxPosts, arg.Limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetPostContentParams struct {
+	ID      uuid.UUID
+	Content sql.NullString
+}
+
+func (q *Queries) SetPostContent(ctx context.Context, arg SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.ID, arg.Content)
+	return err
+}
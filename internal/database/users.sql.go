@@ -7,20 +7,69 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const countAdmins = `-- name: CountAdmins :one
+SELECT COUNT(*) FROM users WHERE role = 'admin'
+`
+
+func (q *Queries) CountAdmins(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAdmins)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserData = `-- name: CountUserData :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = $1) AS follows,
+    (SELECT COUNT(*) FROM tags WHERE tags.user_id = $1) AS tags,
+    (SELECT COUNT(*) FROM post_reads WHERE post_reads.user_id = $1) AS reads,
+    (SELECT COUNT(*) FROM post_stars WHERE post_stars.user_id = $1) AS stars,
+    (SELECT COUNT(*) FROM post_rules WHERE post_rules.user_id = $1) AS rules,
+    (SELECT COUNT(*) FROM views WHERE views.user_id = $1) AS views,
+    (SELECT COUNT(*) FROM webhooks WHERE webhooks.user_id = $1) AS webhooks
+`
+
+type CountUserDataRow struct {
+	Follows  int64
+	Tags     int64
+	Reads    int64
+	Stars    int64
+	Rules    int64
+	Views    int64
+	Webhooks int64
+}
+
+func (q *Queries) CountUserData(ctx context.Context, userID uuid.UUID) (CountUserDataRow, error) {
+	row := q.db.QueryRowContext(ctx, countUserData, userID)
+	var i CountUserDataRow
+	err := row.Scan(
+		&i.Follows,
+		&i.Tags,
+		&i.Reads,
+		&i.Stars,
+		&i.Rules,
+		&i.Views,
+		&i.Webhooks,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
+INSERT INTO users (id, created_at, updated_at, name, role)
 VALUES (
     $1,
     $2,
     $3,
-    $4
+    $4,
+    CASE WHEN EXISTS (SELECT 1 FROM users) THEN 'member' ELSE 'admin' END
 )
-RETURNING id, created_at, updated_at, name
+RETURNING id, created_at, updated_at, name, fever_api_key, email, last_digest_at, role, password_hash
 `
 
 type CreateUserParams struct {
@@ -43,21 +92,178 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.FeverApiKey,
+		&i.Email,
+		&i.LastDigestAt,
+		&i.Role,
+		&i.PasswordHash,
 	)
 	return i, err
 }
 
-const deleteUsers = `-- name: DeleteUsers :exec
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserFollows = `-- name: DeleteUserFollows :execrows
+DELETE FROM feed_follows WHERE feed_follows.user_id = $1
+`
+
+func (q *Queries) DeleteUserFollows(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserFollows, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserReads = `-- name: DeleteUserReads :execrows
+DELETE FROM post_reads WHERE post_reads.user_id = $1
+`
+
+func (q *Queries) DeleteUserReads(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserReads, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserRules = `-- name: DeleteUserRules :execrows
+DELETE FROM post_rules WHERE post_rules.user_id = $1
+`
+
+func (q *Queries) DeleteUserRules(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserRules, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserStars = `-- name: DeleteUserStars :execrows
+DELETE FROM post_stars WHERE post_stars.user_id = $1
+`
+
+func (q *Queries) DeleteUserStars(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserStars, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserTags = `-- name: DeleteUserTags :execrows
+DELETE FROM tags WHERE tags.user_id = $1
+`
+
+func (q *Queries) DeleteUserTags(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserTags, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserViews = `-- name: DeleteUserViews :execrows
+DELETE FROM views WHERE views.user_id = $1
+`
+
+func (q *Queries) DeleteUserViews(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserViews, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserWebhooks = `-- name: DeleteUserWebhooks :execrows
+DELETE FROM webhooks WHERE webhooks.user_id = $1
+`
+
+func (q *Queries) DeleteUserWebhooks(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserWebhooks, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUsers = `-- name: DeleteUsers :execrows
 DELETE FROM users
 `
 
-func (q *Queries) DeleteUsers(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteUsers)
-	return err
+func (q *Queries) DeleteUsers(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUsers)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getDigestRecipients = `-- name: GetDigestRecipients :many
+SELECT id, created_at, updated_at, name, fever_api_key, email, last_digest_at, role, password_hash FROM users
+WHERE email IS NOT NULL
+ORDER BY name
+`
+
+func (q *Queries) GetDigestRecipients(ctx context.Context) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getDigestRecipients)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.FeverApiKey,
+			&i.Email,
+			&i.LastDigestAt,
+			&i.Role,
+			&i.PasswordHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLastPostReadAt = `-- name: GetLastPostReadAt :one
+SELECT created_at FROM post_reads
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetLastPostReadAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getLastPostReadAt, userID)
+	var created_at time.Time
+	err := row.Scan(&created_at)
+	return created_at, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, name FROM users WHERE name = $1
+SELECT id, created_at, updated_at, name, fever_api_key, email, last_digest_at, role, password_hash FROM users WHERE name = $1
 `
 
 func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
@@ -68,12 +274,65 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.FeverApiKey,
+		&i.Email,
+		&i.LastDigestAt,
+		&i.Role,
+		&i.PasswordHash,
+	)
+	return i, err
+}
+
+const getUserByFeverApiKey = `-- name: GetUserByFeverApiKey :one
+SELECT id, created_at, updated_at, name, fever_api_key, email, last_digest_at, role, password_hash FROM users WHERE fever_api_key = $1
+`
+
+func (q *Queries) GetUserByFeverApiKey(ctx context.Context, feverApiKey sql.NullString) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByFeverApiKey, feverApiKey)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.FeverApiKey,
+		&i.Email,
+		&i.LastDigestAt,
+		&i.Role,
+		&i.PasswordHash,
+	)
+	return i, err
+}
+
+const getUserStats = `-- name: GetUserStats :one
+SELECT
+    (SELECT COUNT(*) FROM feeds WHERE feeds.user_id = $1) AS feed_count,
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = $1) AS follow_count,
+    (SELECT COUNT(*) FROM post_reads WHERE post_reads.user_id = $1) AS read_count,
+    (SELECT COUNT(*) FROM post_stars WHERE post_stars.user_id = $1) AS star_count
+`
+
+type GetUserStatsRow struct {
+	FeedCount   int64
+	FollowCount int64
+	ReadCount   int64
+	StarCount   int64
+}
+
+func (q *Queries) GetUserStats(ctx context.Context, userID uuid.UUID) (GetUserStatsRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserStats, userID)
+	var i GetUserStatsRow
+	err := row.Scan(
+		&i.FeedCount,
+		&i.FollowCount,
+		&i.ReadCount,
+		&i.StarCount,
 	)
 	return i, err
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, fever_api_key, email, last_digest_at, role, password_hash FROM users
 `
 
 func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
@@ -90,6 +349,11 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Name,
+			&i.FeverApiKey,
+			&i.Email,
+			&i.LastDigestAt,
+			&i.Role,
+			&i.PasswordHash,
 		); err != nil {
 			return nil, err
 		}
@@ -103,3 +367,120 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	}
 	return items, nil
 }
+
+const renameUser = `-- name: RenameUser :execrows
+UPDATE users SET name = $2, fever_api_key = NULL, updated_at = NOW()
+WHERE id = $1
+`
+
+type RenameUserParams struct {
+	ID   uuid.UUID
+	Name string
+}
+
+func (q *Queries) RenameUser(ctx context.Context, arg RenameUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renameUser, arg.ID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeverApiKey = `-- name: SetFeverApiKey :exec
+UPDATE users SET fever_api_key = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeverApiKeyParams struct {
+	ID          uuid.UUID
+	FeverApiKey sql.NullString
+}
+
+func (q *Queries) SetFeverApiKey(ctx context.Context, arg SetFeverApiKeyParams) error {
+	_, err := q.db.ExecContext(ctx, setFeverApiKey, arg.ID, arg.FeverApiKey)
+	return err
+}
+
+const setLastDigestAt = `-- name: SetLastDigestAt :exec
+UPDATE users SET last_digest_at = $2
+WHERE id = $1
+`
+
+type SetLastDigestAtParams struct {
+	ID           uuid.UUID
+	LastDigestAt sql.NullTime
+}
+
+func (q *Queries) SetLastDigestAt(ctx context.Context, arg SetLastDigestAtParams) error {
+	_, err := q.db.ExecContext(ctx, setLastDigestAt, arg.ID, arg.LastDigestAt)
+	return err
+}
+
+const setUserEmail = `-- name: SetUserEmail :exec
+UPDATE users SET email = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserEmailParams struct {
+	ID    uuid.UUID
+	Email sql.NullString
+}
+
+func (q *Queries) SetUserEmail(ctx context.Context, arg SetUserEmailParams) error {
+	_, err := q.db.ExecContext(ctx, setUserEmail, arg.ID, arg.Email)
+	return err
+}
+
+const setUserPassword = `-- name: SetUserPassword :exec
+UPDATE users SET password_hash = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserPasswordParams struct {
+	ID           uuid.UUID
+	PasswordHash sql.NullString
+}
+
+func (q *Queries) SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, setUserPassword, arg.ID, arg.PasswordHash)
+	return err
+}
+
+const setUserRole = `-- name: SetUserRole :execrows
+UPDATE users SET role = $2, updated_at = NOW()
+WHERE name = $1
+`
+
+type SetUserRoleParams struct {
+	Name string
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserRole, arg.Name, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const transferFeedsAndDeleteUser = `-- name: TransferFeedsAndDeleteUser :execrows
+WITH transferred AS (
+    UPDATE feeds SET user_id = $1, updated_at = NOW()
+    WHERE user_id = $2
+)
+DELETE FROM users WHERE id = $2
+`
+
+type TransferFeedsAndDeleteUserParams struct {
+	ToUserID uuid.UUID
+	ID       uuid.UUID
+}
+
+func (q *Queries) TransferFeedsAndDeleteUser(ctx context.Context, arg TransferFeedsAndDeleteUserParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeedsAndDeleteUser, arg.ToUserID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
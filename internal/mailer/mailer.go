@@ -0,0 +1,211 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+
+	"github.com/inscrutabletaco/gator/internal/config"
+)
+
+const timeout = 2 * time.Minute
+
+// Message is an email with both a plain text and an HTML body, sent as
+// multipart/alternative so clients can show whichever they prefer.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Bytes renders the message in RFC 5322 format.
+func (m Message) Bytes() ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=utf-8", m.Text},
+		{"text/html; charset=utf-8", m.HTML},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		_, err = qp.Write([]byte(part.content))
+		if err != nil {
+			return nil, err
+		}
+		err = qp.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	messageID, err := randomMessageID()
+	if err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", m.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@gator>\r\n", messageID)
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n", writer.Boundary())
+	fmt.Fprintf(&msg, "\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// Send delivers msg through the SMTP server described by cfg. It gives up
+// when ctx is done, or after a while if ctx has no deadline.
+func Send(ctx context.Context, cfg config.SMTPConfig, msg Message) (err error) {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address %q: %w", msg.From, err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid to address %q: %w", msg.To, err)
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = defaultPort(cfg.TLS)
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+
+	// Report why the conversation was cut short rather than the timeout
+	// it shows up as.
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+	}()
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch cfg.TLS {
+	case "tls":
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	case "", "starttls", "none":
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	default:
+		return fmt.Errorf("unknown smtp tls mode %q, expected starttls, tls or none", cfg.TLS)
+	}
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if cfg.TLS == "" || cfg.TLS == "starttls" {
+		ok, _ := c.Extension("STARTTLS")
+		if ok {
+			err = c.StartTLS(tlsConfig)
+			if err != nil {
+				return err
+			}
+		} else if cfg.TLS == "starttls" {
+			return fmt.Errorf("smtp server %v does not support STARTTLS", addr)
+		}
+	}
+
+	if cfg.Username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted
+		// connection unless the server is on localhost.
+		err = c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.Mail(from.Address)
+	if err != nil {
+		return err
+	}
+	err = c.Rcpt(to.Address)
+	if err != nil {
+		return err
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+func defaultPort(mode string) int {
+	switch mode {
+	case "tls":
+		return 465
+	case "none":
+		return 25
+	default:
+		return 587
+	}
+}
+
+func randomMessageID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,81 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/inscrutabletaco/gator/internal/config"
+)
+
+// stalledServer accepts connections but never greets, like an SMTP server
+// that hangs, and returns a config for it.
+func stalledServer(t *testing.T) config.SMTPConfig {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				for _, conn := range conns {
+					conn.Close()
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return config.SMTPConfig{Host: addr.IP.String(), Port: addr.Port, TLS: "none"}
+}
+
+var testMessage = Message{From: "gator@example.com", To: "alice@example.com", Subject: "Digest", Text: "Hi", HTML: "<p>Hi</p>"}
+
+func TestSendStopsAtDeadline(t *testing.T) {
+	cfg := stalledServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := Send(ctx, cfg, testMessage)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Send returned %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Send took %v past the deadline", elapsed)
+	}
+}
+
+func TestSendStopsWhenCanceled(t *testing.T) {
+	cfg := stalledServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := Send(ctx, cfg, testMessage)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Send returned %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Send took %v after being canceled", elapsed)
+	}
+}
+
+func TestSendDoesNotDialWhenDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Nothing listens here; Send must give up before trying.
+	err := Send(ctx, config.SMTPConfig{Host: "127.0.0.1", Port: 1, TLS: "none"}, testMessage)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Send returned %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,255 @@
+// Package sqlite runs gator's queries on a SQLite database. sqlc generates
+// the queries in internal/database for Postgres, so DB swaps each one for
+// its SQLite version in sql/sqlite/queries, found by the name sqlc starts
+// every query with, and database.Queries works unchanged on top of it.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/inscrutabletaco/gator/sql/sqlite/queries"
+	"github.com/lib/pq"
+	sqlitedriver "modernc.org/sqlite"
+)
+
+// MemoryPath opens a database that lives only as long as the process.
+const MemoryPath = ":memory:"
+
+var queryNameRe = regexp.MustCompile(`(?m)^-- name: (\w+) :\w+$`)
+
+func init() {
+	sqlitedriver.MustRegisterDeterministicScalarFunction("pq_array", 1, pqArrayLiteral)
+}
+
+// Open opens the SQLite database file at path, creating it if it doesn't
+// exist.
+func Open(path string) (*sql.DB, error) {
+	params := url.Values{
+		// SQLite ignores foreign keys, and so ON DELETE CASCADE, by default.
+		// WAL and the busy timeout let agg write while other commands read.
+		"_pragma": {"foreign_keys(1)", "journal_mode(WAL)", "busy_timeout(5000)"},
+		// Times are written in a format SQLite's date functions understand
+		// and that sorts in order, see sql/sqlite/schema/001_initial.sql.
+		"_time_format": {"sqlite"},
+		"_txlock":      {"immediate"},
+	}
+	db, err := sql.Open("sqlite", path+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	if path == MemoryPath {
+		// Every connection to :memory: gets a database of its own.
+		db.SetMaxOpenConns(1)
+	}
+	return db, nil
+}
+
+type query struct {
+	sql string
+	// multi is set when the query has several statements, which run in a
+	// transaction so they are as atomic as the Postgres query they replace.
+	multi bool
+}
+
+// DB is a database.DBTX that runs the SQLite version of each query.
+type DB struct {
+	db      *sql.DB
+	queries map[string]query
+}
+
+// New returns a DB that runs queries on db.
+func New(db *sql.DB) (*DB, error) {
+	parsed, err := readQueries(queries.FS)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read SQLite queries: %w", err)
+	}
+	return &DB{db: db, queries: parsed}, nil
+}
+
+func readQueries(fsys fs.FS) (map[string]query, error) {
+	paths, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := map[string]query{}
+	for _, path := range paths {
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		text := string(data)
+
+		matches := queryNameRe.FindAllStringSubmatchIndex(text, -1)
+		for i, m := range matches {
+			end := len(text)
+			if i+1 < len(matches) {
+				end = matches[i+1][0]
+			}
+			name := text[m[2]:m[3]]
+			if _, ok := parsed[name]; ok {
+				return nil, fmt.Errorf("%v: query %v is defined twice", path, name)
+			}
+			body := strings.TrimSuffix(strings.TrimSpace(text[m[1]:end]), ";")
+			parsed[name] = query{sql: body, multi: strings.Contains(body, ";")}
+		}
+	}
+	return parsed, nil
+}
+
+// lookup returns the SQLite version of pgQuery. Every query in
+// internal/database has one, so a missing query is a bug in gator rather
+// than something callers could handle, and panics.
+func (db *DB) lookup(pgQuery string) query {
+	m := queryNameRe.FindStringSubmatch(pgQuery)
+	if m == nil {
+		panic(fmt.Sprintf("sqlite: query has no name: %.60q", pgQuery))
+	}
+	q, ok := db.queries[m[1]]
+	if !ok {
+		panic(fmt.Sprintf("sqlite: no SQLite version of query %v in sql/sqlite/queries", m[1]))
+	}
+	return q
+}
+
+// convertArgs stores times in UTC, so they compare correctly as text, and
+// arrays as JSON, which queries read with json_each.
+func convertArgs(args []interface{}) []interface{} {
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case time.Time:
+			arg = v.UTC()
+		case sql.NullTime:
+			if v.Valid {
+				arg = v.Time.UTC()
+			} else {
+				arg = nil
+			}
+		default:
+			if array, ok := pqArrayJSON(arg); ok {
+				arg = array
+			}
+		}
+		converted[i] = arg
+	}
+	return converted
+}
+
+// pqArrayJSON encodes arg as a JSON array if it is one of the array types
+// pq.Array wraps slices in for Postgres. A nil slice is NULL, as lib/pq
+// sends it.
+func pqArrayJSON(arg interface{}) (interface{}, bool) {
+	var slice reflect.Value
+	if generic, ok := arg.(pq.GenericArray); ok {
+		slice = reflect.ValueOf(generic.A)
+	} else if t := reflect.TypeOf(arg); t != nil && isPQArrayType(t) {
+		slice = reflect.ValueOf(arg)
+	} else {
+		return nil, false
+	}
+	for slice.Kind() == reflect.Pointer && !slice.IsNil() {
+		slice = slice.Elem()
+	}
+	if !slice.IsValid() || slice.IsZero() {
+		return nil, true
+	}
+	if slice.Kind() != reflect.Slice {
+		return nil, false
+	}
+
+	data, err := json.Marshal(slice.Interface())
+	if err != nil {
+		// lib/pq's encoding goes through instead, and the query fails on it.
+		return nil, false
+	}
+	return string(data), true
+}
+
+// isPQArrayType reports whether t is one of lib/pq's array types, such as
+// pq.StringArray, or a pointer to one.
+func isPQArrayType(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Slice && t.PkgPath() == reflect.TypeOf(pq.StringArray{}).PkgPath()
+}
+
+// pqArrayLiteral is the SQL function pq_array, which turns a JSON array of
+// strings back into the Postgres array literal lib/pq scans, e.g.
+// ["a","b"] into {"a","b"}.
+func pqArrayLiteral(ctx *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+	var text string
+	switch v := args[0].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return nil, fmt.Errorf("pq_array: want a JSON array, got %T", v)
+	}
+
+	var elements []string
+	err := json.Unmarshal([]byte(text), &elements)
+	if err != nil {
+		return nil, fmt.Errorf("pq_array: %w", err)
+	}
+	return pq.StringArray(elements).Value()
+}
+
+func (db *DB) ExecContext(ctx context.Context, pgQuery string, args ...interface{}) (sql.Result, error) {
+	q := db.lookup(pgQuery)
+	args = convertArgs(args)
+	if !q.multi {
+		return db.db.ExecContext(ctx, q.sql, args...)
+	}
+
+	tx, err := db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// The result is that of the last statement.
+	result, err := tx.ExecContext(ctx, q.sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return result, tx.Commit()
+}
+
+func (db *DB) PrepareContext(ctx context.Context, pgQuery string) (*sql.Stmt, error) {
+	q := db.lookup(pgQuery)
+	if q.multi {
+		return nil, fmt.Errorf("sqlite: query with several statements can't be prepared: %.60q", pgQuery)
+	}
+	return db.db.PrepareContext(ctx, q.sql)
+}
+
+func (db *DB) QueryContext(ctx context.Context, pgQuery string, args ...interface{}) (*sql.Rows, error) {
+	q := db.lookup(pgQuery)
+	if q.multi {
+		return nil, fmt.Errorf("sqlite: query with several statements can't return rows: %.60q", pgQuery)
+	}
+	return db.db.QueryContext(ctx, q.sql, convertArgs(args)...)
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, pgQuery string, args ...interface{}) *sql.Row {
+	q := db.lookup(pgQuery)
+	if q.multi {
+		panic(fmt.Sprintf("sqlite: query with several statements can't return rows: %.60q", pgQuery))
+	}
+	return db.db.QueryRowContext(ctx, q.sql, convertArgs(args)...)
+}
@@ -0,0 +1,307 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+	sqliteschema "github.com/inscrutabletaco/gator/sql/sqlite/schema"
+	"github.com/lib/pq"
+	"github.com/pressly/goose/v3"
+)
+
+// Urls and tags that break naive parsing of Postgres array literals.
+var (
+	commaUrl  = "https://example.com/feed?tags=a,b"
+	quoteUrl  = `https://example.com/feed?q="x"\y`
+	commaTag  = "news, world"
+	quotedTag = `say "hi" \o/`
+)
+
+// openTestDB returns a database in memory migrated up to version, or all the
+// way when version is 0.
+func openTestDB(t *testing.T, version int64) (*sql.DB, *goose.Provider) {
+	t.Helper()
+	db, err := Open(MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, sqliteschema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version == 0 {
+		_, err = provider.Up(context.Background())
+	} else {
+		_, err = provider.UpTo(context.Background(), version)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, provider
+}
+
+func newQueries(t *testing.T, db *sql.DB) *database.Queries {
+	t.Helper()
+	q, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return database.New(q)
+}
+
+func createUser(t *testing.T, q *database.Queries, name string) database.User {
+	t.Helper()
+	now := time.Now().UTC()
+	user, err := q.CreateUser(context.Background(), database.CreateUserParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func createFeed(t *testing.T, q *database.Queries, user database.User, url string) database.Feed {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Now().UTC()
+	feed, err := q.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), CreatedAt: now, UpdatedAt: now, Name: url, Url: url, UserID: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{UserID: user.ID, FeedID: feed.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return feed
+}
+
+func TestPQArrayJSON(t *testing.T) {
+	id := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	tests := []struct {
+		arg  interface{}
+		want interface{}
+	}{
+		{pq.Array([]uuid.UUID{id}), `["6ba7b810-9dad-11d1-80b4-00c04fd430c8"]`},
+		{pq.Array([]string{commaUrl, quoteUrl}), `["https://example.com/feed?tags=a,b","https://example.com/feed?q=\"x\"\\y"]`},
+		{pq.Array([]int64{1, 2}), `[1,2]`},
+		{pq.Array([]string{}), `[]`},
+		{pq.Array([]string(nil)), nil},
+		{pq.Array([]uuid.UUID(nil)), nil},
+	}
+	for _, test := range tests {
+		got, ok := pqArrayJSON(test.arg)
+		if !ok || got != test.want {
+			t.Errorf("pqArrayJSON(%#v) = %#v, %v, want %#v", test.arg, got, ok, test.want)
+		}
+	}
+
+	for _, arg := range []interface{}{nil, "{a,b}", []string{"a"}, id} {
+		_, ok := pqArrayJSON(arg)
+		if ok {
+			t.Errorf("pqArrayJSON(%#v) converted an argument that isn't a pq array", arg)
+		}
+	}
+}
+
+func TestGetFeedByUrls(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestDB(t, 0)
+	q := newQueries(t, db)
+	alice := createUser(t, q, "alice")
+	commaFeed := createFeed(t, q, alice, commaUrl)
+	quoteFeed := createFeed(t, q, alice, quoteUrl)
+
+	for _, test := range []struct {
+		urls []string
+		want database.Feed
+	}{
+		{[]string{commaUrl}, commaFeed},
+		{[]string{quoteUrl}, quoteFeed},
+		// The first url that is found wins.
+		{[]string{"https://example.com/missing", quoteUrl, commaUrl}, quoteFeed},
+	} {
+		feed, err := q.GetFeedByUrls(ctx, test.urls)
+		if err != nil {
+			t.Errorf("GetFeedByUrls(%q): %v", test.urls, err)
+			continue
+		}
+		if feed.ID != test.want.ID {
+			t.Errorf("GetFeedByUrls(%q) found %v, want %v", test.urls, feed.Url, test.want.Url)
+		}
+	}
+
+	// Neither half of a url with a comma is a url of its own.
+	_, err := q.GetFeedByUrls(ctx, []string{"https://example.com/feed?tags=a", "b"})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetFeedByUrls found a feed by part of its url: %v", err)
+	}
+}
+
+func TestViewArrays(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestDB(t, 0)
+	q := newQueries(t, db)
+	alice := createUser(t, q, "alice")
+	blog := createFeed(t, q, alice, "https://blog.example/feed")
+	news := createFeed(t, q, alice, "https://news.example/feed")
+	for _, feed := range []database.Feed{blog, news} {
+		_, err := q.CreatePost(ctx, database.CreatePostParams{Title: feed.Url, Url: feed.Url + "/1", FeedID: feed.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tag, err := q.UpsertTag(ctx, database.UpsertTagParams{UserID: alice.ID, Name: commaTag})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = q.TagFeedFollow(ctx, database.TagFeedFollowParams{TagID: tag.ID, UserID: alice.ID, FeedID: news.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name    string
+		feedIDs []uuid.UUID
+		tags    []string
+		want    []string
+	}{
+		{"everything", nil, nil, []string{blog.Url, news.Url}},
+		{"blog", []uuid.UUID{blog.ID}, nil, []string{blog.Url}},
+		{"both", []uuid.UUID{news.ID, blog.ID}, nil, []string{blog.Url, news.Url}},
+		{"tagged", nil, []string{quotedTag, commaTag}, []string{news.Url}},
+		{"not tagged", nil, []string{quotedTag, "news"}, nil},
+		{"none", []uuid.UUID{}, []string{}, nil},
+	} {
+		saved, err := q.SaveView(ctx, database.SaveViewParams{UserID: alice.ID, Name: test.name, FeedIds: test.feedIDs, Tags: test.tags})
+		if err != nil {
+			t.Fatalf("saving view %v: %v", test.name, err)
+		}
+		view, err := q.GetViewByName(ctx, database.GetViewByNameParams{UserID: alice.ID, Name: test.name})
+		if err != nil {
+			t.Fatalf("getting view %v: %v", test.name, err)
+		}
+		for _, got := range []database.View{saved, view} {
+			if !reflect.DeepEqual(got.FeedIds, test.feedIDs) || !reflect.DeepEqual(got.Tags, test.tags) {
+				t.Errorf("view %v has feeds %#v and tags %#v, want %#v and %#v", test.name, got.FeedIds, got.Tags, test.feedIDs, test.tags)
+			}
+		}
+
+		posts, err := q.GetPostsForView(ctx, database.GetPostsForViewParams{ViewID: view.ID, Limit: 10})
+		if err != nil {
+			t.Fatalf("getting posts for view %v: %v", test.name, err)
+		}
+		var got []string
+		for _, post := range posts {
+			got = append(got, post.Title)
+		}
+		if len(got) != len(test.want) || len(got) > 0 && !sameElements(got, test.want) {
+			t.Errorf("view %v has posts %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestGetPostItemsByShortIDs(t *testing.T) {
+	ctx := context.Background()
+	db, _ := openTestDB(t, 0)
+	q := newQueries(t, db)
+	alice := createUser(t, q, "alice")
+	feed := createFeed(t, q, alice, "https://blog.example/feed")
+	var posts []database.Post
+	for _, url := range []string{"/1", "/2", "/3"} {
+		post, err := q.CreatePost(ctx, database.CreatePostParams{Title: url, Url: feed.Url + url, FeedID: feed.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		posts = append(posts, post)
+	}
+
+	items, err := q.GetPostItemsByShortIDs(ctx, database.GetPostItemsByShortIDsParams{
+		UserID:   alice.ID,
+		ShortIds: []int64{posts[2].ShortID, posts[0].ShortID, 12345},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].ShortID != posts[0].ShortID || items[1].ShortID != posts[2].ShortID {
+		t.Errorf("got posts %+v, want /1 and /3", items)
+	}
+}
+
+// TestMigrateViewsToJSON checks that migration 2 turns the Postgres array
+// literals earlier versions of gator stored into JSON, and back.
+func TestMigrateViewsToJSON(t *testing.T) {
+	ctx := context.Background()
+	db, provider := openTestDB(t, 1)
+	q := newQueries(t, db)
+	alice := createUser(t, q, "alice")
+	feedID := uuid.New()
+	_, err := db.ExecContext(ctx, `INSERT INTO views (user_id, name, feed_ids, tags) VALUES (?, 'old', ?, ?)`,
+		alice.ID, pq.Array([]uuid.UUID{feedID}), pq.Array([]string{commaTag, quotedTag}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.ExecContext(ctx, `INSERT INTO views (user_id, name) VALUES (?, 'empty')`, alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := func(name string) (feedIDs, tags sql.NullString) {
+		t.Helper()
+		err := db.QueryRowContext(ctx, `SELECT feed_ids, tags FROM views WHERE name = ?`, name).Scan(&feedIDs, &tags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return feedIDs, tags
+	}
+
+	_, err = provider.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feedIDs, tags := stored("old")
+	if feedIDs.String != `["`+feedID.String()+`"]` || tags.String != `["news, world","say \"hi\" \\o/"]` {
+		t.Errorf("migrated view has feeds %v and tags %v, want JSON arrays", feedIDs.String, tags.String)
+	}
+	view, err := q.GetViewByName(ctx, database.GetViewByNameParams{UserID: alice.ID, Name: "old"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(view.FeedIds, []uuid.UUID{feedID}) || !reflect.DeepEqual(view.Tags, []string{commaTag, quotedTag}) {
+		t.Errorf("migrated view has feeds %v and tags %q", view.FeedIds, view.Tags)
+	}
+	if feedIDs, tags := stored("empty"); feedIDs.Valid || tags.Valid {
+		t.Errorf("migration filled in a view without feeds or tags: %v, %v", feedIDs, tags)
+	}
+
+	_, err = provider.DownTo(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	feedIDs, tags = stored("old")
+	if feedIDs.String != `{"`+feedID.String()+`"}` || tags.String != `{"news, world","say \"hi\" \\o/"}` {
+		t.Errorf("view migrated down has feeds %v and tags %v, want Postgres array literals", feedIDs.String, tags.String)
+	}
+}
+
+func sameElements(a, b []string) bool {
+	counts := map[string]int{}
+	for _, s := range a {
+		counts[s]++
+	}
+	for _, s := range b {
+		counts[s]--
+	}
+	for _, n := range counts {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,321 @@
+package store
+
+import (
+	"bytes"
+	"cmp"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// Memory is a Store that keeps everything in memory and loses it when the
+// process exits, for testing commands without a database. Its methods do
+// what the queries in sql/queries do, down to the order of the rows they
+// return, unique keys and ON DELETE CASCADE; memory_test.go checks this
+// against the SQLite backend.
+type Memory struct {
+	mu sync.Mutex
+
+	users  []database.User
+	tokens []database.ApiToken
+
+	feeds         []database.Feed
+	follows       []database.FeedFollow
+	subscriptions []database.WebsubSubscription
+	lastFeedID    int64
+
+	tags       []database.Tag
+	followTags []database.FeedFollowTag
+	lastTagID  int64
+
+	posts       []database.Post
+	reads       []database.PostRead
+	stars       []database.PostStar
+	prunedPosts map[string]time.Time
+	lastPostID  int64
+	fullTexts   []database.FullTextFetch
+
+	rules      []database.PostRule
+	views      []database.View
+	webhooks   []database.Webhook
+	deliveries []database.WebhookDelivery
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory returns an empty Memory.
+func NewMemory() *Memory {
+	return &Memory{prunedPosts: map[string]time.Time{}}
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func duplicateKey(constraint string) error {
+	return fmt.Errorf("%w value violates unique constraint %q", ErrDuplicateKey, constraint)
+}
+
+func foreignKey(table, column string) error {
+	return fmt.Errorf("insert or update on table %q violates foreign key constraint on %v", table, column)
+}
+
+func find[T any](rows []T, match func(*T) bool) *T {
+	for i := range rows {
+		if match(&rows[i]) {
+			return &rows[i]
+		}
+	}
+	return nil
+}
+
+func count[T any](rows []T, match func(*T) bool) int64 {
+	var n int64
+	for i := range rows {
+		if match(&rows[i]) {
+			n++
+		}
+	}
+	return n
+}
+
+func deleteWhere[T any](rows *[]T, match func(*T) bool) int64 {
+	n := len(*rows)
+	*rows = slices.DeleteFunc(*rows, func(row T) bool {
+		return match(&row)
+	})
+	return int64(n - len(*rows))
+}
+
+// page applies LIMIT and OFFSET to rows.
+func page[T any](rows []T, limit, offset int32) []T {
+	start := min(max(int(offset), 0), len(rows))
+	end := min(start+max(int(limit), 0), len(rows))
+	return rows[start:end]
+}
+
+func (m *Memory) user(id uuid.UUID) *database.User {
+	return find(m.users, func(u *database.User) bool { return u.ID == id })
+}
+
+func (m *Memory) feed(id uuid.UUID) *database.Feed {
+	return find(m.feeds, func(f *database.Feed) bool { return f.ID == id })
+}
+
+func (m *Memory) post(id uuid.UUID) *database.Post {
+	return find(m.posts, func(p *database.Post) bool { return p.ID == id })
+}
+
+func (m *Memory) follow(userID, feedID uuid.UUID) *database.FeedFollow {
+	return find(m.follows, func(ff *database.FeedFollow) bool {
+		return ff.UserID == userID && ff.FeedID == feedID
+	})
+}
+
+func (m *Memory) subscription(feedID uuid.UUID) *database.WebsubSubscription {
+	return find(m.subscriptions, func(sub *database.WebsubSubscription) bool { return sub.FeedID == feedID })
+}
+
+func (m *Memory) isRead(userID, postID uuid.UUID) bool {
+	return find(m.reads, func(r *database.PostRead) bool { return r.UserID == userID && r.PostID == postID }) != nil
+}
+
+func (m *Memory) isStarred(userID, postID uuid.UUID) bool {
+	return find(m.stars, func(s *database.PostStar) bool { return s.UserID == userID && s.PostID == postID }) != nil
+}
+
+// followedPosts returns the posts of the feeds userID follows, with the
+// follows, in short_id order.
+func (m *Memory) followedPosts(userID uuid.UUID) ([]*database.Post, []*database.FeedFollow) {
+	var posts []*database.Post
+	var follows []*database.FeedFollow
+	for i := range m.posts {
+		ff := m.follow(userID, m.posts[i].FeedID)
+		if ff != nil {
+			posts = append(posts, &m.posts[i])
+			follows = append(follows, ff)
+		}
+	}
+	return posts, follows
+}
+
+// followName is the name a follower sees for a feed.
+func (m *Memory) followName(ff *database.FeedFollow) string {
+	if ff.DisplayName.Valid {
+		return ff.DisplayName.String
+	}
+	return m.feed(ff.FeedID).Name
+}
+
+// followTagNames returns the names of the tags on a follow, sorted.
+func (m *Memory) followTagNames(followID uuid.UUID) []string {
+	var names []string
+	for _, ft := range m.followTags {
+		if ft.FeedFollowID != followID {
+			continue
+		}
+		tag := find(m.tags, func(t *database.Tag) bool { return t.ID == ft.TagID })
+		if tag != nil {
+			names = append(names, tag.Name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// The deletes below cascade like the foreign keys in sql/schema.
+
+func (m *Memory) deleteUsers(match func(*database.User) bool) int64 {
+	ids := map[uuid.UUID]bool{}
+	n := deleteWhere(&m.users, func(u *database.User) bool {
+		ids[u.ID] = match(u)
+		return ids[u.ID]
+	})
+	m.deleteFeeds(func(f *database.Feed) bool { return ids[f.UserID] })
+	m.deleteUserData(func(userID uuid.UUID) bool { return ids[userID] })
+	deleteWhere(&m.tokens, func(t *database.ApiToken) bool { return ids[t.UserID] })
+	return n
+}
+
+// deleteUserData deletes what users have besides their account, feeds and
+// API tokens.
+func (m *Memory) deleteUserData(match func(userID uuid.UUID) bool) {
+	m.deleteFollows(func(ff *database.FeedFollow) bool { return match(ff.UserID) })
+	m.deleteTags(func(t *database.Tag) bool { return match(t.UserID) })
+	deleteWhere(&m.reads, func(r *database.PostRead) bool { return match(r.UserID) })
+	deleteWhere(&m.stars, func(s *database.PostStar) bool { return match(s.UserID) })
+	deleteWhere(&m.rules, func(r *database.PostRule) bool { return match(r.UserID) })
+	deleteWhere(&m.views, func(v *database.View) bool { return match(v.UserID) })
+	m.deleteWebhooks(func(w *database.Webhook) bool { return match(w.UserID) })
+}
+
+func (m *Memory) deleteFeeds(match func(*database.Feed) bool) int64 {
+	ids := map[uuid.UUID]bool{}
+	n := deleteWhere(&m.feeds, func(f *database.Feed) bool {
+		ids[f.ID] = match(f)
+		return ids[f.ID]
+	})
+	m.deleteFollows(func(ff *database.FeedFollow) bool { return ids[ff.FeedID] })
+	m.deletePosts(func(p *database.Post) bool { return ids[p.FeedID] })
+	m.deleteWebhooks(func(w *database.Webhook) bool { return w.FeedID.Valid && ids[w.FeedID.UUID] })
+	deleteWhere(&m.rules, func(r *database.PostRule) bool { return r.FeedID.Valid && ids[r.FeedID.UUID] })
+	deleteWhere(&m.subscriptions, func(sub *database.WebsubSubscription) bool { return ids[sub.FeedID] })
+	return n
+}
+
+func (m *Memory) deleteFollows(match func(*database.FeedFollow) bool) int64 {
+	ids := map[uuid.UUID]bool{}
+	n := deleteWhere(&m.follows, func(ff *database.FeedFollow) bool {
+		ids[ff.ID] = match(ff)
+		return ids[ff.ID]
+	})
+	deleteWhere(&m.followTags, func(ft *database.FeedFollowTag) bool { return ids[ft.FeedFollowID] })
+	return n
+}
+
+func (m *Memory) deleteTags(match func(*database.Tag) bool) int64 {
+	ids := map[uuid.UUID]bool{}
+	n := deleteWhere(&m.tags, func(t *database.Tag) bool {
+		ids[t.ID] = match(t)
+		return ids[t.ID]
+	})
+	deleteWhere(&m.followTags, func(ft *database.FeedFollowTag) bool { return ids[ft.TagID] })
+	return n
+}
+
+func (m *Memory) deletePosts(match func(*database.Post) bool) int64 {
+	ids := map[uuid.UUID]bool{}
+	n := deleteWhere(&m.posts, func(p *database.Post) bool {
+		ids[p.ID] = match(p)
+		return ids[p.ID]
+	})
+	deleteWhere(&m.reads, func(r *database.PostRead) bool { return ids[r.PostID] })
+	deleteWhere(&m.stars, func(s *database.PostStar) bool { return ids[s.PostID] })
+	deleteWhere(&m.deliveries, func(d *database.WebhookDelivery) bool { return ids[d.PostID] })
+	deleteWhere(&m.fullTexts, func(f *database.FullTextFetch) bool { return ids[f.PostID] })
+	return n
+}
+
+func (m *Memory) deleteWebhooks(match func(*database.Webhook) bool) int64 {
+	ids := map[uuid.UUID]bool{}
+	n := deleteWhere(&m.webhooks, func(w *database.Webhook) bool {
+		ids[w.ID] = match(w)
+		return ids[w.ID]
+	})
+	deleteWhere(&m.deliveries, func(d *database.WebhookDelivery) bool { return ids[d.WebhookID] })
+	return n
+}
+
+// compareNullTimes orders a before b like ORDER BY in Postgres, which puts
+// NULLs last in ascending order and first in descending order.
+func compareNullTimes(a, b sql.NullTime) int {
+	switch {
+	case !a.Valid && !b.Valid:
+		return 0
+	case !a.Valid:
+		return 1
+	case !b.Valid:
+		return -1
+	}
+	return a.Time.Compare(b.Time)
+}
+
+// compareNullTimesNullsFirst is compareNullTimes for ORDER BY ... NULLS
+// FIRST.
+func compareNullTimesNullsFirst(a, b sql.NullTime) int {
+	if a.Valid != b.Valid {
+		return -compareNullTimes(a, b)
+	}
+	return compareNullTimes(a, b)
+}
+
+// compareNullTimesDescNullsLast is compareNullTimes for ORDER BY ... DESC
+// NULLS LAST.
+func compareNullTimesDescNullsLast(a, b sql.NullTime) int {
+	if a.Valid != b.Valid {
+		return compareNullTimes(a, b)
+	}
+	return -compareNullTimes(a, b)
+}
+
+func compareUUIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// compareNewestFirst is the order posts are browsed in.
+func compareNewestFirst(a, b *database.Post) int {
+	return cmp.Or(
+		-compareNullTimes(a.PublishedAt, b.PublishedAt),
+		b.UpdatedAt.Compare(a.UpdatedAt),
+		b.CreatedAt.Compare(a.CreatedAt),
+	)
+}
+
+// postedAt is when a post was published, or saved if its feed didn't say.
+func postedAt(p *database.Post) time.Time {
+	if p.PublishedAt.Valid {
+		return p.PublishedAt.Time
+	}
+	return p.CreatedAt
+}
+
+// containsKeyword reports whether a post's title or description contains
+// keyword, ignoring case, or keyword is NULL.
+func containsKeyword(p *database.Post, keyword sql.NullString) bool {
+	if !keyword.Valid {
+		return true
+	}
+	k := strings.ToLower(keyword.String)
+	return strings.Contains(strings.ToLower(p.Title), k) ||
+		p.Description.Valid && strings.Contains(strings.ToLower(p.Description.String), k)
+}
+
+func seconds(n int32) time.Duration {
+	return time.Duration(n) * time.Second
+}
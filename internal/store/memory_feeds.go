@@ -0,0 +1,620 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+func (m *Memory) feedByUrl(url string) *database.Feed {
+	return find(m.feeds, func(f *database.Feed) bool { return f.Url == url })
+}
+
+func (m *Memory) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.feed(arg.ID) != nil {
+		return database.Feed{}, duplicateKey("feeds_pkey")
+	}
+	if m.feedByUrl(arg.Url) != nil {
+		return database.Feed{}, duplicateKey("feeds_url_key")
+	}
+	if m.user(arg.UserID) == nil {
+		return database.Feed{}, foreignKey("feeds", "user_id")
+	}
+	m.lastFeedID++
+	m.feeds = append(m.feeds, database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+		ShortID:   m.lastFeedID,
+	})
+	return m.feeds[len(m.feeds)-1], nil
+}
+
+// getFeed returns the first feed match reports.
+func (m *Memory) getFeed(match func(*database.Feed) bool) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feed := find(m.feeds, match)
+	if feed == nil {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return *feed, nil
+}
+
+func (m *Memory) GetFeed(ctx context.Context, name string) (database.Feed, error) {
+	return m.getFeed(func(f *database.Feed) bool { return f.Name == name })
+}
+
+func (m *Memory) GetFeedByID(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	return m.getFeed(func(f *database.Feed) bool { return f.ID == id })
+}
+
+func (m *Memory) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	return m.getFeed(func(f *database.Feed) bool { return f.Url == url })
+}
+
+func (m *Memory) GetFeedByUrls(ctx context.Context, urls []string) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, url := range urls {
+		if feed := m.feedByUrl(url); feed != nil {
+			return *feed, nil
+		}
+	}
+	return database.Feed{}, sql.ErrNoRows
+}
+
+func (m *Memory) GetFeeds(ctx context.Context) ([]database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.feeds), nil
+}
+
+func (m *Memory) GetUserFeeds(ctx context.Context, userID uuid.UUID) ([]database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var feeds []database.Feed
+	for _, feed := range m.feeds {
+		if feed.UserID == userID {
+			feeds = append(feeds, feed)
+		}
+	}
+	return feeds, nil
+}
+
+func (m *Memory) GetFeedsByUser(ctx context.Context) ([]database.GetFeedsByUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetFeedsByUserRow
+	for _, feed := range m.feeds {
+		row := database.GetFeedsByUserRow{Name: feed.Name, Url: feed.Url}
+		if user := m.user(feed.UserID); user != nil {
+			row.Name_2 = sql.NullString{String: user.Name, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	slices.SortStableFunc(rows, func(a, b database.GetFeedsByUserRow) int {
+		if a.Name_2.Valid != b.Name_2.Valid {
+			if a.Name_2.Valid {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(strings.Compare(a.Name_2.String, b.Name_2.String), strings.Compare(a.Name, b.Name))
+	})
+	return rows, nil
+}
+
+func (m *Memory) GetFeedsHealth(ctx context.Context) ([]database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feeds := slices.Clone(m.feeds)
+	slices.SortStableFunc(feeds, func(a, b database.Feed) int {
+		return cmp.Or(
+			compareNullTimes(a.DisabledAt, b.DisabledAt),
+			cmp.Compare(b.ConsecutiveFailures, a.ConsecutiveFailures),
+			strings.Compare(a.Name, b.Name),
+		)
+	})
+	return feeds, nil
+}
+
+// updateFeed runs update on the feed with id and returns it, or
+// sql.ErrNoRows if there is none.
+func (m *Memory) updateFeed(id uuid.UUID, update func(*database.Feed) error) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feed := m.feed(id)
+	if feed == nil {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	updated := *feed
+	err := update(&updated)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	if other := m.feedByUrl(updated.Url); other != nil && other != feed {
+		return database.Feed{}, duplicateKey("feeds_url_key")
+	}
+	updated.UpdatedAt = now()
+	*feed = updated
+	return updated, nil
+}
+
+// execUpdateFeed is updateFeed for queries that don't care whether the
+// feed exists.
+func (m *Memory) execUpdateFeed(id uuid.UUID, update func(*database.Feed)) error {
+	_, err := m.updateFeed(id, func(f *database.Feed) error {
+		update(f)
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
+
+func (m *Memory) UpdateFeed(ctx context.Context, arg database.UpdateFeedParams) (database.Feed, error) {
+	return m.updateFeed(arg.ID, func(f *database.Feed) error {
+		if f.Url != arg.Url {
+			f.RedirectUrl = sql.NullString{}
+			f.RedirectCount = 0
+		}
+		f.Name = arg.Name
+		f.Url = arg.Url
+		f.FetchIntervalSeconds = arg.FetchIntervalSeconds
+		f.FetchFullText = arg.FetchFullText
+		f.DisabledAt = arg.DisabledAt
+		if !arg.DisabledAt.Valid {
+			f.ConsecutiveFailures = 0
+		}
+		return nil
+	})
+}
+
+func (m *Memory) UpdateFeedMetadata(ctx context.Context, arg database.UpdateFeedMetadataParams) error {
+	return m.execUpdateFeed(arg.ID, func(f *database.Feed) {
+		f.Title = arg.Title
+		f.SiteUrl = arg.SiteUrl
+		f.Description = arg.Description
+		f.Language = arg.Language
+		f.ImageUrl = arg.ImageUrl
+		f.Generator = arg.Generator
+	})
+}
+
+func (m *Memory) SetFeedUrl(ctx context.Context, arg database.SetFeedUrlParams) error {
+	return m.execUpdateFeed(arg.ID, func(f *database.Feed) {
+		f.Url = arg.Url
+		f.RedirectUrl = sql.NullString{}
+		f.RedirectCount = 0
+	})
+}
+
+func (m *Memory) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error {
+	return m.execUpdateFeed(arg.ID, func(f *database.Feed) {
+		f.RetentionMaxAgeSeconds = arg.RetentionMaxAgeSeconds
+		f.RetentionMaxPosts = arg.RetentionMaxPosts
+	})
+}
+
+func (m *Memory) EnableFeed(ctx context.Context, url string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	feed := m.feedByUrl(url)
+	if feed == nil {
+		return 0, nil
+	}
+	feed.DisabledAt = sql.NullTime{}
+	feed.ConsecutiveFailures = 0
+	feed.UpdatedAt = now()
+	return 1, nil
+}
+
+func (m *Memory) CountFeedFollowers(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return count(m.follows, func(ff *database.FeedFollow) bool { return ff.FeedID == feedID }), nil
+}
+
+func (m *Memory) DeleteFeed(ctx context.Context, url string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteFeeds(func(f *database.Feed) bool { return f.Url == url })
+	return nil
+}
+
+func (m *Memory) DeleteFeedByID(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteFeeds(func(f *database.Feed) bool { return f.ID == id })
+	return nil
+}
+
+func (m *Memory) DeleteFeeds(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteFeeds(func(*database.Feed) bool { return true }), nil
+}
+
+// pushedTo reports whether a feed has a verified WebSub subscription with
+// an unexpired lease, so it needn't be polled.
+func (m *Memory) pushedTo(feed *database.Feed, now time.Time) bool {
+	sub := m.subscription(feed.ID)
+	return sub != nil && sub.State == "verified" && !(sub.LeaseExpiresAt.Valid && sub.LeaseExpiresAt.Time.Before(now))
+}
+
+func (m *Memory) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := now()
+	var next *database.Feed
+	for i := range m.feeds {
+		feed := &m.feeds[i]
+		if feed.DisabledAt.Valid {
+			continue
+		}
+		fetched := feed.LastFetchedAt
+		if m.pushedTo(feed, now) && fetched.Valid && !fetched.Time.Before(now.Add(-24*time.Hour)) {
+			continue
+		}
+		interval := feed.FetchIntervalSeconds
+		if interval.Valid && fetched.Valid && !fetched.Time.Before(now.Add(-seconds(interval.Int32))) {
+			continue
+		}
+		if next == nil || cmp.Or(compareNullTimesNullsFirst(fetched, next.LastFetchedAt), compareUUIDs(feed.ID, next.ID)) < 0 {
+			next = feed
+		}
+	}
+	if next == nil {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return *next, nil
+}
+
+func (m *Memory) GetFeedQueueLag(ctx context.Context) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := now()
+	var oldest *time.Time
+	for i := range m.feeds {
+		feed := &m.feeds[i]
+		if feed.DisabledAt.Valid || m.pushedTo(feed, now) {
+			continue
+		}
+		fetched := feed.CreatedAt
+		if feed.LastFetchedAt.Valid {
+			fetched = feed.LastFetchedAt.Time
+		}
+		if oldest == nil || fetched.Before(*oldest) {
+			oldest = &fetched
+		}
+	}
+	if oldest == nil {
+		return 0, nil
+	}
+	return now.Sub(*oldest).Seconds(), nil
+}
+
+func (m *Memory) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	return m.execUpdateFeed(id, func(f *database.Feed) {
+		f.LastFetchedAt = sql.NullTime{Time: now(), Valid: true}
+	})
+}
+
+func (m *Memory) RecordFeedSuccess(ctx context.Context, arg database.RecordFeedSuccessParams) error {
+	return m.execUpdateFeed(arg.ID, func(f *database.Feed) {
+		f.LastStatusCode = arg.LastStatusCode
+		f.LastError = sql.NullString{}
+		f.ConsecutiveFailures = 0
+		f.LastSucceededAt = sql.NullTime{Time: now(), Valid: true}
+	})
+}
+
+func (m *Memory) RecordFeedFailure(ctx context.Context, arg database.RecordFeedFailureParams) (database.Feed, error) {
+	return m.updateFeed(arg.ID, func(f *database.Feed) error {
+		f.LastStatusCode = arg.LastStatusCode
+		f.LastError = arg.LastError
+		f.ConsecutiveFailures++
+		if arg.MaxFailures > 0 && f.ConsecutiveFailures >= arg.MaxFailures {
+			f.DisabledAt = sql.NullTime{Time: now(), Valid: true}
+		}
+		return nil
+	})
+}
+
+func (m *Memory) RecordFeedRedirect(ctx context.Context, arg database.RecordFeedRedirectParams) (database.Feed, error) {
+	return m.updateFeed(arg.ID, func(f *database.Feed) error {
+		if f.RedirectUrl.Valid && arg.RedirectUrl.Valid && f.RedirectUrl.String == arg.RedirectUrl.String {
+			f.RedirectCount++
+		} else {
+			f.RedirectCount = 1
+		}
+		f.RedirectUrl = arg.RedirectUrl
+		return nil
+	})
+}
+
+func (m *Memory) ClearFeedRedirect(ctx context.Context, id uuid.UUID) error {
+	return m.execUpdateFeed(id, func(f *database.Feed) {
+		f.RedirectUrl = sql.NullString{}
+		f.RedirectCount = 0
+	})
+}
+
+func (m *Memory) MergeFeedFollows(ctx context.Context, arg database.MergeFeedFollowsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ff := range slices.Clone(m.follows) {
+		if ff.FeedID != arg.FromFeedID || m.follow(ff.UserID, arg.ToFeedID) != nil {
+			continue
+		}
+		_, err := m.createFeedFollow(ff.UserID, arg.ToFeedID, ff.DisplayName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) MergeFeedFollowTags(ctx context.Context, arg database.MergeFeedFollowTagsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, ft := range slices.Clone(m.followTags) {
+		from := find(m.follows, func(ff *database.FeedFollow) bool { return ff.ID == ft.FeedFollowID })
+		if from == nil || from.FeedID != arg.FromFeedID {
+			continue
+		}
+		to := m.follow(from.UserID, arg.ToFeedID)
+		if to != nil {
+			m.tagFeedFollow(to.ID, ft.TagID)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) MergeFeedPosts(ctx context.Context, arg database.MergeFeedPostsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.posts {
+		if m.posts[i].FeedID == arg.FromFeedID {
+			if m.feed(arg.ToFeedID) == nil {
+				return foreignKey("posts", "feed_id")
+			}
+			m.posts[i].FeedID = arg.ToFeedID
+			m.posts[i].UpdatedAt = now()
+		}
+	}
+	return nil
+}
+
+func (m *Memory) MergeFeedWebhooks(ctx context.Context, arg database.MergeFeedWebhooksParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.webhooks {
+		if m.webhooks[i].FeedID == (uuid.NullUUID{UUID: arg.FromFeedID, Valid: true}) {
+			if m.feed(arg.ToFeedID) == nil {
+				return foreignKey("webhooks", "feed_id")
+			}
+			m.webhooks[i].FeedID.UUID = arg.ToFeedID
+			m.webhooks[i].UpdatedAt = now()
+		}
+	}
+	return nil
+}
+
+func (m *Memory) MergeFeedPostRules(ctx context.Context, arg database.MergeFeedPostRulesParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.rules {
+		if m.rules[i].FeedID == (uuid.NullUUID{UUID: arg.FromFeedID, Valid: true}) {
+			if m.feed(arg.ToFeedID) == nil {
+				return foreignKey("post_rules", "feed_id")
+			}
+			m.rules[i].FeedID.UUID = arg.ToFeedID
+		}
+	}
+	return nil
+}
+
+func (m *Memory) MergeFeedViews(ctx context.Context, arg database.MergeFeedViewsParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.views {
+		view := &m.views[i]
+		if !slices.Contains(view.FeedIds, arg.FromFeedID) {
+			continue
+		}
+		view.FeedIds = slices.Clone(view.FeedIds)
+		for j, id := range view.FeedIds {
+			if id == arg.FromFeedID {
+				view.FeedIds[j] = arg.ToFeedID
+			}
+		}
+		view.UpdatedAt = now()
+	}
+	return nil
+}
+
+func (m *Memory) createFeedFollow(userID, feedID uuid.UUID, displayName sql.NullString) (*database.FeedFollow, error) {
+	if m.follow(userID, feedID) != nil {
+		return nil, duplicateKey("feed_follows_user_id_feed_id_key")
+	}
+	if m.user(userID) == nil {
+		return nil, foreignKey("feed_follows", "user_id")
+	}
+	if m.feed(feedID) == nil {
+		return nil, foreignKey("feed_follows", "feed_id")
+	}
+	now := now()
+	m.follows = append(m.follows, database.FeedFollow{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		UserID:      userID,
+		FeedID:      feedID,
+		DisplayName: displayName,
+	})
+	return &m.follows[len(m.follows)-1], nil
+}
+
+func (m *Memory) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ff, err := m.createFeedFollow(arg.UserID, arg.FeedID, sql.NullString{})
+	if err != nil {
+		return database.CreateFeedFollowRow{}, err
+	}
+	return database.CreateFeedFollowRow{
+		ID:          ff.ID,
+		CreatedAt:   ff.CreatedAt,
+		UpdatedAt:   ff.UpdatedAt,
+		UserID:      ff.UserID,
+		FeedID:      ff.FeedID,
+		DisplayName: ff.DisplayName,
+		FeedName:    m.feed(ff.FeedID).Name,
+		UserName:    m.user(ff.UserID).Name,
+	}, nil
+}
+
+func (m *Memory) GetFeedFollowsForUser(ctx context.Context, arg database.GetFeedFollowsForUserParams) ([]database.GetFeedFollowsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetFeedFollowsForUserRow
+	for i := range m.follows {
+		ff := &m.follows[i]
+		if ff.UserID != arg.UserID {
+			continue
+		}
+		tags := m.followTagNames(ff.ID)
+		if arg.Tag.Valid && !slices.Contains(tags, arg.Tag.String) {
+			continue
+		}
+		rows = append(rows, database.GetFeedFollowsForUserRow{
+			Follower: m.user(ff.UserID).Name,
+			FeedName: m.followName(ff),
+			FeedUrl:  m.feed(ff.FeedID).Url,
+			Tags:     strings.Join(tags, ","),
+		})
+	}
+	slices.SortStableFunc(rows, func(a, b database.GetFeedFollowsForUserRow) int {
+		return strings.Compare(a.FeedName, b.FeedName)
+	})
+	return rows, nil
+}
+
+func (m *Memory) GetFollowedFeeds(ctx context.Context, userID uuid.UUID) ([]database.GetFollowedFeedsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type followedFeed struct {
+		row  database.GetFollowedFeedsRow
+		name string
+	}
+	var feeds []followedFeed
+	for i := range m.follows {
+		ff := &m.follows[i]
+		if ff.UserID != userID {
+			continue
+		}
+		f := m.feed(ff.FeedID)
+		feeds = append(feeds, followedFeed{
+			row: database.GetFollowedFeedsRow{
+				ID:                     f.ID,
+				CreatedAt:              f.CreatedAt,
+				UpdatedAt:              f.UpdatedAt,
+				Name:                   f.Name,
+				Url:                    f.Url,
+				UserID:                 f.UserID,
+				LastFetchedAt:          f.LastFetchedAt,
+				ShortID:                f.ShortID,
+				RetentionMaxAgeSeconds: f.RetentionMaxAgeSeconds,
+				RetentionMaxPosts:      f.RetentionMaxPosts,
+				LastStatusCode:         f.LastStatusCode,
+				LastError:              f.LastError,
+				ConsecutiveFailures:    f.ConsecutiveFailures,
+				LastSucceededAt:        f.LastSucceededAt,
+				DisabledAt:             f.DisabledAt,
+				RedirectUrl:            f.RedirectUrl,
+				RedirectCount:          f.RedirectCount,
+				Title:                  f.Title,
+				SiteUrl:                f.SiteUrl,
+				Description:            f.Description,
+				Language:               f.Language,
+				ImageUrl:               f.ImageUrl,
+				Generator:              f.Generator,
+				FetchIntervalSeconds:   f.FetchIntervalSeconds,
+				FetchFullText:          f.FetchFullText,
+				DisplayName:            ff.DisplayName,
+			},
+			name: m.followName(ff),
+		})
+	}
+	slices.SortStableFunc(feeds, func(a, b followedFeed) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	rows := make([]database.GetFollowedFeedsRow, len(feeds))
+	for i, feed := range feeds {
+		rows[i] = feed.row
+	}
+	return rows, nil
+}
+
+func (m *Memory) SetFeedFollowDisplayName(ctx context.Context, arg database.SetFeedFollowDisplayNameParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ff := m.follow(arg.UserID, arg.FeedID)
+	if ff == nil {
+		return 0, nil
+	}
+	ff.DisplayName = arg.DisplayName
+	ff.UpdatedAt = now()
+	return 1, nil
+}
+
+func (m *Memory) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteFollows(func(ff *database.FeedFollow) bool {
+		return ff.UserID == arg.UserID && ff.FeedID == arg.FeedID
+	})
+	return nil
+}
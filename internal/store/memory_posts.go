@@ -0,0 +1,546 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+func (m *Memory) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.prunedPosts[arg.Url]; ok {
+		return database.Post{}, sql.ErrNoRows
+	}
+	if find(m.posts, func(p *database.Post) bool { return p.Url == arg.Url }) != nil {
+		return database.Post{}, duplicateKey("posts_url_key")
+	}
+	if m.feed(arg.FeedID) == nil {
+		return database.Post{}, foreignKey("posts", "feed_id")
+	}
+	now := now()
+	m.lastPostID++
+	m.posts = append(m.posts, database.Post{
+		ID:          uuid.New(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		ShortID:     m.lastPostID,
+		Author:      arg.Author,
+	})
+	return m.posts[len(m.posts)-1], nil
+}
+
+func (m *Memory) GetPostByShortID(ctx context.Context, arg database.GetPostByShortIDParams) (database.Post, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post := find(m.posts, func(p *database.Post) bool { return p.ShortID == arg.ShortID })
+	if post == nil || m.follow(arg.UserID, post.FeedID) == nil {
+		return database.Post{}, sql.ErrNoRows
+	}
+	return *post, nil
+}
+
+// feedPost is a post with the name its follower sees for its feed, the row
+// of the queries that list posts to read.
+func feedPost(p *database.Post, feedName string) database.GetPostsForUserRow {
+	return database.GetPostsForUserRow{
+		ID:          p.ID,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		Title:       p.Title,
+		Url:         p.Url,
+		Description: p.Description,
+		PublishedAt: p.PublishedAt,
+		FeedID:      p.FeedID,
+		ShortID:     p.ShortID,
+		Author:      p.Author,
+		Content:     p.Content,
+		FeedName:    feedName,
+	}
+}
+
+func compareFeedPostsNewestFirst(a, b database.GetPostsForUserRow) int {
+	return cmp.Or(
+		-compareNullTimes(a.PublishedAt, b.PublishedAt),
+		b.UpdatedAt.Compare(a.UpdatedAt),
+		b.CreatedAt.Compare(a.CreatedAt),
+	)
+}
+
+func (m *Memory) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetPostsForUserRow
+	posts, follows := m.followedPosts(arg.UserID)
+	for i, post := range posts {
+		if arg.Tag.Valid && !slices.Contains(m.followTagNames(follows[i].ID), arg.Tag.String) {
+			continue
+		}
+		rows = append(rows, feedPost(post, m.followName(follows[i])))
+	}
+	slices.SortStableFunc(rows, compareFeedPostsNewestFirst)
+	return page(rows, arg.Limit, arg.Offset), nil
+}
+
+func (m *Memory) GetPostsForView(ctx context.Context, arg database.GetPostsForViewParams) ([]database.GetPostsForViewRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	view := find(m.views, func(v *database.View) bool { return v.ID == arg.ViewID })
+	if view == nil {
+		return nil, nil
+	}
+
+	now := now()
+	var rows []database.GetPostsForUserRow
+	posts, follows := m.followedPosts(view.UserID)
+	for i, post := range posts {
+		ff := follows[i]
+		switch {
+		case view.FeedIds != nil && !slices.Contains(view.FeedIds, post.FeedID):
+			continue
+		case view.Tags != nil && !slices.ContainsFunc(m.followTagNames(ff.ID), func(tag string) bool {
+			return slices.Contains(view.Tags, tag)
+		}):
+			continue
+		case !containsKeyword(post, view.Keyword):
+			continue
+		case view.MaxAgeSeconds.Valid && !postedAt(post).After(now.Add(-seconds(view.MaxAgeSeconds.Int32))):
+			continue
+		case view.UnreadOnly && m.isRead(view.UserID, post.ID):
+			continue
+		}
+		rows = append(rows, feedPost(post, m.followName(ff)))
+	}
+	slices.SortStableFunc(rows, compareFeedPostsNewestFirst)
+
+	var viewRows []database.GetPostsForViewRow
+	for _, row := range page(rows, arg.Limit, arg.Offset) {
+		viewRows = append(viewRows, database.GetPostsForViewRow(row))
+	}
+	return viewRows, nil
+}
+
+func (m *Memory) GetDigestPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetDigestPostsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.user(userID)
+	if user == nil {
+		return nil, nil
+	}
+	since := now().Add(-24 * time.Hour)
+	if user.LastDigestAt.Valid {
+		since = user.LastDigestAt.Time
+	}
+
+	var rows []database.GetDigestPostsForUserRow
+	posts, follows := m.followedPosts(userID)
+	for i, post := range posts {
+		if post.CreatedAt.After(since) {
+			rows = append(rows, database.GetDigestPostsForUserRow(feedPost(post, m.followName(follows[i]))))
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b database.GetDigestPostsForUserRow) int {
+		return cmp.Or(
+			strings.Compare(a.FeedName, b.FeedName),
+			compareNullTimesDescNullsLast(a.PublishedAt, b.PublishedAt),
+			b.CreatedAt.Compare(a.CreatedAt),
+		)
+	})
+	return rows, nil
+}
+
+func (m *Memory) GetUnreadPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadPostsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var unread []database.GetUnreadPostsForUserRow
+	posts, _ := m.followedPosts(userID)
+	for _, post := range posts {
+		if !m.isRead(userID, post.ID) {
+			unread = append(unread, database.GetUnreadPostsForUserRow{
+				ID:          post.ID,
+				ShortID:     post.ShortID,
+				FeedID:      post.FeedID,
+				Title:       post.Title,
+				Url:         post.Url,
+				Description: post.Description,
+				Author:      post.Author,
+			})
+		}
+	}
+	return unread, nil
+}
+
+func (m *Memory) GetUnreadPostShortIDs(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []int64
+	posts, _ := m.followedPosts(userID)
+	for _, post := range posts {
+		if !m.isRead(userID, post.ID) {
+			ids = append(ids, post.ShortID)
+		}
+	}
+	return ids, nil
+}
+
+// postItem is a post with its feed's short ID and whether userID read and
+// starred it, the row of the queries the Fever API lists items with.
+func (m *Memory) postItem(userID uuid.UUID, p *database.Post) database.GetPostItemsSinceRow {
+	return database.GetPostItemsSinceRow{
+		ID:          p.ID,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		Title:       p.Title,
+		Url:         p.Url,
+		Description: p.Description,
+		PublishedAt: p.PublishedAt,
+		FeedID:      p.FeedID,
+		ShortID:     p.ShortID,
+		Author:      p.Author,
+		Content:     p.Content,
+		FeedShortID: m.feed(p.FeedID).ShortID,
+		IsRead:      m.isRead(userID, p.ID),
+		IsStarred:   m.isStarred(userID, p.ID),
+	}
+}
+
+func (m *Memory) GetPostItemsSince(ctx context.Context, arg database.GetPostItemsSinceParams) ([]database.GetPostItemsSinceRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetPostItemsSinceRow
+	posts, _ := m.followedPosts(arg.UserID)
+	for _, post := range posts {
+		if post.ShortID > arg.ShortID {
+			rows = append(rows, m.postItem(arg.UserID, post))
+		}
+	}
+	return page(rows, arg.Limit, 0), nil
+}
+
+func (m *Memory) GetPostItemsBefore(ctx context.Context, arg database.GetPostItemsBeforeParams) ([]database.GetPostItemsBeforeRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetPostItemsBeforeRow
+	posts, _ := m.followedPosts(arg.UserID)
+	for _, post := range slices.Backward(posts) {
+		if post.ShortID < arg.ShortID {
+			rows = append(rows, database.GetPostItemsBeforeRow(m.postItem(arg.UserID, post)))
+		}
+	}
+	return page(rows, arg.Limit, 0), nil
+}
+
+func (m *Memory) GetPostItemsByShortIDs(ctx context.Context, arg database.GetPostItemsByShortIDsParams) ([]database.GetPostItemsByShortIDsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetPostItemsByShortIDsRow
+	posts, _ := m.followedPosts(arg.UserID)
+	for _, post := range posts {
+		if slices.Contains(arg.ShortIds, post.ShortID) {
+			rows = append(rows, database.GetPostItemsByShortIDsRow(m.postItem(arg.UserID, post)))
+		}
+	}
+	return rows, nil
+}
+
+func (m *Memory) CountPosts(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return int64(len(m.posts)), nil
+}
+
+func (m *Memory) CountPostsForUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	posts, _ := m.followedPosts(userID)
+	return int64(len(posts)), nil
+}
+
+func (m *Memory) SetPostContent(ctx context.Context, arg database.SetPostContentParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if post := m.post(arg.ID); post != nil {
+		post.Content = arg.Content
+		post.UpdatedAt = now()
+	}
+	return nil
+}
+
+func (m *Memory) EnqueueFullTextFetch(ctx context.Context, arg database.EnqueueFullTextFetchParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.post(arg.PostID) == nil {
+		return foreignKey("full_text_fetches", "post_id")
+	}
+	if find(m.fullTexts, func(f *database.FullTextFetch) bool { return f.PostID == arg.PostID }) != nil {
+		return nil
+	}
+	now := now()
+	m.fullTexts = append(m.fullTexts, database.FullTextFetch{
+		PostID:        arg.PostID,
+		CreatedAt:     now,
+		Url:           arg.Url,
+		NextAttemptAt: now,
+	})
+	return nil
+}
+
+func (m *Memory) ClaimFullTextFetches(ctx context.Context, limit int32) ([]database.FullTextFetch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := now()
+	var due []*database.FullTextFetch
+	for i := range m.fullTexts {
+		f := &m.fullTexts[i]
+		if !f.NextAttemptAt.After(now) {
+			due = append(due, f)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b *database.FullTextFetch) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+
+	var claimed []database.FullTextFetch
+	for _, f := range page(due, limit, 0) {
+		f.Attempts++
+		f.NextAttemptAt = now.Add(5 * time.Minute)
+		claimed = append(claimed, *f)
+	}
+	return claimed, nil
+}
+
+func (m *Memory) DeleteFullTextFetch(ctx context.Context, postID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleteWhere(&m.fullTexts, func(f *database.FullTextFetch) bool { return f.PostID == postID })
+	return nil
+}
+
+func (m *Memory) DeletePosts(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deletePosts(func(*database.Post) bool { return true }), nil
+}
+
+// prunablePosts returns the IDs of the posts past their feed's retention,
+// or the default one in arg where the feed has none. Starred posts are
+// kept.
+func (m *Memory) prunablePosts(arg database.CountPrunablePostsParams) []uuid.UUID {
+	byFeed := map[uuid.UUID][]*database.Post{}
+	for i := range m.posts {
+		post := &m.posts[i]
+		byFeed[post.FeedID] = append(byFeed[post.FeedID], post)
+	}
+
+	now := now()
+	var ids []uuid.UUID
+	for i := range m.feeds {
+		feed := &m.feeds[i]
+		maxAge := cmp.Or(feed.RetentionMaxAgeSeconds, arg.MaxAgeSeconds)
+		maxPosts := cmp.Or(feed.RetentionMaxPosts, arg.MaxPosts)
+
+		posts := byFeed[feed.ID]
+		slices.SortStableFunc(posts, func(a, b *database.Post) int {
+			return cmp.Or(
+				compareNullTimesDescNullsLast(a.PublishedAt, b.PublishedAt),
+				b.CreatedAt.Compare(a.CreatedAt),
+			)
+		})
+		for position, post := range posts {
+			tooOld := maxAge.Valid && postedAt(post).Before(now.Add(-seconds(maxAge.Int32)))
+			tooMany := maxPosts.Valid && position+1 > int(maxPosts.Int32)
+			starred := find(m.stars, func(s *database.PostStar) bool { return s.PostID == post.ID }) != nil
+			if (tooOld || tooMany) && !starred {
+				ids = append(ids, post.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func (m *Memory) CountPrunablePosts(ctx context.Context, arg database.CountPrunablePostsParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return int64(len(m.prunablePosts(arg))), nil
+}
+
+func (m *Memory) PrunePosts(ctx context.Context, arg database.PrunePostsParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := m.prunablePosts(database.CountPrunablePostsParams{
+		MaxAgeSeconds: arg.MaxAgeSeconds,
+		MaxPosts:      arg.MaxPosts,
+	})
+	ids = page(ids, arg.Limit, 0)
+
+	now := now()
+	for _, id := range ids {
+		m.prunedPosts[m.post(id).Url] = now
+	}
+	return m.deletePosts(func(p *database.Post) bool { return slices.Contains(ids, p.ID) }), nil
+}
+
+func (m *Memory) DeletePrunedPosts(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clear(m.prunedPosts)
+	return nil
+}
+
+func (m *Memory) DeletePrunedPostsBefore(ctx context.Context, prunedAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for url, at := range m.prunedPosts {
+		if at.Before(prunedAt) {
+			delete(m.prunedPosts, url)
+		}
+	}
+	return nil
+}
+
+// markRead marks posts read for userID, if they aren't already.
+func (m *Memory) markRead(userID uuid.UUID, posts []*database.Post) {
+	now := now()
+	for _, post := range posts {
+		if !m.isRead(userID, post.ID) {
+			m.reads = append(m.reads, database.PostRead{UserID: userID, PostID: post.ID, CreatedAt: now})
+		}
+	}
+}
+
+// markFollowedRead marks the posts of the feeds userID follows read, when
+// match reports them.
+func (m *Memory) markFollowedRead(userID uuid.UUID, match func(*database.Post, *database.FeedFollow) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var read []*database.Post
+	posts, follows := m.followedPosts(userID)
+	for i, post := range posts {
+		if match(post, follows[i]) {
+			read = append(read, post)
+		}
+	}
+	m.markRead(userID, read)
+}
+
+func (m *Memory) MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.user(arg.UserID) == nil {
+		return foreignKey("post_reads", "user_id")
+	}
+	post := m.post(arg.PostID)
+	if post == nil {
+		return foreignKey("post_reads", "post_id")
+	}
+	m.markRead(arg.UserID, []*database.Post{post})
+	return nil
+}
+
+func (m *Memory) MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleteWhere(&m.reads, func(r *database.PostRead) bool {
+		return r.UserID == arg.UserID && r.PostID == arg.PostID
+	})
+	return nil
+}
+
+func (m *Memory) MarkFeedReadBefore(ctx context.Context, arg database.MarkFeedReadBeforeParams) error {
+	m.markFollowedRead(arg.UserID, func(p *database.Post, _ *database.FeedFollow) bool {
+		return p.FeedID == arg.FeedID && p.CreatedAt.Before(arg.CreatedAt)
+	})
+	return nil
+}
+
+func (m *Memory) MarkTagReadBefore(ctx context.Context, arg database.MarkTagReadBeforeParams) error {
+	m.markFollowedRead(arg.UserID, func(p *database.Post, ff *database.FeedFollow) bool {
+		tagged := find(m.followTags, func(ft *database.FeedFollowTag) bool {
+			tag := find(m.tags, func(t *database.Tag) bool { return t.ID == ft.TagID })
+			return ft.FeedFollowID == ff.ID && tag != nil && tag.ShortID == arg.ShortID
+		}) != nil
+		return tagged && p.CreatedAt.Before(arg.CreatedAt)
+	})
+	return nil
+}
+
+func (m *Memory) MarkAllReadBefore(ctx context.Context, arg database.MarkAllReadBeforeParams) error {
+	m.markFollowedRead(arg.UserID, func(p *database.Post, _ *database.FeedFollow) bool {
+		return p.CreatedAt.Before(arg.CreatedAt)
+	})
+	return nil
+}
+
+func (m *Memory) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.user(arg.UserID) == nil {
+		return foreignKey("post_stars", "user_id")
+	}
+	if m.post(arg.PostID) == nil {
+		return foreignKey("post_stars", "post_id")
+	}
+	if !m.isStarred(arg.UserID, arg.PostID) {
+		m.stars = append(m.stars, database.PostStar{UserID: arg.UserID, PostID: arg.PostID, CreatedAt: now()})
+	}
+	return nil
+}
+
+func (m *Memory) UnstarPost(ctx context.Context, arg database.UnstarPostParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deleteWhere(&m.stars, func(s *database.PostStar) bool {
+		return s.UserID == arg.UserID && s.PostID == arg.PostID
+	})
+	return nil
+}
+
+func (m *Memory) GetStarredPostShortIDs(ctx context.Context, userID uuid.UUID) ([]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ids []int64
+	for _, post := range m.posts {
+		if m.isStarred(userID, post.ID) {
+			ids = append(ids, post.ShortID)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,245 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+func (m *Memory) UpsertTag(ctx context.Context, arg database.UpsertTagParams) (database.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Like a sequence, the short ID is used up even when the tag exists.
+	m.lastTagID++
+	tag := find(m.tags, func(t *database.Tag) bool { return t.UserID == arg.UserID && t.Name == arg.Name })
+	if tag != nil {
+		return *tag, nil
+	}
+	if m.user(arg.UserID) == nil {
+		return database.Tag{}, foreignKey("tags", "user_id")
+	}
+	m.tags = append(m.tags, database.Tag{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		ShortID:   m.lastTagID,
+	})
+	return m.tags[len(m.tags)-1], nil
+}
+
+// tagFeedFollow tags a follow, and reports whether it wasn't already.
+func (m *Memory) tagFeedFollow(followID, tagID uuid.UUID) bool {
+	tagged := find(m.followTags, func(ft *database.FeedFollowTag) bool {
+		return ft.FeedFollowID == followID && ft.TagID == tagID
+	})
+	if tagged != nil {
+		return false
+	}
+	m.followTags = append(m.followTags, database.FeedFollowTag{FeedFollowID: followID, TagID: tagID})
+	return true
+}
+
+func (m *Memory) TagFeedFollow(ctx context.Context, arg database.TagFeedFollowParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ff := m.follow(arg.UserID, arg.FeedID)
+	if ff == nil {
+		return 0, nil
+	}
+	if find(m.tags, func(t *database.Tag) bool { return t.ID == arg.TagID }) == nil {
+		return 0, foreignKey("feed_follow_tags", "tag_id")
+	}
+	if !m.tagFeedFollow(ff.ID, arg.TagID) {
+		return 0, duplicateKey("feed_follow_tags_pkey")
+	}
+	return 1, nil
+}
+
+func (m *Memory) UntagFeedFollow(ctx context.Context, arg database.UntagFeedFollowParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ff := m.follow(arg.UserID, arg.FeedID)
+	if ff == nil {
+		return 0, nil
+	}
+	return deleteWhere(&m.followTags, func(ft *database.FeedFollowTag) bool {
+		tag := find(m.tags, func(t *database.Tag) bool { return t.ID == ft.TagID })
+		return ft.FeedFollowID == ff.ID && tag.Name == arg.Name
+	}), nil
+}
+
+func (m *Memory) DeleteUnusedTags(ctx context.Context, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteTags(func(t *database.Tag) bool {
+		used := find(m.followTags, func(ft *database.FeedFollowTag) bool { return ft.TagID == t.ID })
+		return t.UserID == userID && used == nil
+	})
+	return nil
+}
+
+func (m *Memory) GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]database.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tags []database.Tag
+	for _, tag := range m.tags {
+		if tag.UserID == userID {
+			tags = append(tags, tag)
+		}
+	}
+	slices.SortStableFunc(tags, func(a, b database.Tag) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return tags, nil
+}
+
+func (m *Memory) GetFeedTagsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedTagsForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetFeedTagsForUserRow
+	for _, ft := range m.followTags {
+		tag := find(m.tags, func(t *database.Tag) bool { return t.ID == ft.TagID })
+		if tag.UserID != userID {
+			continue
+		}
+		ff := find(m.follows, func(ff *database.FeedFollow) bool { return ff.ID == ft.FeedFollowID })
+		rows = append(rows, database.GetFeedTagsForUserRow{
+			TagShortID:  tag.ShortID,
+			TagName:     tag.Name,
+			FeedShortID: m.feed(ff.FeedID).ShortID,
+		})
+	}
+	slices.SortStableFunc(rows, func(a, b database.GetFeedTagsForUserRow) int {
+		return cmp.Or(strings.Compare(a.TagName, b.TagName), cmp.Compare(a.FeedShortID, b.FeedShortID))
+	})
+	return rows, nil
+}
+
+func (m *Memory) CreatePostRule(ctx context.Context, arg database.CreatePostRuleParams) (database.PostRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.user(arg.UserID) == nil {
+		return database.PostRule{}, foreignKey("post_rules", "user_id")
+	}
+	if arg.FeedID.Valid && m.feed(arg.FeedID.UUID) == nil {
+		return database.PostRule{}, foreignKey("post_rules", "feed_id")
+	}
+	m.rules = append(m.rules, database.PostRule{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UserID:    arg.UserID,
+		Kind:      arg.Kind,
+		Pattern:   arg.Pattern,
+		FeedID:    arg.FeedID,
+		Action:    arg.Action,
+	})
+	return m.rules[len(m.rules)-1], nil
+}
+
+func (m *Memory) GetPostRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.PostRule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rules []database.PostRule
+	for _, rule := range m.rules {
+		if rule.UserID == userID {
+			rules = append(rules, rule)
+		}
+	}
+	slices.SortStableFunc(rules, func(a, b database.PostRule) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return rules, nil
+}
+
+func (m *Memory) DeletePostRule(ctx context.Context, arg database.DeletePostRuleParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return deleteWhere(&m.rules, func(r *database.PostRule) bool {
+		return r.ID == arg.ID && r.UserID == arg.UserID
+	}), nil
+}
+
+// cloneView returns a copy of a view that doesn't share its arrays.
+func cloneView(view database.View) database.View {
+	view.FeedIds = slices.Clone(view.FeedIds)
+	view.Tags = slices.Clone(view.Tags)
+	return view
+}
+
+func (m *Memory) SaveView(ctx context.Context, arg database.SaveViewParams) (database.View, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := now()
+	view := find(m.views, func(v *database.View) bool { return v.UserID == arg.UserID && v.Name == arg.Name })
+	if view == nil {
+		if m.user(arg.UserID) == nil {
+			return database.View{}, foreignKey("views", "user_id")
+		}
+		m.views = append(m.views, database.View{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UserID:    arg.UserID,
+			Name:      arg.Name,
+		})
+		view = &m.views[len(m.views)-1]
+	}
+	view.UpdatedAt = now
+	view.FeedIds = slices.Clone(arg.FeedIds)
+	view.Tags = slices.Clone(arg.Tags)
+	view.Keyword = arg.Keyword
+	view.MaxAgeSeconds = arg.MaxAgeSeconds
+	view.UnreadOnly = arg.UnreadOnly
+	return cloneView(*view), nil
+}
+
+func (m *Memory) GetViewByName(ctx context.Context, arg database.GetViewByNameParams) (database.View, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	view := find(m.views, func(v *database.View) bool { return v.UserID == arg.UserID && v.Name == arg.Name })
+	if view == nil {
+		return database.View{}, sql.ErrNoRows
+	}
+	return cloneView(*view), nil
+}
+
+func (m *Memory) GetViewsForUser(ctx context.Context, userID uuid.UUID) ([]database.View, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var views []database.View
+	for _, view := range m.views {
+		if view.UserID == userID {
+			views = append(views, cloneView(view))
+		}
+	}
+	slices.SortStableFunc(views, func(a, b database.View) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return views, nil
+}
+
+func (m *Memory) DeleteView(ctx context.Context, arg database.DeleteViewParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return deleteWhere(&m.views, func(v *database.View) bool {
+		return v.UserID == arg.UserID && v.Name == arg.Name
+	}), nil
+}
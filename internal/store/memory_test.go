@@ -0,0 +1,624 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+	"github.com/inscrutabletaco/gator/internal/sqlite"
+	sqliteschema "github.com/inscrutabletaco/gator/sql/sqlite/schema"
+	"github.com/pressly/goose/v3"
+)
+
+var paritySteps = []struct {
+	name string
+	run  func(ctx context.Context, s Store, f *fixture, r *recorder)
+}{
+	{"users", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		f.alice, err = s.CreateUser(ctx, database.CreateUserParams{ID: id(0), CreatedAt: t0, UpdatedAt: t0, Name: "alice"})
+		r.rec("CreateUser", f.alice, err)
+		f.bob, err = s.CreateUser(ctx, database.CreateUserParams{ID: id(1), CreatedAt: t0, UpdatedAt: t0, Name: "bob"})
+		r.rec("CreateUser", f.bob, err)
+		_, err = s.CreateUser(ctx, database.CreateUserParams{ID: id(2), CreatedAt: t0, UpdatedAt: t0, Name: "bob"})
+		r.rec("CreateUser dup", err)
+		f.carol, _ = s.CreateUser(ctx, database.CreateUserParams{ID: id(3), CreatedAt: t0, UpdatedAt: t0, Name: "carol"})
+		r.rec("GetUser", fmt.Sprint(s.GetUser(ctx, "bob")))
+		_, err = s.GetUser(ctx, "nobody")
+		r.rec("GetUser none", err)
+		r.rec("GetUsers", fmt.Sprint(s.GetUsers(ctx)))
+		r.rec("SetUserRole", fmt.Sprint(s.SetUserRole(ctx, database.SetUserRoleParams{Name: "bob", Role: "admin"})))
+		r.rec("SetUserRole none", fmt.Sprint(s.SetUserRole(ctx, database.SetUserRoleParams{Name: "x", Role: "admin"})))
+		r.rec("CountAdmins", fmt.Sprint(s.CountAdmins(ctx)))
+		r.rec("SetUserPassword", s.SetUserPassword(ctx, database.SetUserPasswordParams{ID: f.bob.ID, PasswordHash: ns("h")}))
+		r.rec("SetUserEmail", s.SetUserEmail(ctx, database.SetUserEmailParams{ID: f.bob.ID, Email: ns("b@x")}))
+		r.rec("SetUserEmail", s.SetUserEmail(ctx, database.SetUserEmailParams{ID: f.alice.ID, Email: ns("a@x")}))
+		r.rec("SetFeverApiKey", s.SetFeverApiKey(ctx, database.SetFeverApiKeyParams{ID: f.bob.ID, FeverApiKey: ns("k")}))
+		r.rec("SetFeverApiKey dup", s.SetFeverApiKey(ctx, database.SetFeverApiKeyParams{ID: f.alice.ID, FeverApiKey: ns("k")}))
+		r.rec("GetUserByFeverApiKey", fmt.Sprint(s.GetUserByFeverApiKey(ctx, ns("k"))))
+		_, err = s.GetUserByFeverApiKey(ctx, sql.NullString{})
+		r.rec("GetUserByFeverApiKey null", err)
+		r.rec("GetDigestRecipients", fmt.Sprint(s.GetDigestRecipients(ctx)))
+		r.rec("SetLastDigestAt", s.SetLastDigestAt(ctx, database.SetLastDigestAtParams{ID: f.alice.ID, LastDigestAt: nt(t0)}))
+		r.rec("RenameUser", fmt.Sprint(s.RenameUser(ctx, database.RenameUserParams{ID: f.bob.ID, Name: "robert"})))
+		_, err = s.RenameUser(ctx, database.RenameUserParams{ID: f.bob.ID, Name: "alice"})
+		r.rec("RenameUser dup", err)
+		r.rec("RenameUser same", fmt.Sprint(s.RenameUser(ctx, database.RenameUserParams{ID: f.bob.ID, Name: "robert"})))
+		u, _ := s.GetUser(ctx, "robert")
+		r.rec("after rename", u.FeverApiKey, u.Name)
+	}},
+	{"API tokens", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		tok, err := s.CreateApiToken(ctx, database.CreateApiTokenParams{ID: id(4), CreatedAt: t0, UserID: f.alice.ID, Name: "laptop", TokenHash: "h1"})
+		r.rec("CreateApiToken", tok, err)
+		tok, err = s.CreateApiToken(ctx, database.CreateApiTokenParams{ID: id(5), CreatedAt: t0.Add(time.Hour), UserID: f.alice.ID, Name: "laptop", TokenHash: "h2"})
+		r.rec("CreateApiToken upsert", tok, err)
+		_, err = s.CreateApiToken(ctx, database.CreateApiTokenParams{ID: id(6), CreatedAt: t0, UserID: f.bob.ID, Name: "x", TokenHash: "h2"})
+		r.rec("CreateApiToken dup", err)
+		s.CreateApiToken(ctx, database.CreateApiTokenParams{ID: id(7), CreatedAt: t0.Add(-time.Hour), UserID: f.alice.ID, Name: "phone", TokenHash: "h3"})
+		r.rec("TouchApiToken", s.TouchApiToken(ctx, "h3"))
+		toks, err := s.GetApiTokensForUser(ctx, f.alice.ID)
+		for _, t := range toks {
+			r.rec("tok", t.Name, t.LastUsedAt.Valid, t.CreatedAt)
+		}
+		r.rec("GetUserByApiToken", fmt.Sprint(s.GetUserByApiToken(ctx, "h2")))
+		_, err = s.GetUserByApiToken(ctx, "nope")
+		r.rec("GetUserByApiToken none", err)
+		r.rec("DeleteApiToken", fmt.Sprint(s.DeleteApiToken(ctx, database.DeleteApiTokenParams{UserID: f.alice.ID, Name: "phone"})))
+	}},
+	{"feeds", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		for i, name := range []string{"Zeta", "Alpha", "Mid", "Bobs"} {
+			owner := f.alice.ID
+			if i == 3 {
+				owner = f.bob.ID
+			}
+			feed, err := s.CreateFeed(ctx, database.CreateFeedParams{ID: id(8 + i), CreatedAt: t0.Add(time.Duration(i) * time.Minute), UpdatedAt: t0, Name: name, Url: "http://f/" + name, UserID: owner})
+			r.rec("CreateFeed", feed, err)
+			f.feeds = append(f.feeds, feed)
+		}
+		_, err = s.CreateFeed(ctx, database.CreateFeedParams{ID: id(12), CreatedAt: t0, UpdatedAt: t0, Name: "dup", Url: "http://f/Zeta", UserID: f.alice.ID})
+		r.rec("CreateFeed dup", err)
+		_, err = s.CreateFeed(ctx, database.CreateFeedParams{ID: id(12), CreatedAt: t0, UpdatedAt: t0, Name: "fk", Url: "http://f/fk", UserID: id(19)})
+		r.rec("CreateFeed fk", err != nil)
+		r.rec("GetFeed", fmt.Sprint(s.GetFeed(ctx, "Mid")))
+		r.rec("GetFeedByID", fmt.Sprint(s.GetFeedByID(ctx, f.feeds[1].ID)))
+		r.rec("GetFeedByUrl", fmt.Sprint(s.GetFeedByUrl(ctx, "http://f/Alpha")))
+		r.rec("GetFeedByUrls", fmt.Sprint(s.GetFeedByUrls(ctx, []string{"http://none", "http://f/Mid", "http://f/Alpha"})))
+		_, err = s.GetFeedByUrls(ctx, []string{"x"})
+		r.rec("GetFeedByUrls none", err)
+		r.rec("GetFeeds", fmt.Sprint(s.GetFeeds(ctx)))
+		r.rec("GetUserFeeds", fmt.Sprint(s.GetUserFeeds(ctx, f.bob.ID)))
+		r.rec("GetFeedsByUser", fmt.Sprint(s.GetFeedsByUser(ctx)))
+		r.rec("GetNextFeedToFetch", fmt.Sprint(s.GetNextFeedToFetch(ctx)))
+		lag, err := s.GetFeedQueueLag(ctx)
+		r.rec("GetFeedQueueLag", lag > 1000, err)
+		r.rec("MarkFeedFetched", s.MarkFeedFetched(ctx, f.feeds[0].ID))
+		next, _ := s.GetNextFeedToFetch(ctx)
+		r.rec("next", next.Name)
+		for _, feed := range f.feeds[1:] {
+			s.MarkFeedFetched(ctx, feed.ID)
+			step()
+		}
+		next, _ = s.GetNextFeedToFetch(ctx)
+		r.rec("next all fetched", next.Name)
+		upd, err := s.UpdateFeed(ctx, database.UpdateFeedParams{ID: f.feeds[1].ID, Name: "Alpha2", Url: "http://f/Alpha", FetchIntervalSeconds: ni(1), FetchFullText: true})
+		r.rec("UpdateFeed", upd.Name, upd.FetchIntervalSeconds, upd.FetchFullText, err)
+		_, err = s.UpdateFeed(ctx, database.UpdateFeedParams{ID: f.feeds[1].ID, Name: "Alpha2", Url: "http://f/Mid"})
+		r.rec("UpdateFeed dup", err)
+		_, err = s.UpdateFeed(ctx, database.UpdateFeedParams{ID: id(19), Name: "x", Url: "x"})
+		r.rec("UpdateFeed none", err)
+		time.Sleep(1100 * time.Millisecond)
+		next, _ = s.GetNextFeedToFetch(ctx)
+		r.rec("next interval", next.Name)
+		r.rec("UpdateFeedMetadata", s.UpdateFeedMetadata(ctx, database.UpdateFeedMetadataParams{ID: f.feeds[2].ID, Title: ns("T"), Language: ns("en")}))
+		r.rec("SetFeedRetention", s.SetFeedRetention(ctx, database.SetFeedRetentionParams{ID: f.feeds[2].ID, RetentionMaxPosts: ni(2)}))
+		r.rec("RecordFeedSuccess", s.RecordFeedSuccess(ctx, database.RecordFeedSuccessParams{ID: f.feeds[3].ID, LastStatusCode: ni(200)}))
+		for i := 0; i < 3; i++ {
+			ff, err := s.RecordFeedFailure(ctx, database.RecordFeedFailureParams{ID: f.feeds[3].ID, LastStatusCode: ni(500), LastError: ns("boom"), MaxFailures: 3})
+			r.rec("RecordFeedFailure", ff.ConsecutiveFailures, ff.DisabledAt.Valid, ff.LastError, err)
+		}
+		ff, err := s.RecordFeedFailure(ctx, database.RecordFeedFailureParams{ID: f.feeds[0].ID, LastError: ns("x"), MaxFailures: 0})
+		r.rec("RecordFeedFailure 0", ff.ConsecutiveFailures, ff.DisabledAt.Valid, err)
+		hs, _ := s.GetFeedsHealth(ctx)
+		for _, h := range hs {
+			r.rec("health", h.Name, h.ConsecutiveFailures, h.DisabledAt.Valid)
+		}
+		r.rec("EnableFeed", fmt.Sprint(s.EnableFeed(ctx, "http://f/Bobs")))
+		r.rec("EnableFeed none", fmt.Sprint(s.EnableFeed(ctx, "http://none")))
+		for i, u := range []sql.NullString{ns("http://r/1"), ns("http://r/1"), ns("http://r/2"), {}} {
+			ff, err := s.RecordFeedRedirect(ctx, database.RecordFeedRedirectParams{ID: f.feeds[2].ID, RedirectUrl: u})
+			r.rec("RecordFeedRedirect", i, ff.RedirectCount, ff.RedirectUrl, err)
+		}
+		s.RecordFeedRedirect(ctx, database.RecordFeedRedirectParams{ID: f.feeds[2].ID, RedirectUrl: ns("http://r/3")})
+		r.rec("ClearFeedRedirect", s.ClearFeedRedirect(ctx, f.feeds[0].ID))
+		r.rec("SetFeedUrl", s.SetFeedUrl(ctx, database.SetFeedUrlParams{ID: f.feeds[2].ID, Url: "http://f/Mid2"}))
+		r.rec("SetFeedUrl dup", s.SetFeedUrl(ctx, database.SetFeedUrlParams{ID: f.feeds[2].ID, Url: "http://f/Zeta"}))
+		r.rec("GetFeeds2", fmt.Sprint(s.GetFeeds(ctx)))
+		upd, err = s.UpdateFeed(ctx, database.UpdateFeedParams{ID: f.feeds[3].ID, Name: "Bobs", Url: "http://f/Bobs", DisabledAt: nt(t0)})
+		r.rec("UpdateFeed disable", upd.DisabledAt, upd.ConsecutiveFailures, err)
+	}},
+	{"follows", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		for _, fol := range []struct{ u, f int }{{0, 0}, {0, 1}, {0, 2}, {1, 0}, {1, 3}, {2, 2}} {
+			user := []uuid.UUID{f.alice.ID, f.bob.ID, f.carol.ID}[fol.u]
+			row, err := s.CreateFeedFollow(ctx, database.CreateFeedFollowParams{UserID: user, FeedID: f.feeds[fol.f].ID})
+			r.rec("CreateFeedFollow", row.FeedName, row.UserName, row.DisplayName, err)
+		}
+		_, err = s.CreateFeedFollow(ctx, database.CreateFeedFollowParams{UserID: f.alice.ID, FeedID: f.feeds[0].ID})
+		r.rec("CreateFeedFollow dup", err)
+		r.rec("SetFeedFollowDisplayName", fmt.Sprint(s.SetFeedFollowDisplayName(ctx, database.SetFeedFollowDisplayNameParams{UserID: f.alice.ID, FeedID: f.feeds[0].ID, DisplayName: ns("AAA")})))
+		r.rec("SetFeedFollowDisplayName none", fmt.Sprint(s.SetFeedFollowDisplayName(ctx, database.SetFeedFollowDisplayNameParams{UserID: f.carol.ID, FeedID: f.feeds[0].ID, DisplayName: ns("x")})))
+		r.rec("CountFeedFollowers", fmt.Sprint(s.CountFeedFollowers(ctx, f.feeds[0].ID)))
+		fols, _ := s.GetFollowedFeeds(ctx, f.alice.ID)
+		for _, fo := range fols {
+			r.rec("followed", fo.Name, fo.DisplayName, fo.ShortID, fo.Url)
+		}
+	}},
+	{"tags", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		f.tagA, err = s.UpsertTag(ctx, database.UpsertTagParams{UserID: f.alice.ID, Name: "news"})
+		r.rec("UpsertTag", f.tagA.Name, f.tagA.ShortID, err)
+		tagA2, err := s.UpsertTag(ctx, database.UpsertTagParams{UserID: f.alice.ID, Name: "news"})
+		r.rec("UpsertTag again", tagA2.ID == f.tagA.ID, err)
+		tagB, _ := s.UpsertTag(ctx, database.UpsertTagParams{UserID: f.alice.ID, Name: "art"})
+		tagC, _ := s.UpsertTag(ctx, database.UpsertTagParams{UserID: f.bob.ID, Name: "news"})
+		r.rec("TagFeedFollow", fmt.Sprint(s.TagFeedFollow(ctx, database.TagFeedFollowParams{TagID: f.tagA.ID, UserID: f.alice.ID, FeedID: f.feeds[0].ID})))
+		r.rec("TagFeedFollow", fmt.Sprint(s.TagFeedFollow(ctx, database.TagFeedFollowParams{TagID: tagB.ID, UserID: f.alice.ID, FeedID: f.feeds[0].ID})))
+		r.rec("TagFeedFollow", fmt.Sprint(s.TagFeedFollow(ctx, database.TagFeedFollowParams{TagID: f.tagA.ID, UserID: f.alice.ID, FeedID: f.feeds[2].ID})))
+		r.rec("TagFeedFollow f.bob", fmt.Sprint(s.TagFeedFollow(ctx, database.TagFeedFollowParams{TagID: tagC.ID, UserID: f.bob.ID, FeedID: f.feeds[0].ID})))
+		_, err = s.TagFeedFollow(ctx, database.TagFeedFollowParams{TagID: f.tagA.ID, UserID: f.alice.ID, FeedID: f.feeds[0].ID})
+		r.rec("TagFeedFollow dup", err)
+		r.rec("TagFeedFollow nofollow", fmt.Sprint(s.TagFeedFollow(ctx, database.TagFeedFollowParams{TagID: f.tagA.ID, UserID: f.alice.ID, FeedID: f.feeds[3].ID})))
+		r.rec("GetFeedFollowsForUser", fmt.Sprint(s.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{UserID: f.alice.ID})))
+		r.rec("GetFeedFollowsForUser tag", fmt.Sprint(s.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{UserID: f.alice.ID, Tag: ns("art")})))
+		tags, _ := s.GetTagsForUser(ctx, f.alice.ID)
+		for _, t := range tags {
+			r.rec("tag", t.Name, t.ShortID)
+		}
+		r.rec("GetFeedTagsForUser", fmt.Sprint(s.GetFeedTagsForUser(ctx, f.alice.ID)))
+		r.rec("UntagFeedFollow", fmt.Sprint(s.UntagFeedFollow(ctx, database.UntagFeedFollowParams{UserID: f.alice.ID, FeedID: f.feeds[0].ID, Name: "art"})))
+		r.rec("UntagFeedFollow none", fmt.Sprint(s.UntagFeedFollow(ctx, database.UntagFeedFollowParams{UserID: f.alice.ID, FeedID: f.feeds[0].ID, Name: "art"})))
+		r.rec("DeleteUnusedTags", s.DeleteUnusedTags(ctx, f.alice.ID))
+		tags, _ = s.GetTagsForUser(ctx, f.alice.ID)
+		r.rec("tags after", len(tags))
+	}},
+	{"posts", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		pub := []sql.NullTime{nt(t0.Add(time.Hour)), {}, nt(t0.Add(3 * time.Hour)), nt(t0.Add(2 * time.Hour)), {}, nt(time.Now().Add(-time.Minute))}
+		for i := 0; i < 6; i++ {
+			feed := f.feeds[i%3]
+			p, err := s.CreatePost(ctx, database.CreatePostParams{Title: fmt.Sprintf("Post %d golang", i), Url: fmt.Sprintf("http://p/%d", i), Description: ns(fmt.Sprintf("about RUST %d", i)), PublishedAt: pub[i], FeedID: feed.ID, Author: ns("me")})
+			r.rec("CreatePost", p.Title, p.ShortID, p.PublishedAt, p.Content, err)
+			f.posts = append(f.posts, p)
+			step()
+		}
+		p, err := s.CreatePost(ctx, database.CreatePostParams{Title: "bobs", Url: "http://p/f.bob", FeedID: f.feeds[3].ID})
+		r.rec("CreatePost", p.ShortID, err)
+		f.posts = append(f.posts, p)
+		_, err = s.CreatePost(ctx, database.CreatePostParams{Title: "dup", Url: "http://p/0", FeedID: f.feeds[0].ID})
+		r.rec("CreatePost dup", err)
+		r.rec("GetPostByShortID", fmt.Sprint(s.GetPostByShortID(ctx, database.GetPostByShortIDParams{UserID: f.alice.ID, ShortID: f.posts[2].ShortID})))
+		_, err = s.GetPostByShortID(ctx, database.GetPostByShortIDParams{UserID: f.alice.ID, ShortID: 999})
+		r.rec("GetPostByShortID none", err)
+		_, err = s.GetPostByShortID(ctx, database.GetPostByShortIDParams{UserID: f.carol.ID, ShortID: f.posts[2].ShortID})
+		r.rec("GetPostByShortID not followed", err)
+		r.rec("SetPostContent", s.SetPostContent(ctx, database.SetPostContentParams{ID: f.posts[0].ID, Content: ns("body")}))
+		step()
+		rows, err := s.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: f.alice.ID, Limit: 10})
+		for _, row := range rows {
+			r.rec("GetPostsForUser", row.Title, row.FeedName, row.Content)
+		}
+		rows, err = s.GetPostsForUser(ctx, database.GetPostsForUserParams{UserID: f.alice.ID, Limit: 2, Offset: 1, Tag: ns("news")})
+		for _, row := range rows {
+			r.rec("GetPostsForUser tag page", row.Title, row.FeedName)
+		}
+		r.rec("CountPostsForUser", fmt.Sprint(s.CountPostsForUser(ctx, f.alice.ID)))
+		r.rec("CountPosts", fmt.Sprint(s.CountPosts(ctx)))
+	}},
+	{"read and starred posts", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		r.rec("MarkPostRead", s.MarkPostRead(ctx, database.MarkPostReadParams{UserID: f.alice.ID, PostID: f.posts[0].ID}))
+		r.rec("MarkPostRead again", s.MarkPostRead(ctx, database.MarkPostReadParams{UserID: f.alice.ID, PostID: f.posts[0].ID}))
+		r.rec("MarkPostRead fk", s.MarkPostRead(ctx, database.MarkPostReadParams{UserID: f.alice.ID, PostID: id(19)}) != nil)
+		r.rec("StarPost", s.StarPost(ctx, database.StarPostParams{UserID: f.alice.ID, PostID: f.posts[1].ID}))
+		r.rec("StarPost", s.StarPost(ctx, database.StarPostParams{UserID: f.alice.ID, PostID: f.posts[1].ID}))
+		r.rec("StarPost f.bob", s.StarPost(ctx, database.StarPostParams{UserID: f.bob.ID, PostID: f.posts[5].ID}))
+		r.rec("GetStarredPostShortIDs", fmt.Sprint(s.GetStarredPostShortIDs(ctx, f.alice.ID)))
+		items, _ := s.GetPostItemsSince(ctx, database.GetPostItemsSinceParams{UserID: f.alice.ID, ShortID: f.posts[0].ShortID, Limit: 3})
+		for _, it := range items {
+			r.rec("since", it.Title, it.FeedShortID, it.IsRead, it.IsStarred)
+		}
+		itemsB, _ := s.GetPostItemsBefore(ctx, database.GetPostItemsBeforeParams{UserID: f.alice.ID, ShortID: f.posts[4].ShortID, Limit: 3})
+		for _, it := range itemsB {
+			r.rec("before", it.Title, it.FeedShortID, it.IsRead, it.IsStarred)
+		}
+		itemsC, _ := s.GetPostItemsByShortIDs(ctx, database.GetPostItemsByShortIDsParams{UserID: f.alice.ID, ShortIds: []int64{f.posts[5].ShortID, f.posts[0].ShortID, f.posts[6].ShortID}})
+		for _, it := range itemsC {
+			r.rec("byids", it.Title, it.IsRead)
+		}
+		unread, _ := s.GetUnreadPostsForUser(ctx, f.alice.ID)
+		for _, p := range unread {
+			r.rec("unread", p.Title)
+		}
+		r.rec("GetLastPostReadAt", fmt.Sprint(func() (bool, error) { t, err := s.GetLastPostReadAt(ctx, f.alice.ID); return !t.IsZero(), err }()))
+		_, err = s.GetLastPostReadAt(ctx, f.carol.ID)
+		r.rec("GetLastPostReadAt none", err)
+		r.rec("MarkPostUnread", s.MarkPostUnread(ctx, database.MarkPostUnreadParams{UserID: f.alice.ID, PostID: f.posts[0].ID}))
+		r.rec("MarkFeedReadBefore", s.MarkFeedReadBefore(ctx, database.MarkFeedReadBeforeParams{UserID: f.alice.ID, FeedID: f.feeds[1].ID, CreatedAt: time.Now().Add(time.Hour)}))
+		r.rec("MarkTagReadBefore", s.MarkTagReadBefore(ctx, database.MarkTagReadBeforeParams{UserID: f.alice.ID, ShortID: f.tagA.ShortID, CreatedAt: f.posts[5].CreatedAt}))
+		unread, _ = s.GetUnreadPostsForUser(ctx, f.alice.ID)
+		for _, p := range unread {
+			r.rec("unread2", p.Title, p.ShortID, p.Url, p.Description, p.Author)
+		}
+		r.rec("GetUnreadPostShortIDs", fmt.Sprint(s.GetUnreadPostShortIDs(ctx, f.alice.ID)))
+		r.rec("MarkAllReadBefore", s.MarkAllReadBefore(ctx, database.MarkAllReadBeforeParams{UserID: f.bob.ID, CreatedAt: time.Now().Add(time.Hour)}))
+		r.rec("GetUserStats", fmt.Sprint(s.GetUserStats(ctx, f.alice.ID)))
+		r.rec("GetUserStats f.bob", fmt.Sprint(s.GetUserStats(ctx, f.bob.ID)))
+		r.rec("UnstarPost", s.UnstarPost(ctx, database.UnstarPostParams{UserID: f.bob.ID, PostID: f.posts[5].ID}))
+
+		digest, _ := s.GetDigestPostsForUser(ctx, f.bob.ID)
+		for _, d := range digest {
+			r.rec("digest f.bob", d.Title, d.FeedName)
+		}
+		digest, _ = s.GetDigestPostsForUser(ctx, f.alice.ID)
+		for _, d := range digest {
+			r.rec("digest f.alice", d.Title, d.FeedName)
+		}
+	}},
+	{"post rules", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		rule, err := s.CreatePostRule(ctx, database.CreatePostRuleParams{UserID: f.alice.ID, Kind: "keyword", Pattern: "go", Action: "mute"})
+		r.rec("CreatePostRule", rule.Kind, rule.Pattern, rule.FeedID, err)
+		step()
+		s.CreatePostRule(ctx, database.CreatePostRuleParams{UserID: f.alice.ID, Kind: "feed", Pattern: "x", FeedID: uuid.NullUUID{UUID: f.feeds[1].ID, Valid: true}, Action: "highlight"})
+		rules, _ := s.GetPostRulesForUser(ctx, f.alice.ID)
+		for _, ru := range rules {
+			r.rec("rule", ru.Kind, ru.FeedID)
+		}
+		r.rec("DeletePostRule", fmt.Sprint(s.DeletePostRule(ctx, database.DeletePostRuleParams{ID: rule.ID, UserID: f.bob.ID})))
+		r.rec("DeletePostRule", fmt.Sprint(s.DeletePostRule(ctx, database.DeletePostRuleParams{ID: rule.ID, UserID: f.alice.ID})))
+	}},
+	{"views", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		view, err := s.SaveView(ctx, database.SaveViewParams{UserID: f.alice.ID, Name: "v1", Tags: []string{"news"}, Keyword: ns("GOLANG"), UnreadOnly: false})
+		r.rec("SaveView", view.Name, view.FeedIds, view.Tags, view.Keyword, err)
+		vrows, _ := s.GetPostsForView(ctx, database.GetPostsForViewParams{ViewID: view.ID, Limit: 10})
+		for _, row := range vrows {
+			r.rec("view v1", row.Title, row.FeedName)
+		}
+		view2, err := s.SaveView(ctx, database.SaveViewParams{UserID: f.alice.ID, Name: "v1", FeedIds: []uuid.UUID{f.feeds[1].ID, f.feeds[0].ID}, UnreadOnly: true})
+		r.rec("SaveView upsert", view2.ID == view.ID, view2.Tags, view2.UnreadOnly, err)
+		vrows, _ = s.GetPostsForView(ctx, database.GetPostsForViewParams{ViewID: view.ID, Limit: 10})
+		for _, row := range vrows {
+			r.rec("view v1b", row.Title, row.FeedName)
+		}
+		view3, _ := s.SaveView(ctx, database.SaveViewParams{UserID: f.alice.ID, Name: "a-recent", MaxAgeSeconds: ni(3600)})
+		vrows, _ = s.GetPostsForView(ctx, database.GetPostsForViewParams{ViewID: view3.ID, Limit: 10})
+		for _, row := range vrows {
+			r.rec("view recent", row.Title)
+		}
+		view4, _ := s.SaveView(ctx, database.SaveViewParams{UserID: f.alice.ID, Name: "empty", Tags: []string{}})
+		vrows, _ = s.GetPostsForView(ctx, database.GetPostsForViewParams{ViewID: view4.ID, Limit: 10})
+		r.rec("view empty", len(vrows))
+		view5, _ := s.SaveView(ctx, database.SaveViewParams{UserID: f.alice.ID, Name: "wildcard", Keyword: ns("%_")})
+		vrows, _ = s.GetPostsForView(ctx, database.GetPostsForViewParams{ViewID: view5.ID, Limit: 10})
+		r.rec("view wildcard", len(vrows))
+		vs, _ := s.GetViewsForUser(ctx, f.alice.ID)
+		for _, v := range vs {
+			r.rec("views", v.Name, v.FeedIds, v.Tags)
+		}
+		gv, err := s.GetViewByName(ctx, database.GetViewByNameParams{UserID: f.alice.ID, Name: "v1"})
+		r.rec("GetViewByName", gv.Name, gv.FeedIds, err)
+		_, err = s.GetViewByName(ctx, database.GetViewByNameParams{UserID: f.alice.ID, Name: "nope"})
+		r.rec("GetViewByName none", err)
+		r.rec("DeleteView", fmt.Sprint(s.DeleteView(ctx, database.DeleteViewParams{UserID: f.alice.ID, Name: "empty"})))
+	}},
+	{"webhooks", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		wh, err := s.CreateWebhook(ctx, database.CreateWebhookParams{ID: id(13), CreatedAt: t0, UpdatedAt: t0, UserID: f.alice.ID, Url: "http://hook/1", Keyword: ns("RUST")})
+		r.rec("CreateWebhook", wh, err)
+		wh2, err := s.CreateWebhook(ctx, database.CreateWebhookParams{ID: id(14), CreatedAt: t0.Add(time.Second), UpdatedAt: t0, UserID: f.alice.ID, Url: "http://hook/2", FeedID: uuid.NullUUID{UUID: f.feeds[0].ID, Valid: true}, Secret: ns("s")})
+		r.rec("CreateWebhook", wh2, err)
+		r.rec("GetWebhooksForUser", fmt.Sprint(s.GetWebhooksForUser(ctx, f.alice.ID)))
+		for _, p := range f.posts {
+			n, err := s.EnqueueWebhookDeliveries(ctx, p.ID)
+			r.rec("Enqueue", p.Title, n, err)
+		}
+		n, _ := s.EnqueueWebhookDeliveries(ctx, f.posts[0].ID)
+		r.rec("Enqueue again", n)
+		claimed, err := s.ClaimWebhookDeliveries(ctx, 3)
+		r.rec("Claim", len(claimed), err)
+		for _, d := range claimed {
+			pl, err := s.GetWebhookDeliveryPayload(ctx, d.ID)
+			r.rec("payload", d.Attempts, d.Status, pl, err)
+		}
+		step()
+		r.rec("Succeeded", s.MarkWebhookDeliverySucceeded(ctx, database.MarkWebhookDeliverySucceededParams{ID: claimed[0].ID, LastStatusCode: ni(200)}))
+		step()
+		r.rec("Failed", s.MarkWebhookDeliveryFailed(ctx, database.MarkWebhookDeliveryFailedParams{ID: claimed[1].ID, Status: "pending", RetrySeconds: 0, LastStatusCode: ni(500), LastError: ns("x")}))
+		step()
+		claimed2, _ := s.ClaimWebhookDeliveries(ctx, 10)
+		r.rec("Claim2", len(claimed2))
+		// Claimed deliveries come back in no particular order.
+		slices.SortFunc(claimed2, func(a, b database.WebhookDelivery) int {
+			return cmp.Compare(b.Attempts, a.Attempts)
+		})
+		for _, d := range claimed2 {
+			r.rec("claim2", d.Attempts, d.LastError)
+		}
+		_, err = s.GetWebhookDeliveryPayload(ctx, id(19))
+		r.rec("payload none", err)
+		dels, _ := s.GetWebhookDeliveriesForUser(ctx, database.GetWebhookDeliveriesForUserParams{UserID: f.alice.ID, Limit: 10})
+		// Deliveries claimed together tie on updated_at.
+		slices.SortStableFunc(dels, func(a, b database.GetWebhookDeliveriesForUserRow) int {
+			return cmp.Or(b.UpdatedAt.Compare(a.UpdatedAt), cmp.Compare(a.PostTitle, b.PostTitle), cmp.Compare(a.WebhookUrl, b.WebhookUrl))
+		})
+		for _, d := range dels {
+			r.rec("deliveries", d.Status, d.WebhookUrl, d.PostTitle, d.Attempts)
+		}
+	}},
+	{"full text", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		for _, p := range f.posts[:3] {
+			r.rec("EnqueueFullTextFetch", s.EnqueueFullTextFetch(ctx, database.EnqueueFullTextFetchParams{PostID: p.ID, Url: p.Url}))
+			step()
+		}
+		r.rec("Enqueue again", s.EnqueueFullTextFetch(ctx, database.EnqueueFullTextFetchParams{PostID: f.posts[0].ID, Url: "http://p/other"}))
+		err := s.EnqueueFullTextFetch(ctx, database.EnqueueFullTextFetchParams{PostID: id(19), Url: "http://p/none"})
+		r.rec("Enqueue no post", err != nil)
+		claimed, err := s.ClaimFullTextFetches(ctx, 2)
+		r.rec("Claim", len(claimed), err)
+		for _, c := range claimed {
+			r.rec("claimed", c.Url, c.Attempts)
+		}
+		r.rec("DeleteFullTextFetch", s.DeleteFullTextFetch(ctx, claimed[0].PostID))
+		claimed, err = s.ClaimFullTextFetches(ctx, 10)
+		r.rec("Claim2", len(claimed), err)
+		for _, c := range claimed {
+			r.rec("claimed2", c.Url, c.Attempts)
+		}
+		claimed, err = s.ClaimFullTextFetches(ctx, 10)
+		r.rec("Claim3", len(claimed), err)
+	}},
+	{"WebSub", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		sub, err := s.UpsertWebsubSubscription(ctx, database.UpsertWebsubSubscriptionParams{FeedID: f.feeds[1].ID, HubUrl: "h", TopicUrl: "t", Secret: "s"})
+		r.rec("Upsert sub", sub.State, sub.HubUrl, err)
+		r.rec("Verify", s.VerifyWebsubSubscription(ctx, database.VerifyWebsubSubscriptionParams{FeedID: f.feeds[1].ID, LeaseExpiresAt: nt(time.Now().Add(time.Hour))}))
+		g, err := s.GetWebsubSubscription(ctx, f.feeds[1].ID)
+		r.rec("Get sub", g.State, g.LeaseExpiresAt.Valid, err)
+		for _, feed := range f.feeds {
+			s.MarkFeedFetched(ctx, feed.ID)
+		}
+		_, err = s.GetNextFeedToFetch(ctx)
+		r.rec("next none", err)
+		lag, err := s.GetFeedQueueLag(ctx)
+		r.rec("lag", lag < 5, err)
+		sub, err = s.UpsertWebsubSubscription(ctx, database.UpsertWebsubSubscriptionParams{FeedID: f.feeds[1].ID, HubUrl: "h2", TopicUrl: "t", Secret: "s"})
+		r.rec("Upsert sub again", sub.State, sub.HubUrl, sub.LeaseExpiresAt.Valid, err)
+		r.rec("Deny", s.DenyWebsubSubscription(ctx, f.feeds[1].ID))
+		g, err = s.GetWebsubSubscription(ctx, f.feeds[1].ID)
+		r.rec("Get sub denied", g.State, g.LeaseExpiresAt.Valid, err)
+		_, err = s.GetWebsubSubscription(ctx, f.feeds[0].ID)
+		r.rec("Get sub none", err)
+	}},
+	{"prune", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		var err error
+		for _, ma := range []sql.NullInt32{{}, ni(3600), ni(1)} {
+			for _, mp := range []sql.NullInt32{{}, ni(1), ni(0)} {
+				c, err := s.CountPrunablePosts(ctx, database.CountPrunablePostsParams{MaxAgeSeconds: ma, MaxPosts: mp})
+				r.rec("CountPrunable", ma, mp, c, err)
+			}
+		}
+		pr, err := s.PrunePosts(ctx, database.PrunePostsParams{MaxPosts: ni(1), Limit: 100})
+		r.rec("PrunePosts", pr, err)
+		_, err = s.CreatePost(ctx, database.CreatePostParams{Title: "again", Url: f.posts[0].Url, FeedID: f.feeds[0].ID})
+		r.rec("CreatePost pruned", err)
+		r.rec("CountPosts", fmt.Sprint(s.CountPosts(ctx)))
+		r.rec("DeletePrunedPostsBefore", s.DeletePrunedPostsBefore(ctx, t0))
+		_, err = s.CreatePost(ctx, database.CreatePostParams{Title: "again", Url: f.posts[0].Url, FeedID: f.feeds[0].ID})
+		r.rec("CreatePost pruned2", err)
+		r.rec("DeletePrunedPostsBefore", s.DeletePrunedPostsBefore(ctx, time.Now().Add(time.Minute)))
+		_, err = s.CreatePost(ctx, database.CreatePostParams{Title: "again", Url: f.posts[0].Url, FeedID: f.feeds[0].ID})
+		r.rec("CreatePost after", err)
+		r.rec("DeletePrunedPosts", s.DeletePrunedPosts(ctx))
+	}},
+	{"merge and delete", func(ctx context.Context, s Store, f *fixture, r *recorder) {
+		mf, mt := f.feeds[0].ID, f.feeds[1].ID
+		r.rec("MergeFeedFollows", s.MergeFeedFollows(ctx, database.MergeFeedFollowsParams{ToFeedID: mt, FromFeedID: mf}))
+		r.rec("MergeFeedFollowTags", s.MergeFeedFollowTags(ctx, database.MergeFeedFollowTagsParams{ToFeedID: mt, FromFeedID: mf}))
+		r.rec("MergeFeedPosts", s.MergeFeedPosts(ctx, database.MergeFeedPostsParams{ToFeedID: mt, FromFeedID: mf}))
+		r.rec("MergeFeedWebhooks", s.MergeFeedWebhooks(ctx, database.MergeFeedWebhooksParams{ToFeedID: mt, FromFeedID: mf}))
+		r.rec("MergeFeedPostRules", s.MergeFeedPostRules(ctx, database.MergeFeedPostRulesParams{ToFeedID: mt, FromFeedID: mf}))
+		r.rec("MergeFeedViews", s.MergeFeedViews(ctx, database.MergeFeedViewsParams{ToFeedID: mt, FromFeedID: mf}))
+		r.rec("DeleteFeedByID", s.DeleteFeedByID(ctx, mf))
+		r.rec("follows f.alice", fmt.Sprint(s.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{UserID: f.alice.ID})))
+		r.rec("follows f.bob", fmt.Sprint(s.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{UserID: f.bob.ID})))
+		gv, _ := s.GetViewByName(ctx, database.GetViewByNameParams{UserID: f.alice.ID, Name: "v1"})
+		r.rec("view merged", gv.FeedIds)
+		r.rec("webhooks merged", fmt.Sprint(s.GetWebhooksForUser(ctx, f.alice.ID)))
+		rules, _ := s.GetPostRulesForUser(ctx, f.alice.ID)
+		r.rec("rules", len(rules))
+		r.rec("CountUserData", fmt.Sprint(s.CountUserData(ctx, f.alice.ID)))
+		n, err := s.TransferFeedsAndDeleteUser(ctx, database.TransferFeedsAndDeleteUserParams{ToUserID: id(19), ID: f.bob.ID})
+		r.rec("TransferFeedsAndDeleteUser no heir", n, err != nil)
+		r.rec("GetFeedsByUser", fmt.Sprint(s.GetFeedsByUser(ctx)))
+		r.rec("TransferFeedsAndDeleteUser", fmt.Sprint(s.TransferFeedsAndDeleteUser(ctx, database.TransferFeedsAndDeleteUserParams{ToUserID: f.carol.ID, ID: f.bob.ID})))
+		r.rec("GetFeedsByUser", fmt.Sprint(s.GetFeedsByUser(ctx)))
+		r.rec("DeleteFeedFollow", s.DeleteFeedFollow(ctx, database.DeleteFeedFollowParams{UserID: f.alice.ID, FeedID: f.feeds[2].ID}))
+		r.rec("follows alice2", fmt.Sprint(s.GetFeedFollowsForUser(ctx, database.GetFeedFollowsForUserParams{UserID: f.alice.ID})))
+		for _, del := range []func(context.Context, uuid.UUID) (int64, error){
+			s.DeleteUserFollows, s.DeleteUserTags, s.DeleteUserReads, s.DeleteUserStars, s.DeleteUserRules, s.DeleteUserViews, s.DeleteUserWebhooks,
+		} {
+			r.rec("DeleteUser...", fmt.Sprint(del(ctx, f.alice.ID)))
+		}
+		r.rec("CountUserData", fmt.Sprint(s.CountUserData(ctx, f.alice.ID)))
+		r.rec("GetUserStats", fmt.Sprint(s.GetUserStats(ctx, f.alice.ID)))
+		r.rec("DeleteFeed", s.DeleteFeed(ctx, "http://f/Mid2"))
+		r.rec("DeleteUser", fmt.Sprint(s.DeleteUser(ctx, f.carol.ID)))
+		r.rec("GetFeeds", fmt.Sprint(s.GetFeeds(ctx)))
+		r.rec("DeletePosts", fmt.Sprint(s.DeletePosts(ctx)))
+		r.rec("DeleteFeeds", fmt.Sprint(s.DeleteFeeds(ctx)))
+		r.rec("DeleteUsers", fmt.Sprint(s.DeleteUsers(ctx)))
+		r.rec("GetUsers", fmt.Sprint(s.GetUsers(ctx)))
+	}},
+}
+
+// fixture holds what the steps of one store share.
+type fixture struct {
+	alice, bob, carol database.User
+	feeds             []database.Feed
+	posts             []database.Post
+	tagA              database.Tag
+}
+
+// recorder keeps what a store's calls returned, one line per call, with
+// the UUIDs and timestamps the store generated replaced by placeholders so
+// the stores can be compared.
+type recorder struct {
+	lines []string
+	uuids map[string]string
+}
+
+var (
+	uuidPattern = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	timePattern = regexp.MustCompile(`\d{4}-\d\d-\d\d[ T]\d\d:\d\d:\d\d(\.\d+)?( ?[+-]\d\d:?\d\d| UTC|Z)?( UTC)?( m=[+-][\d.]+)?`)
+)
+
+func (r *recorder) rec(name string, values ...any) {
+	for i, v := range values {
+		if err, ok := v.(error); ok && err != nil {
+			switch {
+			case err == sql.ErrNoRows:
+				values[i] = "ErrNoRows"
+			case strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "UNIQUE constraint failed"):
+				values[i] = "duplicate key"
+			default:
+				values[i] = "error"
+			}
+		}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		panic(err)
+	}
+	line := name + " " + string(data)
+
+	line = uuidPattern.ReplaceAllStringFunc(line, func(u string) string {
+		if strings.HasPrefix(u, fixedIDPrefix) {
+			return "id" + strings.TrimLeft(u[len(fixedIDPrefix):], "0")
+		}
+		if _, ok := r.uuids[u]; !ok {
+			r.uuids[u] = fmt.Sprintf("uuid%d", len(r.uuids))
+		}
+		return r.uuids[u]
+	})
+	line = timePattern.ReplaceAllStringFunc(line, func(ts string) string {
+		if strings.HasPrefix(ts, t0.Format("2006-01-02")) {
+			return ts[:19]
+		}
+		return "now"
+	})
+	r.lines = append(r.lines, line)
+}
+
+// t0 is when the rows the steps create were made, unless a query sets it.
+var t0 = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const fixedIDPrefix = "00000000-0000-4000-8000-"
+
+// id returns the nth of the UUIDs the steps pass to queries.
+func id(n int) uuid.UUID {
+	return uuid.MustParse(fmt.Sprintf("%v%012d", fixedIDPrefix, n+1))
+}
+
+// step waits long enough for NOW() to move on, so rows ordered by when
+// they were written sort the same way in every store.
+func step() {
+	time.Sleep(3 * time.Millisecond)
+}
+
+func ns(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: true}
+}
+
+func ni(n int32) sql.NullInt32 {
+	return sql.NullInt32{Int32: n, Valid: true}
+}
+
+func nt(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+// newSQLite returns the SQLite backend on a migrated in-memory database.
+func newSQLite(t *testing.T) Store {
+	t.Helper()
+	db, err := sqlite.Open(sqlite.MemoryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	provider, err := goose.NewProvider(goose.DialectSQLite3, db, sqliteschema.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = provider.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := sqlite.New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return database.New(q)
+}
+
+// TestMemoryMatchesSQLite runs every query against Memory and the SQLite
+// backend and fails where they return different rows or errors.
+func TestMemoryMatchesSQLite(t *testing.T) {
+	ctx := context.Background()
+	stores := map[string]Store{
+		"sqlite": newSQLite(t),
+		"memory": NewMemory(),
+	}
+	fixtures := map[string]*fixture{}
+	recorders := map[string]*recorder{}
+	for name := range stores {
+		fixtures[name] = &fixture{}
+		recorders[name] = &recorder{uuids: map[string]string{}}
+	}
+
+	for _, ps := range paritySteps {
+		// Both stores run each step before the next, so they see the same
+		// clock.
+		for _, name := range []string{"sqlite", "memory"} {
+			recorders[name].lines = nil
+			ps.run(ctx, stores[name], fixtures[name], recorders[name])
+		}
+
+		want, got := recorders["sqlite"].lines, recorders["memory"].lines
+		for i := range max(len(want), len(got)) {
+			var w, g string
+			if i < len(want) {
+				w = want[i]
+			}
+			if i < len(got) {
+				g = got[i]
+			}
+			if w != g {
+				t.Errorf("%v, call %d:\nsqlite: %v\nmemory: %v", ps.name, i+1, w, g)
+			}
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+func (m *Memory) userByName(name string) *database.User {
+	return find(m.users, func(u *database.User) bool { return u.Name == name })
+}
+
+func (m *Memory) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.user(arg.ID) != nil {
+		return database.User{}, duplicateKey("users_pkey")
+	}
+	if m.userByName(arg.Name) != nil {
+		return database.User{}, duplicateKey("users_name_key")
+	}
+	user := database.User{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Role:      "admin",
+	}
+	if len(m.users) > 0 {
+		user.Role = "member"
+	}
+	m.users = append(m.users, user)
+	return user, nil
+}
+
+func (m *Memory) GetUser(ctx context.Context, name string) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.userByName(name)
+	if user == nil {
+		return database.User{}, sql.ErrNoRows
+	}
+	return *user, nil
+}
+
+func (m *Memory) GetUsers(ctx context.Context) ([]database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.users), nil
+}
+
+func (m *Memory) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteUsers(func(u *database.User) bool { return u.ID == id }), nil
+}
+
+func (m *Memory) TransferFeedsAndDeleteUser(ctx context.Context, arg database.TransferFeedsAndDeleteUserParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Checked first so that, as in a transaction, nothing changes if the
+	// feeds can't be transferred.
+	owns := func(f *database.Feed) bool { return f.UserID == arg.ID }
+	if m.user(arg.ToUserID) == nil && find(m.feeds, owns) != nil {
+		return 0, foreignKey("feeds", "user_id")
+	}
+	for i := range m.feeds {
+		if owns(&m.feeds[i]) {
+			m.feeds[i].UserID = arg.ToUserID
+			m.feeds[i].UpdatedAt = now()
+		}
+	}
+	return m.deleteUsers(func(u *database.User) bool { return u.ID == arg.ID }), nil
+}
+
+func (m *Memory) DeleteUsers(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteUsers(func(*database.User) bool { return true }), nil
+}
+
+func (m *Memory) RenameUser(ctx context.Context, arg database.RenameUserParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.user(arg.ID)
+	if user == nil {
+		return 0, nil
+	}
+	if other := m.userByName(arg.Name); other != nil && other != user {
+		return 0, duplicateKey("users_name_key")
+	}
+	user.Name = arg.Name
+	user.FeverApiKey = sql.NullString{}
+	user.UpdatedAt = now()
+	return 1, nil
+}
+
+func (m *Memory) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.userByName(arg.Name)
+	if user == nil {
+		return 0, nil
+	}
+	user.Role = arg.Role
+	user.UpdatedAt = now()
+	return 1, nil
+}
+
+func (m *Memory) CountAdmins(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return count(m.users, func(u *database.User) bool { return u.Role == "admin" }), nil
+}
+
+// updateUser runs update on the user with id, if there is one.
+func (m *Memory) updateUser(id uuid.UUID, update func(*database.User)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if user := m.user(id); user != nil {
+		update(user)
+	}
+}
+
+func (m *Memory) SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error {
+	m.updateUser(arg.ID, func(u *database.User) {
+		u.PasswordHash = arg.PasswordHash
+		u.UpdatedAt = now()
+	})
+	return nil
+}
+
+func (m *Memory) SetUserEmail(ctx context.Context, arg database.SetUserEmailParams) error {
+	m.updateUser(arg.ID, func(u *database.User) {
+		u.Email = arg.Email
+		u.UpdatedAt = now()
+	})
+	return nil
+}
+
+func (m *Memory) SetFeverApiKey(ctx context.Context, arg database.SetFeverApiKeyParams) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user := m.user(arg.ID)
+	if user == nil {
+		return nil
+	}
+	if arg.FeverApiKey.Valid {
+		other := find(m.users, func(u *database.User) bool { return u.FeverApiKey == arg.FeverApiKey })
+		if other != nil && other != user {
+			return duplicateKey("users_fever_api_key_key")
+		}
+	}
+	user.FeverApiKey = arg.FeverApiKey
+	user.UpdatedAt = now()
+	return nil
+}
+
+func (m *Memory) GetUserByFeverApiKey(ctx context.Context, feverApiKey sql.NullString) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !feverApiKey.Valid {
+		return database.User{}, sql.ErrNoRows
+	}
+	user := find(m.users, func(u *database.User) bool { return u.FeverApiKey == feverApiKey })
+	if user == nil {
+		return database.User{}, sql.ErrNoRows
+	}
+	return *user, nil
+}
+
+func (m *Memory) GetDigestRecipients(ctx context.Context) ([]database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var users []database.User
+	for _, user := range m.users {
+		if user.Email.Valid {
+			users = append(users, user)
+		}
+	}
+	slices.SortStableFunc(users, func(a, b database.User) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return users, nil
+}
+
+func (m *Memory) SetLastDigestAt(ctx context.Context, arg database.SetLastDigestAtParams) error {
+	m.updateUser(arg.ID, func(u *database.User) {
+		u.LastDigestAt = arg.LastDigestAt
+	})
+	return nil
+}
+
+func (m *Memory) GetUserStats(ctx context.Context, userID uuid.UUID) (database.GetUserStatsRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return database.GetUserStatsRow{
+		FeedCount:   count(m.feeds, func(f *database.Feed) bool { return f.UserID == userID }),
+		FollowCount: count(m.follows, func(ff *database.FeedFollow) bool { return ff.UserID == userID }),
+		ReadCount:   count(m.reads, func(r *database.PostRead) bool { return r.UserID == userID }),
+		StarCount:   count(m.stars, func(s *database.PostStar) bool { return s.UserID == userID }),
+	}, nil
+}
+
+func (m *Memory) GetLastPostReadAt(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var last *database.PostRead
+	for i, read := range m.reads {
+		if read.UserID == userID && (last == nil || read.CreatedAt.After(last.CreatedAt)) {
+			last = &m.reads[i]
+		}
+	}
+	if last == nil {
+		return time.Time{}, sql.ErrNoRows
+	}
+	return last.CreatedAt, nil
+}
+
+func (m *Memory) CountUserData(ctx context.Context, userID uuid.UUID) (database.CountUserDataRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return database.CountUserDataRow{
+		Follows:  count(m.follows, func(ff *database.FeedFollow) bool { return ff.UserID == userID }),
+		Tags:     count(m.tags, func(t *database.Tag) bool { return t.UserID == userID }),
+		Reads:    count(m.reads, func(r *database.PostRead) bool { return r.UserID == userID }),
+		Stars:    count(m.stars, func(s *database.PostStar) bool { return s.UserID == userID }),
+		Rules:    count(m.rules, func(r *database.PostRule) bool { return r.UserID == userID }),
+		Views:    count(m.views, func(v *database.View) bool { return v.UserID == userID }),
+		Webhooks: count(m.webhooks, func(w *database.Webhook) bool { return w.UserID == userID }),
+	}, nil
+}
+
+func (m *Memory) DeleteUserFollows(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteFollows(func(ff *database.FeedFollow) bool { return ff.UserID == userID }), nil
+}
+
+func (m *Memory) DeleteUserTags(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteTags(func(t *database.Tag) bool { return t.UserID == userID }), nil
+}
+
+func (m *Memory) DeleteUserReads(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return deleteWhere(&m.reads, func(r *database.PostRead) bool { return r.UserID == userID }), nil
+}
+
+func (m *Memory) DeleteUserStars(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return deleteWhere(&m.stars, func(s *database.PostStar) bool { return s.UserID == userID }), nil
+}
+
+func (m *Memory) DeleteUserRules(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return deleteWhere(&m.rules, func(r *database.PostRule) bool { return r.UserID == userID }), nil
+}
+
+func (m *Memory) DeleteUserViews(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return deleteWhere(&m.views, func(v *database.View) bool { return v.UserID == userID }), nil
+}
+
+func (m *Memory) DeleteUserWebhooks(ctx context.Context, userID uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteWebhooks(func(w *database.Webhook) bool { return w.UserID == userID }), nil
+}
+
+func (m *Memory) CreateApiToken(ctx context.Context, arg database.CreateApiTokenParams) (database.ApiToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.user(arg.UserID) == nil {
+		return database.ApiToken{}, foreignKey("api_tokens", "user_id")
+	}
+	token := find(m.tokens, func(t *database.ApiToken) bool {
+		return t.UserID == arg.UserID && t.Name == arg.Name
+	})
+	other := find(m.tokens, func(t *database.ApiToken) bool { return t.TokenHash == arg.TokenHash })
+	if other != nil && other != token {
+		return database.ApiToken{}, duplicateKey("api_tokens_token_hash_key")
+	}
+	if token != nil {
+		token.CreatedAt = arg.CreatedAt
+		token.LastUsedAt = sql.NullTime{}
+		token.TokenHash = arg.TokenHash
+		return *token, nil
+	}
+
+	if find(m.tokens, func(t *database.ApiToken) bool { return t.ID == arg.ID }) != nil {
+		return database.ApiToken{}, duplicateKey("api_tokens_pkey")
+	}
+	m.tokens = append(m.tokens, database.ApiToken{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UserID:    arg.UserID,
+		Name:      arg.Name,
+		TokenHash: arg.TokenHash,
+	})
+	return m.tokens[len(m.tokens)-1], nil
+}
+
+func (m *Memory) GetApiTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var tokens []database.ApiToken
+	for _, token := range m.tokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	slices.SortStableFunc(tokens, func(a, b database.ApiToken) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return tokens, nil
+}
+
+func (m *Memory) DeleteApiToken(ctx context.Context, arg database.DeleteApiTokenParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return deleteWhere(&m.tokens, func(t *database.ApiToken) bool {
+		return t.UserID == arg.UserID && t.Name == arg.Name
+	}), nil
+}
+
+func (m *Memory) GetUserByApiToken(ctx context.Context, tokenHash string) (database.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token := find(m.tokens, func(t *database.ApiToken) bool { return t.TokenHash == tokenHash })
+	if token == nil {
+		return database.User{}, sql.ErrNoRows
+	}
+	return *m.user(token.UserID), nil
+}
+
+func (m *Memory) TouchApiToken(ctx context.Context, tokenHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if token := find(m.tokens, func(t *database.ApiToken) bool { return t.TokenHash == tokenHash }); token != nil {
+		token.LastUsedAt = sql.NullTime{Time: now(), Valid: true}
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+func (m *Memory) webhook(id uuid.UUID) *database.Webhook {
+	return find(m.webhooks, func(w *database.Webhook) bool { return w.ID == id })
+}
+
+func (m *Memory) delivery(id uuid.UUID) *database.WebhookDelivery {
+	return find(m.deliveries, func(d *database.WebhookDelivery) bool { return d.ID == id })
+}
+
+func (m *Memory) CreateWebhook(ctx context.Context, arg database.CreateWebhookParams) (database.Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.webhook(arg.ID) != nil {
+		return database.Webhook{}, duplicateKey("webhooks_pkey")
+	}
+	if m.user(arg.UserID) == nil {
+		return database.Webhook{}, foreignKey("webhooks", "user_id")
+	}
+	if arg.FeedID.Valid && m.feed(arg.FeedID.UUID) == nil {
+		return database.Webhook{}, foreignKey("webhooks", "feed_id")
+	}
+	m.webhooks = append(m.webhooks, database.Webhook(arg))
+	return m.webhooks[len(m.webhooks)-1], nil
+}
+
+func (m *Memory) GetWebhooksForUser(ctx context.Context, userID uuid.UUID) ([]database.GetWebhooksForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetWebhooksForUserRow
+	for _, w := range m.webhooks {
+		if w.UserID != userID {
+			continue
+		}
+		row := database.GetWebhooksForUserRow{
+			ID:        w.ID,
+			CreatedAt: w.CreatedAt,
+			UpdatedAt: w.UpdatedAt,
+			UserID:    w.UserID,
+			Url:       w.Url,
+			Secret:    w.Secret,
+			FeedID:    w.FeedID,
+			Keyword:   w.Keyword,
+		}
+		if w.FeedID.Valid {
+			row.FeedUrl = sql.NullString{String: m.feed(w.FeedID.UUID).Url, Valid: true}
+		}
+		rows = append(rows, row)
+	}
+	slices.SortStableFunc(rows, func(a, b database.GetWebhooksForUserRow) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return rows, nil
+}
+
+func (m *Memory) DeleteWebhook(ctx context.Context, arg database.DeleteWebhookParams) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.deleteWebhooks(func(w *database.Webhook) bool {
+		return w.ID == arg.ID && w.UserID == arg.UserID
+	}), nil
+}
+
+func (m *Memory) EnqueueWebhookDeliveries(ctx context.Context, id uuid.UUID) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	post := m.post(id)
+	if post == nil {
+		return 0, nil
+	}
+
+	now := now()
+	var n int64
+	for _, w := range m.webhooks {
+		switch {
+		case m.follow(w.UserID, post.FeedID) == nil:
+			continue
+		case w.FeedID.Valid && w.FeedID.UUID != post.FeedID:
+			continue
+		case !containsKeyword(post, w.Keyword):
+			continue
+		}
+		queued := find(m.deliveries, func(d *database.WebhookDelivery) bool {
+			return d.WebhookID == w.ID && d.PostID == post.ID
+		})
+		if queued != nil {
+			continue
+		}
+		m.deliveries = append(m.deliveries, database.WebhookDelivery{
+			ID:            uuid.New(),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+			WebhookID:     w.ID,
+			PostID:        post.ID,
+			Status:        "pending",
+			NextAttemptAt: now,
+		})
+		n++
+	}
+	return n, nil
+}
+
+func (m *Memory) ClaimWebhookDeliveries(ctx context.Context, limit int32) ([]database.WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := now()
+	var due []*database.WebhookDelivery
+	for i := range m.deliveries {
+		d := &m.deliveries[i]
+		if d.Status == "pending" && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b *database.WebhookDelivery) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+
+	var claimed []database.WebhookDelivery
+	for _, d := range page(due, limit, 0) {
+		d.Attempts++
+		d.NextAttemptAt = now.Add(5 * time.Minute)
+		d.UpdatedAt = now
+		claimed = append(claimed, *d)
+	}
+	return claimed, nil
+}
+
+func (m *Memory) GetWebhookDeliveryPayload(ctx context.Context, id uuid.UUID) (database.GetWebhookDeliveryPayloadRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d := m.delivery(id)
+	if d == nil {
+		return database.GetWebhookDeliveryPayloadRow{}, sql.ErrNoRows
+	}
+	w := m.webhook(d.WebhookID)
+	post := m.post(d.PostID)
+	feed := m.feed(post.FeedID)
+	return database.GetWebhookDeliveryPayloadRow{
+		WebhookUrl:  w.Url,
+		Secret:      w.Secret,
+		Title:       post.Title,
+		Url:         post.Url,
+		Description: post.Description,
+		PublishedAt: post.PublishedAt,
+		FeedName:    feed.Name,
+		FeedUrl:     feed.Url,
+	}, nil
+}
+
+// updateDelivery runs update on the delivery with id, if there is one.
+func (m *Memory) updateDelivery(id uuid.UUID, update func(*database.WebhookDelivery)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if d := m.delivery(id); d != nil {
+		update(d)
+		d.UpdatedAt = now()
+	}
+}
+
+func (m *Memory) MarkWebhookDeliverySucceeded(ctx context.Context, arg database.MarkWebhookDeliverySucceededParams) error {
+	m.updateDelivery(arg.ID, func(d *database.WebhookDelivery) {
+		d.Status = "delivered"
+		d.DeliveredAt = sql.NullTime{Time: now(), Valid: true}
+		d.LastStatusCode = arg.LastStatusCode
+		d.LastError = sql.NullString{}
+	})
+	return nil
+}
+
+func (m *Memory) MarkWebhookDeliveryFailed(ctx context.Context, arg database.MarkWebhookDeliveryFailedParams) error {
+	m.updateDelivery(arg.ID, func(d *database.WebhookDelivery) {
+		d.Status = arg.Status
+		d.NextAttemptAt = now().Add(seconds(arg.RetrySeconds))
+		d.LastStatusCode = arg.LastStatusCode
+		d.LastError = arg.LastError
+	})
+	return nil
+}
+
+func (m *Memory) GetWebhookDeliveriesForUser(ctx context.Context, arg database.GetWebhookDeliveriesForUserParams) ([]database.GetWebhookDeliveriesForUserRow, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var rows []database.GetWebhookDeliveriesForUserRow
+	for _, d := range m.deliveries {
+		w := m.webhook(d.WebhookID)
+		if w.UserID != arg.UserID {
+			continue
+		}
+		rows = append(rows, database.GetWebhookDeliveriesForUserRow{
+			ID:             d.ID,
+			CreatedAt:      d.CreatedAt,
+			UpdatedAt:      d.UpdatedAt,
+			WebhookID:      d.WebhookID,
+			PostID:         d.PostID,
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			NextAttemptAt:  d.NextAttemptAt,
+			LastStatusCode: d.LastStatusCode,
+			LastError:      d.LastError,
+			DeliveredAt:    d.DeliveredAt,
+			WebhookUrl:     w.Url,
+			PostTitle:      m.post(d.PostID).Title,
+		})
+	}
+	slices.SortStableFunc(rows, func(a, b database.GetWebhookDeliveriesForUserRow) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+	return page(rows, arg.Limit, 0), nil
+}
+
+func (m *Memory) UpsertWebsubSubscription(ctx context.Context, arg database.UpsertWebsubSubscriptionParams) (database.WebsubSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := now()
+	sub := m.subscription(arg.FeedID)
+	if sub == nil {
+		if m.feed(arg.FeedID) == nil {
+			return database.WebsubSubscription{}, foreignKey("websub_subscriptions", "feed_id")
+		}
+		m.subscriptions = append(m.subscriptions, database.WebsubSubscription{
+			FeedID:    arg.FeedID,
+			CreatedAt: now,
+		})
+		sub = &m.subscriptions[len(m.subscriptions)-1]
+	}
+	sub.UpdatedAt = now
+	sub.HubUrl = arg.HubUrl
+	sub.TopicUrl = arg.TopicUrl
+	sub.Secret = arg.Secret
+	sub.State = "pending"
+	return *sub, nil
+}
+
+func (m *Memory) GetWebsubSubscription(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sub := m.subscription(feedID)
+	if sub == nil {
+		return database.WebsubSubscription{}, sql.ErrNoRows
+	}
+	return *sub, nil
+}
+
+// updateSubscription runs update on the subscription to feedID, if there
+// is one.
+func (m *Memory) updateSubscription(feedID uuid.UUID, update func(*database.WebsubSubscription)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sub := m.subscription(feedID); sub != nil {
+		update(sub)
+		sub.UpdatedAt = now()
+	}
+}
+
+func (m *Memory) VerifyWebsubSubscription(ctx context.Context, arg database.VerifyWebsubSubscriptionParams) error {
+	m.updateSubscription(arg.FeedID, func(sub *database.WebsubSubscription) {
+		sub.State = "verified"
+		sub.LeaseExpiresAt = arg.LeaseExpiresAt
+	})
+	return nil
+}
+
+func (m *Memory) DenyWebsubSubscription(ctx context.Context, feedID uuid.UUID) error {
+	m.updateSubscription(feedID, func(sub *database.WebsubSubscription) {
+		sub.State = "denied"
+		sub.LeaseExpiresAt = sql.NullTime{}
+	})
+	return nil
+}
@@ -0,0 +1,199 @@
+// Package store is the storage gator's commands run on. Store has every
+// query they use, in the shapes sqlc generates in internal/database, so
+// *database.Queries is one implementation, on Postgres or SQLite, and Memory
+// is another that needs no database at all.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// ErrDuplicateKey is wrapped by the errors of writes that would break a
+// unique constraint. The databases word these errors their own way.
+var ErrDuplicateKey = errors.New("duplicate key")
+
+type Users interface {
+	CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error)
+	GetUser(ctx context.Context, name string) (database.User, error)
+	GetUsers(ctx context.Context) ([]database.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) (int64, error)
+	TransferFeedsAndDeleteUser(ctx context.Context, arg database.TransferFeedsAndDeleteUserParams) (int64, error)
+	DeleteUsers(ctx context.Context) (int64, error)
+	RenameUser(ctx context.Context, arg database.RenameUserParams) (int64, error)
+	SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (int64, error)
+	CountAdmins(ctx context.Context) (int64, error)
+	SetUserPassword(ctx context.Context, arg database.SetUserPasswordParams) error
+	SetUserEmail(ctx context.Context, arg database.SetUserEmailParams) error
+	SetFeverApiKey(ctx context.Context, arg database.SetFeverApiKeyParams) error
+	GetUserByFeverApiKey(ctx context.Context, feverApiKey sql.NullString) (database.User, error)
+	GetDigestRecipients(ctx context.Context) ([]database.User, error)
+	SetLastDigestAt(ctx context.Context, arg database.SetLastDigestAtParams) error
+	GetUserStats(ctx context.Context, userID uuid.UUID) (database.GetUserStatsRow, error)
+	GetLastPostReadAt(ctx context.Context, userID uuid.UUID) (time.Time, error)
+	CountUserData(ctx context.Context, userID uuid.UUID) (database.CountUserDataRow, error)
+
+	// Deleting a user's data, table by table, for reset --user.
+	DeleteUserFollows(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteUserTags(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteUserReads(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteUserStars(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteUserRules(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteUserViews(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteUserWebhooks(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+type APITokens interface {
+	CreateApiToken(ctx context.Context, arg database.CreateApiTokenParams) (database.ApiToken, error)
+	GetApiTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiToken, error)
+	DeleteApiToken(ctx context.Context, arg database.DeleteApiTokenParams) (int64, error)
+	GetUserByApiToken(ctx context.Context, tokenHash string) (database.User, error)
+	TouchApiToken(ctx context.Context, tokenHash string) error
+}
+
+type Feeds interface {
+	CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error)
+	GetFeed(ctx context.Context, name string) (database.Feed, error)
+	GetFeedByID(ctx context.Context, id uuid.UUID) (database.Feed, error)
+	GetFeedByUrl(ctx context.Context, url string) (database.Feed, error)
+	GetFeedByUrls(ctx context.Context, urls []string) (database.Feed, error)
+	GetFeeds(ctx context.Context) ([]database.Feed, error)
+	GetUserFeeds(ctx context.Context, userID uuid.UUID) ([]database.Feed, error)
+	GetFeedsByUser(ctx context.Context) ([]database.GetFeedsByUserRow, error)
+	GetFeedsHealth(ctx context.Context) ([]database.Feed, error)
+	UpdateFeed(ctx context.Context, arg database.UpdateFeedParams) (database.Feed, error)
+	UpdateFeedMetadata(ctx context.Context, arg database.UpdateFeedMetadataParams) error
+	SetFeedUrl(ctx context.Context, arg database.SetFeedUrlParams) error
+	SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error
+	EnableFeed(ctx context.Context, url string) (int64, error)
+	CountFeedFollowers(ctx context.Context, feedID uuid.UUID) (int64, error)
+	DeleteFeed(ctx context.Context, url string) error
+	DeleteFeedByID(ctx context.Context, id uuid.UUID) error
+	DeleteFeeds(ctx context.Context) (int64, error)
+
+	// The fetch queue.
+	GetNextFeedToFetch(ctx context.Context) (database.Feed, error)
+	GetFeedQueueLag(ctx context.Context) (float64, error)
+	MarkFeedFetched(ctx context.Context, id uuid.UUID) error
+	RecordFeedSuccess(ctx context.Context, arg database.RecordFeedSuccessParams) error
+	RecordFeedFailure(ctx context.Context, arg database.RecordFeedFailureParams) (database.Feed, error)
+	RecordFeedRedirect(ctx context.Context, arg database.RecordFeedRedirectParams) (database.Feed, error)
+	ClearFeedRedirect(ctx context.Context, id uuid.UUID) error
+
+	// Merging a feed into another, see sql/queries/feed_merges.sql.
+	MergeFeedFollows(ctx context.Context, arg database.MergeFeedFollowsParams) error
+	MergeFeedFollowTags(ctx context.Context, arg database.MergeFeedFollowTagsParams) error
+	MergeFeedPosts(ctx context.Context, arg database.MergeFeedPostsParams) error
+	MergeFeedWebhooks(ctx context.Context, arg database.MergeFeedWebhooksParams) error
+	MergeFeedPostRules(ctx context.Context, arg database.MergeFeedPostRulesParams) error
+	MergeFeedViews(ctx context.Context, arg database.MergeFeedViewsParams) error
+}
+
+type Follows interface {
+	CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error)
+	GetFeedFollowsForUser(ctx context.Context, arg database.GetFeedFollowsForUserParams) ([]database.GetFeedFollowsForUserRow, error)
+	GetFollowedFeeds(ctx context.Context, userID uuid.UUID) ([]database.GetFollowedFeedsRow, error)
+	SetFeedFollowDisplayName(ctx context.Context, arg database.SetFeedFollowDisplayNameParams) (int64, error)
+	DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error
+}
+
+type Tags interface {
+	UpsertTag(ctx context.Context, arg database.UpsertTagParams) (database.Tag, error)
+	TagFeedFollow(ctx context.Context, arg database.TagFeedFollowParams) (int64, error)
+	UntagFeedFollow(ctx context.Context, arg database.UntagFeedFollowParams) (int64, error)
+	DeleteUnusedTags(ctx context.Context, userID uuid.UUID) error
+	GetTagsForUser(ctx context.Context, userID uuid.UUID) ([]database.Tag, error)
+	GetFeedTagsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedTagsForUserRow, error)
+}
+
+type Posts interface {
+	CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error)
+	GetPostByShortID(ctx context.Context, arg database.GetPostByShortIDParams) (database.Post, error)
+	GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error)
+	GetPostsForView(ctx context.Context, arg database.GetPostsForViewParams) ([]database.GetPostsForViewRow, error)
+	GetDigestPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetDigestPostsForUserRow, error)
+	GetUnreadPostsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadPostsForUserRow, error)
+	GetUnreadPostShortIDs(ctx context.Context, userID uuid.UUID) ([]int64, error)
+	GetPostItemsSince(ctx context.Context, arg database.GetPostItemsSinceParams) ([]database.GetPostItemsSinceRow, error)
+	GetPostItemsBefore(ctx context.Context, arg database.GetPostItemsBeforeParams) ([]database.GetPostItemsBeforeRow, error)
+	GetPostItemsByShortIDs(ctx context.Context, arg database.GetPostItemsByShortIDsParams) ([]database.GetPostItemsByShortIDsRow, error)
+	CountPosts(ctx context.Context) (int64, error)
+	CountPostsForUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	SetPostContent(ctx context.Context, arg database.SetPostContentParams) error
+	DeletePosts(ctx context.Context) (int64, error)
+
+	// Retention.
+	CountPrunablePosts(ctx context.Context, arg database.CountPrunablePostsParams) (int64, error)
+	PrunePosts(ctx context.Context, arg database.PrunePostsParams) (int64, error)
+	DeletePrunedPosts(ctx context.Context) error
+	DeletePrunedPostsBefore(ctx context.Context, prunedAt time.Time) error
+
+	// The full-text queue.
+	EnqueueFullTextFetch(ctx context.Context, arg database.EnqueueFullTextFetchParams) error
+	ClaimFullTextFetches(ctx context.Context, limit int32) ([]database.FullTextFetch, error)
+	DeleteFullTextFetch(ctx context.Context, postID uuid.UUID) error
+
+	// Read and starred posts.
+	MarkPostRead(ctx context.Context, arg database.MarkPostReadParams) error
+	MarkPostUnread(ctx context.Context, arg database.MarkPostUnreadParams) error
+	MarkFeedReadBefore(ctx context.Context, arg database.MarkFeedReadBeforeParams) error
+	MarkTagReadBefore(ctx context.Context, arg database.MarkTagReadBeforeParams) error
+	MarkAllReadBefore(ctx context.Context, arg database.MarkAllReadBeforeParams) error
+	StarPost(ctx context.Context, arg database.StarPostParams) error
+	UnstarPost(ctx context.Context, arg database.UnstarPostParams) error
+	GetStarredPostShortIDs(ctx context.Context, userID uuid.UUID) ([]int64, error)
+}
+
+type PostRules interface {
+	CreatePostRule(ctx context.Context, arg database.CreatePostRuleParams) (database.PostRule, error)
+	GetPostRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.PostRule, error)
+	DeletePostRule(ctx context.Context, arg database.DeletePostRuleParams) (int64, error)
+}
+
+type Views interface {
+	SaveView(ctx context.Context, arg database.SaveViewParams) (database.View, error)
+	GetViewByName(ctx context.Context, arg database.GetViewByNameParams) (database.View, error)
+	GetViewsForUser(ctx context.Context, userID uuid.UUID) ([]database.View, error)
+	DeleteView(ctx context.Context, arg database.DeleteViewParams) (int64, error)
+}
+
+type Webhooks interface {
+	CreateWebhook(ctx context.Context, arg database.CreateWebhookParams) (database.Webhook, error)
+	GetWebhooksForUser(ctx context.Context, userID uuid.UUID) ([]database.GetWebhooksForUserRow, error)
+	DeleteWebhook(ctx context.Context, arg database.DeleteWebhookParams) (int64, error)
+	EnqueueWebhookDeliveries(ctx context.Context, id uuid.UUID) (int64, error)
+	ClaimWebhookDeliveries(ctx context.Context, limit int32) ([]database.WebhookDelivery, error)
+	GetWebhookDeliveryPayload(ctx context.Context, id uuid.UUID) (database.GetWebhookDeliveryPayloadRow, error)
+	MarkWebhookDeliverySucceeded(ctx context.Context, arg database.MarkWebhookDeliverySucceededParams) error
+	MarkWebhookDeliveryFailed(ctx context.Context, arg database.MarkWebhookDeliveryFailedParams) error
+	GetWebhookDeliveriesForUser(ctx context.Context, arg database.GetWebhookDeliveriesForUserParams) ([]database.GetWebhookDeliveriesForUserRow, error)
+}
+
+type WebSub interface {
+	UpsertWebsubSubscription(ctx context.Context, arg database.UpsertWebsubSubscriptionParams) (database.WebsubSubscription, error)
+	GetWebsubSubscription(ctx context.Context, feedID uuid.UUID) (database.WebsubSubscription, error)
+	VerifyWebsubSubscription(ctx context.Context, arg database.VerifyWebsubSubscriptionParams) error
+	DenyWebsubSubscription(ctx context.Context, feedID uuid.UUID) error
+}
+
+// Store is everything gator stores. Lookups of a single row that find
+// nothing return sql.ErrNoRows, as database/sql does.
+type Store interface {
+	Users
+	APITokens
+	Feeds
+	Follows
+	Tags
+	Posts
+	PostRules
+	Views
+	Webhooks
+	WebSub
+}
+
+var _ Store = (*database.Queries)(nil)
@@ -1,57 +1,133 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/inscrutabletaco/gator/internal/config"
-	"github.com/inscrutabletaco/gator/internal/database"
-	_ "github.com/lib/pq"
 )
 
 func main() {
-	cfg, err := config.Read()
-	if err != nil {
-		log.Fatalf("error reading config: %v", err)
+	// Global flags come before the command, so they never clash with the
+	// command's own flags.
+	fs := flag.NewFlagSet("gator", flag.ExitOnError)
+	configPath := fs.String("config", "", "read the config from this file instead of $XDG_CONFIG_HOME/gator/config.json")
+	profile := fs.String("profile", "", "use this profile from the config, also set with $"+config.EnvProfile)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gator [--config <path>] [--profile <name>] <command> [args...]")
+		fs.PrintDefaults()
 	}
+	fs.Parse(os.Args[1:])
 
-	db, err := sql.Open("postgres", cfg.DBURL)
+	cfg, err := config.Read(*configPath, *profile)
 	if err != nil {
-		log.Fatalf("error connecting to db: %v", err)
+		log.Fatalf("error reading config: %v", err)
 	}
-	defer db.Close()
-	dbQueries := database.New(db)
 
 	programState := &state{
-		db:  dbQueries,
 		cfg: &cfg,
 	}
+	err = openDatabase(programState, cfg.DBURL)
+	if err != nil {
+		log.Fatalf("error connecting to db: %v", err)
+	}
+	defer programState.sqlDB.Close()
 
 	cmds := commands{
-		registeredCommands: make(map[string]func(*state, command) error),
+		registeredCommands: make(map[string]func(context.Context, *state, command) error),
 	}
 	cmds.register("login", handlerLogin)
 	cmds.register("register", handlerRegister)
-	cmds.register("reset", handlerReset)
+	cmds.register("reset", middlewareAdmin(handlerReset))
+	cmds.register("passwd", middlewareLoggedIn(handlerPasswd))
 	cmds.register("users", handlerGetUsers)
+	cmds.register("user", subcommands(map[string]func(context.Context, *state, command) error{
+		"delete": middlewareAdmin(handlerUserDelete),
+		"info":   middlewareLoggedIn(handlerUserInfo),
+		"passwd": middlewareAdmin(handlerUserPasswd),
+		"rename": middlewareLoggedIn(handlerUserRename),
+		"role":   middlewareAdmin(handlerUserRole),
+	}))
 	cmds.register("agg", handlerAgg)
 	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
 	cmds.register("feeds", handlerFeeds)
 	cmds.register("follow", middlewareLoggedIn(handlerFollow))
-	cmds.register("following", middlewareLoggedIn(handlerFollowing))
+	cmds.register("following", middlewareRemote(handlerFollowingRemote, middlewareLoggedIn(handlerFollowing)))
 	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
-	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
-	cmds.register("removefeed", handlerRemoveFeed)
+	cmds.register("browse", middlewareRemote(handlerBrowseRemote, middlewareLoggedIn(handlerBrowse)))
+	cmds.register("removefeed", middlewareLoggedIn(handlerRemoveFeed))
+	cmds.register("rename", middlewareLoggedIn(handlerRename))
+	cmds.register("tag", middlewareLoggedIn(handlerTag))
+	cmds.register("untag", middlewareLoggedIn(handlerUntag))
+	cmds.register("import", middlewareLoggedIn(handlerImport))
+	cmds.register("export", middlewareLoggedIn(handlerExport))
+	cmds.register("fever-password", middlewareLoggedIn(handlerFeverPassword))
+	cmds.register("serve", handlerServe)
+	cmds.register("email", middlewareLoggedIn(handlerEmail))
+	cmds.register("mail-digest", handlerMailDigest)
+	cmds.register("feed", subcommands(map[string]func(context.Context, *state, command) error{
+		"dedupe":    middlewareAdmin(handlerFeedDedupe),
+		"edit":      middlewareLoggedIn(handlerFeedEdit),
+		"enable":    middlewareLoggedIn(handlerFeedEnable),
+		"info":      handlerFeedInfo,
+		"retention": middlewareLoggedIn(handlerFeedRetention),
+	}))
+	cmds.register("prune", middlewareAdmin(handlerPrune))
+	cmds.register("migrate", subcommands(map[string]func(context.Context, *state, command) error{
+		"up":     handlerMigrateUp,
+		"down":   handlerMigrateDown,
+		"status": handlerMigrateStatus,
+	}))
+	cmds.register("view", subcommands(map[string]func(context.Context, *state, command) error{
+		"save":   middlewareLoggedIn(handlerViewSave),
+		"remove": middlewareLoggedIn(handlerViewRemove),
+	}))
+	cmds.register("views", middlewareLoggedIn(handlerViews))
+	cmds.register("rule", subcommands(map[string]func(context.Context, *state, command) error{
+		"add":    middlewareLoggedIn(handlerRuleAdd),
+		"list":   middlewareLoggedIn(handlerRuleList),
+		"remove": middlewareLoggedIn(handlerRuleRemove),
+	}))
+	cmds.register("token", subcommands(map[string]func(context.Context, *state, command) error{
+		"create": middlewareLoggedIn(handlerTokenCreate),
+		"list":   middlewareLoggedIn(handlerTokenList),
+		"revoke": middlewareLoggedIn(handlerTokenRevoke),
+	}))
+	cmds.register("webhook", subcommands(map[string]func(context.Context, *state, command) error{
+		"add":    middlewareLoggedIn(handlerWebhookAdd),
+		"list":   middlewareLoggedIn(handlerWebhookList),
+		"remove": middlewareLoggedIn(handlerWebhookRemove),
+		"log":    middlewareLoggedIn(handlerWebhookLog),
+	}))
 
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: cli <command> [args...]")
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
 	}
 
-	cmdName := os.Args[1]
-	cmdArgs := os.Args[2:]
+	cmdName := fs.Arg(0)
+	cmdArgs := fs.Args()[1:]
+
+	// SIGINT and SIGTERM cancel ctx so long running commands like agg and
+	// serve can stop cleanly. After that a second signal kills gator as
+	// usual, e.g. when it is waiting at a prompt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	if cmdName != "migrate" {
+		err = checkSchema(ctx, programState)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	err = cmds.run(programState, command{Name: cmdName, Args: cmdArgs})
+	err = cmds.run(ctx, programState, command{Name: cmdName, Args: cmdArgs})
 	if err != nil {
 		log.Fatal(err)
 	}
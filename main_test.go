@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/inscrutabletaco/gator/internal/config"
+	"github.com/inscrutabletaco/gator/internal/database"
+	"github.com/inscrutabletaco/gator/internal/store"
+)
+
+const testPassword = "correct horse"
+
+// newTestState returns a state backed by a store.Memory, with a config in a
+// temporary directory that no environment variable overrides.
+func newTestState(t *testing.T) *state {
+	t.Helper()
+	t.Setenv(config.EnvDBURL, "")
+	t.Setenv(config.EnvUser, "")
+	t.Setenv(config.EnvProfile, "")
+	// Where reset backs up follows.
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+
+	cfg, err := config.Read(filepath.Join(t.TempDir(), "config.json"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &state{db: store.NewMemory(), cfg: &cfg}
+}
+
+// runCommand runs handler the way commands.run does, with args as the
+// command line after name.
+func runCommand(s *state, handler func(context.Context, *state, command) error, name string, args ...string) error {
+	return handler(context.Background(), s, command{Name: name, Args: args})
+}
+
+// withStdin answers prompts with the lines in input.
+func withStdin(t *testing.T, input ...string) {
+	t.Helper()
+	old := stdin
+	stdin = bufio.NewReader(strings.NewReader(strings.Join(input, "\n") + "\n"))
+	t.Cleanup(func() { stdin = old })
+}
+
+// registerUser registers name with testPassword, which also logs them in.
+// The first user registered is an admin.
+func registerUser(t *testing.T, s *state, name string) database.User {
+	t.Helper()
+	withStdin(t, testPassword)
+	err := runCommand(s, handlerRegister, "register", name)
+	if err != nil {
+		t.Fatalf("register %v: %v", name, err)
+	}
+	user, err := s.db.GetUser(context.Background(), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// loginAs makes name the current user without asking for a password.
+func loginAs(t *testing.T, s *state, name string) {
+	t.Helper()
+	err := s.cfg.SetUser(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveFeed serves an RSS feed titled title with items and returns its url.
+func serveFeed(t *testing.T, title string, items ...RSSItem) string {
+	t.Helper()
+	var body strings.Builder
+	fmt.Fprintf(&body, "<rss><channel><title>%s</title><description>About %s</description><language>en</language>", title, title)
+	for _, item := range items {
+		fmt.Fprintf(&body, "<item><title>%s</title><link>%s</link><pubDate>%s</pubDate></item>", item.Title, item.Link, item.PubDate)
+	}
+	body.WriteString("</channel></rss>")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprint(w, body.String())
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/feed.xml"
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	feedFetchesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_feed_fetches_total",
+		Help: "Feed fetches attempted by agg, by result.",
+	}, []string{"result"})
+	feedFetchFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gator_feed_fetch_failures_total",
+		Help: "Failed feed fetches, by feed url and error class.",
+	}, []string{"feed", "class"})
+	feedFetchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "gator_feed_fetch_duration_seconds",
+		Help:    "Time taken to download and parse a feed.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	})
+	feedBytesDownloadedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_feed_bytes_downloaded_total",
+		Help: "Bytes of feed content downloaded.",
+	})
+	postsInsertedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "gator_posts_inserted_total",
+		Help: "New posts saved from polled or pushed feeds.",
+	})
+	lastSuccessfulFetch = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gator_last_successful_fetch_timestamp_seconds",
+		Help: "Unix time of the last feed fetched without error.",
+	})
+)
+
+// fetchStatusError is returned by fetchFeed when the server answers with an
+// HTTP error status.
+type fetchStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *fetchStatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %v", e.Status)
+}
+
+// serveMetrics exposes Prometheus metrics for agg on addr until ctx is done.
+// The queue lag is the age of the stalest feed agg still polls, so it keeps
+// growing if agg stops making progress.
+func serveMetrics(ctx context.Context, s *state, addr string) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "gator_feed_queue_lag_seconds",
+		Help: "Time since the least recently fetched feed was last fetched.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		lag, err := s.db.GetFeedQueueLag(ctx)
+		if err != nil {
+			log.Printf("Failed to get feed queue lag: %v", err)
+			return 0
+		}
+		return lag
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	fmt.Printf("Serving metrics on http://%s/metrics\n", addr)
+	err := serveUntilDone(ctx, srv)
+	if err != nil {
+		log.Printf("Metrics listener stopped: %v", err)
+	}
+}
+
+// fetchErrorClass buckets a fetchFeed error into a small, fixed set of
+// label values so failure metrics stay low-cardinality.
+func fetchErrorClass(err error) string {
+	var statusErr *fetchStatusError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var opErr *net.OpError
+	var certErr *tls.CertificateVerificationError
+	var syntaxErr *xml.SyntaxError
+	var unmarshalErr xml.UnmarshalError
+
+	switch {
+	case errors.As(err, &statusErr):
+		if statusErr.StatusCode >= 500 {
+			return "http_5xx"
+		}
+		return "http_4xx"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &certErr):
+		return "tls"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.As(err, &opErr):
+		return "connection"
+	case errors.As(err, &syntaxErr), errors.As(err, &unmarshalErr):
+		return "parse"
+	default:
+		return "other"
+	}
+}
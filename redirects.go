@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/inscrutabletaco/gator/internal/database"
+)
+
+// followFeedRedirect records that fetching feed was permanently redirected
+// to movedTo, or that it wasn't redirected when movedTo is empty. Once the
+// same redirect has been seen redirectsToMove times in a row, the feed is
+// moved to the new url, or merged into the feed already there, and that
+// feed is returned. On error the original feed is returned.
+func followFeedRedirect(ctx context.Context, s *state, feed database.Feed, movedTo string, redirectsToMove int) (database.Feed, error) {
+	// Servers that redirect to add a trailing slash or tracking parameters
+	// lead back to the url already stored.
+	if canonical, err := canonicalFeedURL(movedTo); err == nil {
+		movedTo = canonical
+	}
+	if movedTo == "" || movedTo == feed.Url {
+		if feed.RedirectUrl.Valid {
+			return feed, s.db.ClearFeedRedirect(ctx, feed.ID)
+		}
+		return feed, nil
+	}
+
+	updated, err := s.db.RecordFeedRedirect(ctx, database.RecordFeedRedirectParams{
+		RedirectUrl: sql.NullString{String: movedTo, Valid: true},
+		ID:          feed.ID,
+	})
+	if err != nil {
+		return feed, err
+	}
+	if redirectsToMove <= 0 || int(updated.RedirectCount) < redirectsToMove {
+		return updated, nil
+	}
+
+	target, err := s.db.GetFeedByUrl(ctx, movedTo)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = s.db.SetFeedUrl(ctx, database.SetFeedUrlParams{
+			ID:  feed.ID,
+			Url: movedTo,
+		})
+		if err != nil {
+			return feed, err
+		}
+		log.Printf("Feed %s moved from %s to %s", feed.Name, feed.Url, movedTo)
+		return s.db.GetFeedByID(ctx, feed.ID)
+	}
+	if err != nil {
+		return feed, err
+	}
+
+	err = mergeFeeds(ctx, s, feed, target)
+	if err != nil {
+		return feed, err
+	}
+	log.Printf("Feed %s moved to %s and was merged into %s", feed.Name, movedTo, target.Name)
+	return target, nil
+}
+
+// mergeFeeds moves the follows, posts and everything else referring to from
+// over to into, then deletes from. Every step can be repeated, so a merge
+// that fails partway is completed by running it again.
+func mergeFeeds(ctx context.Context, s *state, from, into database.Feed) error {
+	steps := []struct {
+		what string
+		run  func() error
+	}{
+		{"follows", func() error {
+			return s.db.MergeFeedFollows(ctx, database.MergeFeedFollowsParams{ToFeedID: into.ID, FromFeedID: from.ID})
+		}},
+		{"tags", func() error {
+			return s.db.MergeFeedFollowTags(ctx, database.MergeFeedFollowTagsParams{FromFeedID: from.ID, ToFeedID: into.ID})
+		}},
+		{"posts", func() error {
+			return s.db.MergeFeedPosts(ctx, database.MergeFeedPostsParams{ToFeedID: into.ID, FromFeedID: from.ID})
+		}},
+		{"webhooks", func() error {
+			return s.db.MergeFeedWebhooks(ctx, database.MergeFeedWebhooksParams{ToFeedID: into.ID, FromFeedID: from.ID})
+		}},
+		{"rules", func() error {
+			return s.db.MergeFeedPostRules(ctx, database.MergeFeedPostRulesParams{ToFeedID: into.ID, FromFeedID: from.ID})
+		}},
+		{"views", func() error {
+			return s.db.MergeFeedViews(ctx, database.MergeFeedViewsParams{FromFeedID: from.ID, ToFeedID: into.ID})
+		}},
+		{"feed", func() error {
+			return s.db.DeleteFeedByID(ctx, from.ID)
+		}},
+	}
+
+	for _, step := range steps {
+		err := step.run()
+		if err != nil {
+			return fmt.Errorf("couldn't merge %s of %v into %v: %w", step.what, from.Url, into.Url, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+-- name: CreateApiToken :one
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (user_id, name) DO UPDATE
+SET created_at = EXCLUDED.created_at,
+    last_used_at = NULL,
+    token_hash = EXCLUDED.token_hash
+RETURNING *;
+
+-- name: GetApiTokensForUser :many
+SELECT * FROM api_tokens
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeleteApiToken :execrows
+DELETE FROM api_tokens
+WHERE user_id = $1 AND name = $2;
+
+-- name: GetUserByApiToken :one
+SELECT users.* FROM users
+JOIN api_tokens ON api_tokens.user_id = users.id
+WHERE api_tokens.token_hash = $1;
+
+-- name: TouchApiToken :exec
+UPDATE api_tokens SET last_used_at = NOW()
+WHERE token_hash = $1;
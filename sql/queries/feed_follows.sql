@@ -0,0 +1,49 @@
+-- name: CreateFeedFollow :one
+WITH inserted_feed_follow AS (
+    INSERT INTO feed_follows (user_id, feed_id)
+    VALUES (
+        $1,
+        $2
+    )
+    RETURNING *
+)
+    SELECT
+    inserted_feed_follow.*,
+    feeds.name AS feed_name,
+    users.name AS user_name
+FROM inserted_feed_follow
+INNER JOIN feeds ON feeds.id = inserted_feed_follow.feed_id
+INNER JOIN users ON users.id = inserted_feed_follow.user_id;
+
+-- name: GetFeedFollowsForUser :many
+SELECT users.name AS follower, COALESCE(feed_follows.display_name, feeds.name) AS feed_name, feeds.url AS feed_url,
+    COALESCE((
+        SELECT string_agg(tags.name, ',' ORDER BY tags.name)
+        FROM feed_follow_tags
+        INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+        WHERE feed_follow_tags.feed_follow_id = feed_follows.id
+    ), '')::text AS tags
+FROM feed_follows
+INNER JOIN users ON users.id = feed_follows.user_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_tags
+    INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+    WHERE feed_follow_tags.feed_follow_id = feed_follows.id AND tags.name = sqlc.narg(tag)
+))
+ORDER BY feed_name;
+
+-- name: DeleteFeedFollow :exec
+DELETE FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: GetFollowedFeeds :many
+SELECT feeds.*, feed_follows.display_name FROM feeds
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY COALESCE(feed_follows.display_name, feeds.name);
+
+-- name: SetFeedFollowDisplayName :execrows
+UPDATE feed_follows SET display_name = $3, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = $2;
@@ -0,0 +1,34 @@
+-- Merging a feed into another moves everything that refers to it. Each
+-- statement can be rerun, so an interrupted merge is finished by the next.
+
+-- name: MergeFeedFollows :exec
+INSERT INTO feed_follows (user_id, feed_id, display_name)
+SELECT feed_follows.user_id, sqlc.arg(to_feed_id), feed_follows.display_name
+FROM feed_follows
+WHERE feed_follows.feed_id = sqlc.arg(from_feed_id)
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+-- name: MergeFeedFollowTags :exec
+INSERT INTO feed_follow_tags (feed_follow_id, tag_id)
+SELECT to_follows.id, feed_follow_tags.tag_id
+FROM feed_follow_tags
+INNER JOIN feed_follows AS from_follows ON from_follows.id = feed_follow_tags.feed_follow_id
+INNER JOIN feed_follows AS to_follows ON to_follows.user_id = from_follows.user_id
+WHERE from_follows.feed_id = sqlc.arg(from_feed_id) AND to_follows.feed_id = sqlc.arg(to_feed_id)
+ON CONFLICT DO NOTHING;
+
+-- name: MergeFeedPosts :exec
+UPDATE posts SET feed_id = sqlc.arg(to_feed_id), updated_at = NOW()
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: MergeFeedWebhooks :exec
+UPDATE webhooks SET feed_id = sqlc.arg(to_feed_id), updated_at = NOW()
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: MergeFeedPostRules :exec
+UPDATE post_rules SET feed_id = sqlc.arg(to_feed_id)
+WHERE feed_id = sqlc.arg(from_feed_id);
+
+-- name: MergeFeedViews :exec
+UPDATE views SET feed_ids = array_replace(feed_ids, sqlc.arg(from_feed_id)::uuid, sqlc.arg(to_feed_id)::uuid), updated_at = NOW()
+WHERE sqlc.arg(from_feed_id)::uuid = ANY(feed_ids);
@@ -0,0 +1,162 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING *;
+
+-- name: GetFeed :one
+SELECT * FROM feeds WHERE name = $1;
+
+-- name: GetFeeds :many
+SELECT * FROM feeds;
+
+-- name: GetUserFeeds :many
+SELECT * FROM feeds WHERE user_id = $1;
+
+-- name: DeleteFeeds :execrows
+DELETE FROM feeds;
+
+-- name: GetFeedsByUser :many
+SELECT feeds.name, feeds.url, users.name FROM feeds
+LEFT JOIN users
+ON feeds.user_id = users.id
+ORDER BY users.name, feeds.name;
+
+-- name: GetFeedByID :one
+SELECT * FROM feeds WHERE id = $1;
+
+-- name: GetFeedByUrl :one
+SELECT * FROM feeds WHERE url = $1;
+
+-- name: MarkFeedFetched :exec
+UPDATE feeds
+SET last_fetched_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetNextFeedToFetch :one
+SELECT feeds.* FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+WHERE feeds.disabled_at IS NULL
+AND (
+    websub_subscriptions.state IS DISTINCT FROM 'verified'
+    OR websub_subscriptions.lease_expires_at < NOW()
+    OR feeds.last_fetched_at IS NULL
+    OR feeds.last_fetched_at < NOW() - INTERVAL '1 day'
+)
+AND (
+    feeds.fetch_interval_seconds IS NULL
+    OR feeds.last_fetched_at IS NULL
+    OR feeds.last_fetched_at < NOW() - make_interval(secs => feeds.fetch_interval_seconds)
+)
+ORDER BY feeds.last_fetched_at NULLS FIRST, feeds.id
+LIMIT 1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE url = $1;
+
+-- name: GetFeedQueueLag :one
+SELECT COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(COALESCE(feeds.last_fetched_at, feeds.created_at))), 0)::float8 AS lag_seconds
+FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+WHERE feeds.disabled_at IS NULL
+AND (
+    websub_subscriptions.state IS DISTINCT FROM 'verified'
+    OR websub_subscriptions.lease_expires_at < NOW()
+);
+
+-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_max_age_seconds = $2, retention_max_posts = $3, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET last_status_code = $2,
+    last_error = NULL,
+    consecutive_failures = 0,
+    last_succeeded_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: RecordFeedFailure :one
+UPDATE feeds
+SET last_status_code = sqlc.narg(last_status_code),
+    last_error = sqlc.arg(last_error),
+    consecutive_failures = consecutive_failures + 1,
+    disabled_at = CASE
+        WHEN sqlc.arg(max_failures)::integer > 0 AND consecutive_failures + 1 >= sqlc.arg(max_failures)::integer THEN NOW()
+        ELSE disabled_at
+    END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: EnableFeed :execrows
+UPDATE feeds
+SET disabled_at = NULL, consecutive_failures = 0, updated_at = NOW()
+WHERE url = $1;
+
+-- name: GetFeedsHealth :many
+SELECT * FROM feeds
+ORDER BY disabled_at NULLS LAST, consecutive_failures DESC, name;
+
+-- name: RecordFeedRedirect :one
+UPDATE feeds
+SET redirect_count = CASE WHEN redirect_url = sqlc.arg(redirect_url) THEN redirect_count + 1 ELSE 1 END,
+    redirect_url = sqlc.arg(redirect_url),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ClearFeedRedirect :exec
+UPDATE feeds
+SET redirect_url = NULL, redirect_count = 0, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetFeedUrl :exec
+UPDATE feeds
+SET url = $2, redirect_url = NULL, redirect_count = 0, updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteFeedByID :exec
+DELETE FROM feeds WHERE id = $1;
+
+-- name: GetFeedByUrls :one
+SELECT * FROM feeds
+WHERE url = ANY(sqlc.arg(urls)::text[])
+ORDER BY array_position(sqlc.arg(urls)::text[], url)
+LIMIT 1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2,
+    site_url = $3,
+    description = $4,
+    language = $5,
+    image_url = $6,
+    generator = $7,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: CountFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows WHERE feed_id = $1;
+
+-- name: UpdateFeed :one
+UPDATE feeds
+SET name = sqlc.arg(name),
+    url = sqlc.arg(url),
+    redirect_url = CASE WHEN url = sqlc.arg(url) THEN redirect_url END,
+    redirect_count = CASE WHEN url = sqlc.arg(url) THEN redirect_count ELSE 0 END,
+    fetch_interval_seconds = sqlc.narg(fetch_interval_seconds),
+    fetch_full_text = sqlc.arg(fetch_full_text),
+    disabled_at = sqlc.narg(disabled_at),
+    consecutive_failures = CASE WHEN sqlc.narg(disabled_at)::timestamp IS NULL THEN 0 ELSE consecutive_failures END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,20 @@
+-- name: EnqueueFullTextFetch :exec
+INSERT INTO full_text_fetches (post_id, url)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: ClaimFullTextFetches :many
+UPDATE full_text_fetches
+SET attempts = attempts + 1, next_attempt_at = NOW() + INTERVAL '5 minutes'
+WHERE post_id IN (
+    SELECT post_id FROM full_text_fetches
+    WHERE next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: DeleteFullTextFetch :exec
+DELETE FROM full_text_fetches
+WHERE post_id = $1;
@@ -0,0 +1,13 @@
+-- name: CreatePostRule :one
+INSERT INTO post_rules (user_id, kind, pattern, feed_id, action)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetPostRulesForUser :many
+SELECT * FROM post_rules
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeletePostRule :execrows
+DELETE FROM post_rules
+WHERE id = $1 AND user_id = $2;
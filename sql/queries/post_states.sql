@@ -0,0 +1,69 @@
+-- name: MarkPostRead :exec
+INSERT INTO post_reads (user_id, post_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: MarkPostUnread :exec
+DELETE FROM post_reads
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: MarkFeedReadBefore :exec
+INSERT INTO post_reads (user_id, post_id)
+SELECT feed_follows.user_id, posts.id
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.feed_id = $2 AND posts.created_at < $3
+ON CONFLICT DO NOTHING;
+
+-- name: MarkAllReadBefore :exec
+INSERT INTO post_reads (user_id, post_id)
+SELECT feed_follows.user_id, posts.id
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.created_at < $2
+ON CONFLICT DO NOTHING;
+
+-- name: StarPost :exec
+INSERT INTO post_stars (user_id, post_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: UnstarPost :exec
+DELETE FROM post_stars
+WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetUnreadPostsForUser :many
+SELECT posts.id, posts.short_id, posts.feed_id, posts.title, posts.url, posts.description, posts.author FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+)
+ORDER BY posts.short_id;
+
+-- name: GetUnreadPostShortIDs :many
+SELECT posts.short_id FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+)
+ORDER BY posts.short_id;
+
+-- name: GetStarredPostShortIDs :many
+SELECT posts.short_id FROM posts
+INNER JOIN post_stars ON post_stars.post_id = posts.id
+WHERE post_stars.user_id = $1
+ORDER BY posts.short_id;
+
+-- name: MarkTagReadBefore :exec
+INSERT INTO post_reads (user_id, post_id)
+SELECT feed_follows.user_id, posts.id
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+INNER JOIN feed_follow_tags ON feed_follow_tags.feed_follow_id = feed_follows.id
+INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+WHERE feed_follows.user_id = $1 AND tags.short_id = $2 AND posts.created_at < $3
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,187 @@
+-- name: CreatePost :one
+INSERT INTO posts (title, url, description, published_at, feed_id, author)
+SELECT $1, $2, $3, $4, $5, $6
+WHERE NOT EXISTS (SELECT 1 FROM pruned_posts WHERE pruned_posts.url = $2)
+RETURNING *;
+
+-- name: GetPostsForUser :many
+SELECT posts.*, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(tag)::text IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_tags
+    INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+    WHERE feed_follow_tags.feed_follow_id = feed_follows.id AND tags.name = sqlc.narg(tag)
+))
+ORDER BY posts.published_at DESC, posts.updated_at DESC, posts.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetPostByShortID :one
+SELECT posts.* FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.short_id = $2;
+
+-- name: CountPostsForUser :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1;
+
+-- name: GetPostItemsSince :many
+SELECT posts.*, feeds.short_id AS feed_short_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+    ) AS is_starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.short_id > $2
+ORDER BY posts.short_id
+LIMIT $3;
+
+-- name: GetPostItemsBefore :many
+SELECT posts.*, feeds.short_id AS feed_short_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+    ) AS is_starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.short_id < $2
+ORDER BY posts.short_id DESC
+LIMIT $3;
+
+-- name: GetPostItemsByShortIDs :many
+SELECT posts.*, feeds.short_id AS feed_short_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+    ) AS is_starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id) AND posts.short_id = ANY(sqlc.arg(short_ids)::bigint[])
+ORDER BY posts.short_id;
+
+-- name: GetDigestPostsForUser :many
+SELECT posts.*, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+INNER JOIN users ON users.id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND posts.created_at > COALESCE(users.last_digest_at, NOW() - INTERVAL '1 day')
+ORDER BY feed_name, posts.published_at DESC NULLS LAST, posts.created_at DESC;
+
+-- name: GetPostsForView :many
+SELECT posts.*, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM views
+INNER JOIN feed_follows ON feed_follows.user_id = views.user_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+INNER JOIN posts ON posts.feed_id = feeds.id
+WHERE views.id = sqlc.arg(view_id)
+AND (views.feed_ids IS NULL OR feeds.id = ANY(views.feed_ids))
+AND (views.tags IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_tags
+    INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+    WHERE feed_follow_tags.feed_follow_id = feed_follows.id AND tags.name = ANY(views.tags)
+))
+AND (
+    views.keyword IS NULL
+    OR strpos(lower(posts.title), lower(views.keyword)) > 0
+    OR strpos(lower(posts.description), lower(views.keyword)) > 0
+)
+AND (
+    views.max_age_seconds IS NULL
+    OR COALESCE(posts.published_at, posts.created_at) > NOW() - make_interval(secs => views.max_age_seconds)
+)
+AND (NOT views.unread_only OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.user_id = views.user_id AND post_reads.post_id = posts.id
+))
+ORDER BY posts.published_at DESC, posts.updated_at DESC, posts.created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountPrunablePosts :one
+WITH ranked AS (
+    SELECT posts.id,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+        ) AS position,
+        COALESCE(feeds.retention_max_age_seconds, sqlc.narg(max_age_seconds)::integer) AS max_age_seconds,
+        COALESCE(feeds.retention_max_posts, sqlc.narg(max_posts)::integer) AS max_posts
+    FROM posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+), prunable AS (
+    SELECT ranked.id FROM ranked
+    WHERE (
+        ranked.posted_at < NOW() - make_interval(secs => ranked.max_age_seconds)
+        OR ranked.position > ranked.max_posts
+    )
+    AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id)
+)
+SELECT COUNT(*) FROM prunable;
+
+-- name: PrunePosts :execrows
+WITH ranked AS (
+    SELECT posts.id,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+        ) AS position,
+        COALESCE(feeds.retention_max_age_seconds, sqlc.narg(max_age_seconds)::integer) AS max_age_seconds,
+        COALESCE(feeds.retention_max_posts, sqlc.narg(max_posts)::integer) AS max_posts
+    FROM posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+), prunable AS (
+    SELECT ranked.id FROM ranked
+    WHERE (
+        ranked.posted_at < NOW() - make_interval(secs => ranked.max_age_seconds)
+        OR ranked.position > ranked.max_posts
+    )
+    AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id)
+    LIMIT sqlc.arg('limit')
+), deleted AS (
+    DELETE FROM posts
+    WHERE posts.id IN (SELECT prunable.id FROM prunable)
+    RETURNING posts.url
+)
+INSERT INTO pruned_posts (url)
+SELECT deleted.url FROM deleted
+ON CONFLICT (url) DO UPDATE SET pruned_at = NOW();
+
+-- name: DeletePrunedPostsBefore :exec
+DELETE FROM pruned_posts
+WHERE pruned_at < $1;
+
+-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: CountPosts :one
+SELECT COUNT(*) FROM posts;
+
+-- name: DeletePosts :execrows
+DELETE FROM posts;
+
+-- name: DeletePrunedPosts :exec
+DELETE FROM pruned_posts;
@@ -0,0 +1,41 @@
+-- name: UpsertTag :one
+INSERT INTO tags (user_id, name)
+VALUES ($1, $2)
+ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: TagFeedFollow :execrows
+INSERT INTO feed_follow_tags (feed_follow_id, tag_id)
+SELECT feed_follows.id, sqlc.arg(tag_id)::uuid
+FROM feed_follows
+WHERE feed_follows.user_id = sqlc.arg(user_id) AND feed_follows.feed_id = sqlc.arg(feed_id);
+
+-- name: UntagFeedFollow :execrows
+DELETE FROM feed_follow_tags
+USING feed_follows, tags
+WHERE feed_follow_tags.feed_follow_id = feed_follows.id
+AND feed_follow_tags.tag_id = tags.id
+AND feed_follows.user_id = $1
+AND feed_follows.feed_id = $2
+AND tags.name = $3;
+
+-- name: DeleteUnusedTags :exec
+DELETE FROM tags
+WHERE user_id = $1
+AND NOT EXISTS (
+    SELECT 1 FROM feed_follow_tags WHERE feed_follow_tags.tag_id = tags.id
+);
+
+-- name: GetTagsForUser :many
+SELECT * FROM tags
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetFeedTagsForUser :many
+SELECT tags.short_id AS tag_short_id, tags.name AS tag_name, feeds.short_id AS feed_short_id
+FROM feed_follow_tags
+INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+INNER JOIN feed_follows ON feed_follows.id = feed_follow_tags.feed_follow_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE tags.user_id = $1
+ORDER BY tags.name, feeds.short_id;
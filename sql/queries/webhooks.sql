@@ -0,0 +1,91 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, url, secret, feed_id, keyword)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: GetWebhooksForUser :many
+SELECT webhooks.*, feeds.url AS feed_url
+FROM webhooks
+LEFT JOIN feeds ON feeds.id = webhooks.feed_id
+WHERE webhooks.user_id = $1
+ORDER BY webhooks.created_at;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (webhook_id, post_id)
+SELECT webhooks.id, posts.id
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+INNER JOIN webhooks ON webhooks.user_id = feed_follows.user_id
+WHERE posts.id = $1
+AND (webhooks.feed_id IS NULL OR webhooks.feed_id = posts.feed_id)
+AND (
+    webhooks.keyword IS NULL
+    OR strpos(lower(posts.title), lower(webhooks.keyword)) > 0
+    OR strpos(lower(posts.description), lower(webhooks.keyword)) > 0
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET attempts = attempts + 1, next_attempt_at = NOW() + INTERVAL '5 minutes', updated_at = NOW()
+WHERE id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: GetWebhookDeliveryPayload :one
+SELECT
+    webhooks.url AS webhook_url,
+    webhooks.secret,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url
+FROM webhook_deliveries
+INNER JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+INNER JOIN posts ON posts.id = webhook_deliveries.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE webhook_deliveries.id = $1;
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+SET status = 'delivered', delivered_at = NOW(), last_status_code = $2, last_error = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET
+    status = sqlc.arg(status),
+    next_attempt_at = NOW() + sqlc.arg(retry_seconds)::integer * INTERVAL '1 second',
+    last_status_code = sqlc.arg(last_status_code),
+    last_error = sqlc.arg(last_error),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: GetWebhookDeliveriesForUser :many
+SELECT webhook_deliveries.*, webhooks.url AS webhook_url, posts.title AS post_title
+FROM webhook_deliveries
+INNER JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+INNER JOIN posts ON posts.id = webhook_deliveries.post_id
+WHERE webhooks.user_id = $1
+ORDER BY webhook_deliveries.updated_at DESC
+LIMIT $2;
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN fever_api_key TEXT UNIQUE;
+ALTER TABLE feeds ADD COLUMN short_id BIGSERIAL NOT NULL UNIQUE;
+ALTER TABLE posts ADD COLUMN short_id BIGSERIAL NOT NULL UNIQUE;
+
+CREATE TABLE post_reads (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE post_stars (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE post_stars;
+DROP TABLE post_reads;
+ALTER TABLE posts DROP COLUMN short_id;
+ALTER TABLE feeds DROP COLUMN short_id;
+ALTER TABLE users DROP COLUMN fever_api_key;
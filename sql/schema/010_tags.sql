@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE tags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    short_id BIGSERIAL NOT NULL UNIQUE,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE feed_follow_tags (
+    feed_follow_id UUID NOT NULL REFERENCES feed_follows(id) ON DELETE CASCADE,
+    tag_id UUID NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (feed_follow_id, tag_id)
+);
+
+-- +goose Down
+DROP TABLE feed_follow_tags;
+DROP TABLE tags;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN author TEXT;
+
+CREATE TABLE post_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('keyword', 'regex', 'author', 'domain', 'feed')),
+    pattern TEXT NOT NULL,
+    feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('mute', 'highlight', 'mark-read'))
+);
+
+-- +goose Down
+DROP TABLE post_rules;
+ALTER TABLE posts DROP COLUMN author;
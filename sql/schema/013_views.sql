@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE views (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    feed_ids UUID[],
+    tags TEXT[],
+    keyword TEXT,
+    max_age_seconds INTEGER,
+    unread_only BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (user_id, name)
+);
+
+-- +goose Down
+DROP TABLE views;
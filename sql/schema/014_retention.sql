@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN retention_max_age_seconds INTEGER;
+ALTER TABLE feeds ADD COLUMN retention_max_posts INTEGER;
+
+-- Pruned posts are remembered by url so they aren't saved again while the
+-- feed still lists them.
+CREATE TABLE pruned_posts (
+    url TEXT PRIMARY KEY,
+    pruned_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE pruned_posts;
+ALTER TABLE feeds DROP COLUMN retention_max_posts;
+ALTER TABLE feeds DROP COLUMN retention_max_age_seconds;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'member'
+    CHECK (role IN ('admin', 'member'));
+
+-- Someone has to be able to manage an existing database, so the first user
+-- to register becomes its admin.
+UPDATE users SET role = 'admin'
+WHERE id = (SELECT id FROM users ORDER BY created_at LIMIT 1);
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN role;
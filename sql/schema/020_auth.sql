@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN password_hash TEXT;
+
+CREATE TABLE api_tokens (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    -- Only the SHA-256 of a token is stored; the token itself is shown once.
+    token_hash TEXT NOT NULL UNIQUE,
+    UNIQUE (user_id, name)
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
+ALTER TABLE users DROP COLUMN password_hash;
@@ -0,0 +1,10 @@
+// Package schema embeds the Postgres schema migrations, so gator can set up
+// and upgrade its database without a copy of the source.
+package schema
+
+import "embed"
+
+// FS holds the migrations, named <version>_<name>.sql in goose's format.
+//
+//go:embed *.sql
+var FS embed.FS
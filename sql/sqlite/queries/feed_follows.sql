@@ -0,0 +1,42 @@
+-- name: CreateFeedFollow :one
+INSERT INTO feed_follows (user_id, feed_id)
+VALUES (
+    $1,
+    $2
+)
+RETURNING id, created_at, updated_at, user_id, feed_id, display_name,
+    (SELECT feeds.name FROM feeds WHERE feeds.id = feed_follows.feed_id) AS feed_name,
+    (SELECT users.name FROM users WHERE users.id = feed_follows.user_id) AS user_name;
+
+-- name: GetFeedFollowsForUser :many
+SELECT users.name AS follower, COALESCE(feed_follows.display_name, feeds.name) AS feed_name, feeds.url AS feed_url,
+    COALESCE((
+        SELECT string_agg(tags.name, ',' ORDER BY tags.name)
+        FROM feed_follow_tags
+        INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+        WHERE feed_follow_tags.feed_follow_id = feed_follows.id
+    ), '') AS tags
+FROM feed_follows
+INNER JOIN users ON users.id = feed_follows.user_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND ($2 IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_tags
+    INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+    WHERE feed_follow_tags.feed_follow_id = feed_follows.id AND tags.name = $2
+))
+ORDER BY feed_name;
+
+-- name: DeleteFeedFollow :exec
+DELETE FROM feed_follows
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: GetFollowedFeeds :many
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.short_id, feeds.retention_max_age_seconds, feeds.retention_max_posts, feeds.last_status_code, feeds.last_error, feeds.consecutive_failures, feeds.last_succeeded_at, feeds.disabled_at, feeds.redirect_url, feeds.redirect_count, feeds.title, feeds.site_url, feeds.description, feeds.language, feeds.image_url, feeds.generator, feeds.fetch_interval_seconds, feeds.fetch_full_text, feed_follows.display_name FROM feeds
+INNER JOIN feed_follows ON feed_follows.feed_id = feeds.id
+WHERE feed_follows.user_id = $1
+ORDER BY COALESCE(feed_follows.display_name, feeds.name);
+
+-- name: SetFeedFollowDisplayName :execrows
+UPDATE feed_follows SET display_name = $3, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE user_id = $1 AND feed_id = $2;
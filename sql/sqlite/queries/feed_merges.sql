@@ -0,0 +1,34 @@
+-- Merging a feed into another moves everything that refers to it. Each
+-- statement can be rerun, so an interrupted merge is finished by the next.
+
+-- name: MergeFeedFollows :exec
+INSERT INTO feed_follows (user_id, feed_id, display_name)
+SELECT feed_follows.user_id, $1, feed_follows.display_name
+FROM feed_follows
+WHERE feed_follows.feed_id = $2
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+-- name: MergeFeedFollowTags :exec
+INSERT INTO feed_follow_tags (feed_follow_id, tag_id)
+SELECT to_follows.id, feed_follow_tags.tag_id
+FROM feed_follow_tags
+INNER JOIN feed_follows AS from_follows ON from_follows.id = feed_follow_tags.feed_follow_id
+INNER JOIN feed_follows AS to_follows ON to_follows.user_id = from_follows.user_id
+WHERE from_follows.feed_id = $1 AND to_follows.feed_id = $2
+ON CONFLICT DO NOTHING;
+
+-- name: MergeFeedPosts :exec
+UPDATE posts SET feed_id = $1, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE feed_id = $2;
+
+-- name: MergeFeedWebhooks :exec
+UPDATE webhooks SET feed_id = $1, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE feed_id = $2;
+
+-- name: MergeFeedPostRules :exec
+UPDATE post_rules SET feed_id = $1
+WHERE feed_id = $2;
+
+-- name: MergeFeedViews :exec
+UPDATE views SET feed_ids = replace(feed_ids, '"' || $1 || '"', '"' || $2 || '"'), updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE instr(feed_ids, '"' || $1 || '"') > 0;
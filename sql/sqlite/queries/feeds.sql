@@ -0,0 +1,162 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text;
+
+-- name: GetFeed :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds WHERE name = $1;
+
+-- name: GetFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds;
+
+-- name: GetUserFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds WHERE user_id = $1;
+
+-- name: DeleteFeeds :execrows
+DELETE FROM feeds;
+
+-- name: GetFeedsByUser :many
+SELECT feeds.name, feeds.url, users.name FROM feeds
+LEFT JOIN users
+ON feeds.user_id = users.id
+ORDER BY users.name, feeds.name;
+
+-- name: GetFeedByID :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds WHERE id = $1;
+
+-- name: GetFeedByUrl :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds WHERE url = $1;
+
+-- name: MarkFeedFetched :exec
+UPDATE feeds
+SET last_fetched_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'), updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: GetNextFeedToFetch :one
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.short_id, feeds.retention_max_age_seconds, feeds.retention_max_posts, feeds.last_status_code, feeds.last_error, feeds.consecutive_failures, feeds.last_succeeded_at, feeds.disabled_at, feeds.redirect_url, feeds.redirect_count, feeds.title, feeds.site_url, feeds.description, feeds.language, feeds.image_url, feeds.generator, feeds.fetch_interval_seconds, feeds.fetch_full_text FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+WHERE feeds.disabled_at IS NULL
+AND (
+    websub_subscriptions.state IS NOT 'verified'
+    OR websub_subscriptions.lease_expires_at < strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+    OR feeds.last_fetched_at IS NULL
+    OR feeds.last_fetched_at < strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-1 day')
+)
+AND (
+    feeds.fetch_interval_seconds IS NULL
+    OR feeds.last_fetched_at IS NULL
+    OR feeds.last_fetched_at < strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-' || feeds.fetch_interval_seconds || ' seconds')
+)
+ORDER BY feeds.last_fetched_at NULLS FIRST, feeds.id
+LIMIT 1;
+
+-- name: DeleteFeed :exec
+DELETE FROM feeds WHERE url = $1;
+
+-- name: GetFeedQueueLag :one
+SELECT COALESCE((julianday('now') - julianday(MIN(COALESCE(feeds.last_fetched_at, feeds.created_at)))) * 86400, 0.0) AS lag_seconds
+FROM feeds
+LEFT JOIN websub_subscriptions ON websub_subscriptions.feed_id = feeds.id
+WHERE feeds.disabled_at IS NULL
+AND (
+    websub_subscriptions.state IS NOT 'verified'
+    OR websub_subscriptions.lease_expires_at < strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+);
+
+-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_max_age_seconds = $2, retention_max_posts = $3, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET last_status_code = $2,
+    last_error = NULL,
+    consecutive_failures = 0,
+    last_succeeded_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: RecordFeedFailure :one
+UPDATE feeds
+SET last_status_code = $1,
+    last_error = $2,
+    consecutive_failures = consecutive_failures + 1,
+    disabled_at = CASE
+        WHEN $3 > 0 AND consecutive_failures + 1 >= $3 THEN strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+        ELSE disabled_at
+    END,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $4
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text;
+
+-- name: EnableFeed :execrows
+UPDATE feeds
+SET disabled_at = NULL, consecutive_failures = 0, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE url = $1;
+
+-- name: GetFeedsHealth :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text FROM feeds
+ORDER BY disabled_at NULLS LAST, consecutive_failures DESC, name;
+
+-- name: RecordFeedRedirect :one
+UPDATE feeds
+SET redirect_count = CASE WHEN redirect_url = $1 THEN redirect_count + 1 ELSE 1 END,
+    redirect_url = $1,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $2
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text;
+
+-- name: ClearFeedRedirect :exec
+UPDATE feeds
+SET redirect_url = NULL, redirect_count = 0, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: SetFeedUrl :exec
+UPDATE feeds
+SET url = $2, redirect_url = NULL, redirect_count = 0, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: DeleteFeedByID :exec
+DELETE FROM feeds WHERE id = $1;
+
+-- name: GetFeedByUrls :one
+SELECT feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.short_id, feeds.retention_max_age_seconds, feeds.retention_max_posts, feeds.last_status_code, feeds.last_error, feeds.consecutive_failures, feeds.last_succeeded_at, feeds.disabled_at, feeds.redirect_url, feeds.redirect_count, feeds.title, feeds.site_url, feeds.description, feeds.language, feeds.image_url, feeds.generator, feeds.fetch_interval_seconds, feeds.fetch_full_text FROM feeds
+INNER JOIN json_each($1) AS urls ON urls.value = feeds.url
+ORDER BY urls.key
+LIMIT 1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET title = $2,
+    site_url = $3,
+    description = $4,
+    language = $5,
+    image_url = $6,
+    generator = $7,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: CountFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows WHERE feed_id = $1;
+
+-- name: UpdateFeed :one
+UPDATE feeds
+SET name = $1,
+    url = $2,
+    redirect_url = CASE WHEN url = $2 THEN redirect_url END,
+    redirect_count = CASE WHEN url = $2 THEN redirect_count ELSE 0 END,
+    fetch_interval_seconds = $3,
+    fetch_full_text = $4,
+    disabled_at = $5,
+    consecutive_failures = CASE WHEN $5 IS NULL THEN 0 ELSE consecutive_failures END,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $6
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, short_id, retention_max_age_seconds, retention_max_posts, last_status_code, last_error, consecutive_failures, last_succeeded_at, disabled_at, redirect_url, redirect_count, title, site_url, description, language, image_url, generator, fetch_interval_seconds, fetch_full_text;
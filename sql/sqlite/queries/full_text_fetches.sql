@@ -0,0 +1,19 @@
+-- name: EnqueueFullTextFetch :exec
+INSERT INTO full_text_fetches (post_id, url)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: ClaimFullTextFetches :many
+UPDATE full_text_fetches
+SET attempts = attempts + 1, next_attempt_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '+5 minutes')
+WHERE post_id IN (
+    SELECT post_id FROM full_text_fetches
+    WHERE next_attempt_at <= strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+    ORDER BY next_attempt_at
+    LIMIT $1
+)
+RETURNING post_id, created_at, url, attempts, next_attempt_at;
+
+-- name: DeleteFullTextFetch :exec
+DELETE FROM full_text_fetches
+WHERE post_id = $1;
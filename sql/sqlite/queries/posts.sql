@@ -0,0 +1,192 @@
+-- name: CreatePost :one
+INSERT INTO posts (title, url, description, published_at, feed_id, author)
+SELECT $1, $2, $3, $4, $5, $6
+WHERE NOT EXISTS (SELECT 1 FROM pruned_posts WHERE pruned_posts.url = $2)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, short_id, author, content;
+
+-- name: GetPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+AND ($2 IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_tags
+    INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+    WHERE feed_follow_tags.feed_follow_id = feed_follows.id AND tags.name = $2
+))
+-- Postgres puts NULLs first in descending order, SQLite last.
+ORDER BY posts.published_at DESC NULLS FIRST, posts.updated_at DESC, posts.created_at DESC
+LIMIT $3 OFFSET $4;
+
+-- name: GetPostByShortID :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.short_id = $2;
+
+-- name: CountPostsForUser :one
+SELECT COUNT(*) FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1;
+
+-- name: GetPostItemsSince :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, feeds.short_id AS feed_short_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+    ) AS is_starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.short_id > $2
+ORDER BY posts.short_id
+LIMIT $3;
+
+-- name: GetPostItemsBefore :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, feeds.short_id AS feed_short_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+    ) AS is_starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.short_id < $2
+ORDER BY posts.short_id DESC
+LIMIT $3;
+
+-- name: GetPostItemsByShortIDs :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, feeds.short_id AS feed_short_id,
+    EXISTS (
+        SELECT 1 FROM post_reads
+        WHERE post_reads.user_id = feed_follows.user_id AND post_reads.post_id = posts.id
+    ) AS is_read,
+    EXISTS (
+        SELECT 1 FROM post_stars
+        WHERE post_stars.user_id = feed_follows.user_id AND post_stars.post_id = posts.id
+    ) AS is_starred
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $1 AND posts.short_id IN (SELECT value FROM json_each($2))
+ORDER BY posts.short_id;
+
+-- name: GetDigestPostsForUser :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+INNER JOIN users ON users.id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+AND posts.created_at > COALESCE(users.last_digest_at, strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-1 day'))
+ORDER BY feed_name, posts.published_at DESC NULLS LAST, posts.created_at DESC;
+
+-- name: GetPostsForView :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.short_id, posts.author, posts.content, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM views
+INNER JOIN feed_follows ON feed_follows.user_id = views.user_id
+INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+INNER JOIN posts ON posts.feed_id = feeds.id
+WHERE views.id = $1
+AND (views.feed_ids IS NULL OR feeds.id IN (SELECT value FROM json_each(views.feed_ids)))
+AND (views.tags IS NULL OR EXISTS (
+    SELECT 1 FROM feed_follow_tags
+    INNER JOIN tags ON tags.id = feed_follow_tags.tag_id
+    WHERE feed_follow_tags.feed_follow_id = feed_follows.id AND tags.name IN (SELECT value FROM json_each(views.tags))
+))
+AND (
+    views.keyword IS NULL
+    OR instr(lower(posts.title), lower(views.keyword)) > 0
+    OR instr(lower(posts.description), lower(views.keyword)) > 0
+)
+AND (
+    views.max_age_seconds IS NULL
+    OR COALESCE(posts.published_at, posts.created_at) > strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-' || views.max_age_seconds || ' seconds')
+)
+AND (NOT views.unread_only OR NOT EXISTS (
+    SELECT 1 FROM post_reads
+    WHERE post_reads.user_id = views.user_id AND post_reads.post_id = posts.id
+))
+ORDER BY posts.published_at DESC NULLS FIRST, posts.updated_at DESC, posts.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountPrunablePosts :one
+WITH ranked AS (
+    SELECT posts.id,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+        ) AS position,
+        COALESCE(feeds.retention_max_age_seconds, $1) AS max_age_seconds,
+        COALESCE(feeds.retention_max_posts, $2) AS max_posts
+    FROM posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+), prunable AS (
+    SELECT ranked.id FROM ranked
+    WHERE (
+        ranked.posted_at < strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-' || ranked.max_age_seconds || ' seconds')
+        OR ranked.position > ranked.max_posts
+    )
+    AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id)
+)
+SELECT COUNT(*) FROM prunable;
+
+-- name: PrunePosts :execrows
+CREATE TEMP TABLE IF NOT EXISTS prunable_posts (id TEXT PRIMARY KEY, url TEXT NOT NULL);
+
+DELETE FROM temp.prunable_posts;
+
+INSERT INTO temp.prunable_posts (id, url)
+WITH ranked AS (
+    SELECT posts.id, posts.url,
+        COALESCE(posts.published_at, posts.created_at) AS posted_at,
+        ROW_NUMBER() OVER (
+            PARTITION BY posts.feed_id
+            ORDER BY posts.published_at DESC NULLS LAST, posts.created_at DESC
+        ) AS position,
+        COALESCE(feeds.retention_max_age_seconds, $1) AS max_age_seconds,
+        COALESCE(feeds.retention_max_posts, $2) AS max_posts
+    FROM posts
+    INNER JOIN feeds ON feeds.id = posts.feed_id
+)
+SELECT ranked.id, ranked.url FROM ranked
+WHERE (
+    ranked.posted_at < strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '-' || ranked.max_age_seconds || ' seconds')
+    OR ranked.position > ranked.max_posts
+)
+AND NOT EXISTS (SELECT 1 FROM post_stars WHERE post_stars.post_id = ranked.id)
+LIMIT $3;
+
+INSERT INTO pruned_posts (url)
+SELECT prunable_posts.url FROM temp.prunable_posts WHERE true
+ON CONFLICT (url) DO UPDATE SET pruned_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now');
+
+DELETE FROM posts
+WHERE posts.id IN (SELECT prunable_posts.id FROM temp.prunable_posts);
+
+-- name: DeletePrunedPostsBefore :exec
+DELETE FROM pruned_posts
+WHERE pruned_at < $1;
+
+-- name: SetPostContent :exec
+UPDATE posts
+SET content = $2, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: CountPosts :one
+SELECT COUNT(*) FROM posts;
+
+-- name: DeletePosts :execrows
+DELETE FROM posts;
+
+-- name: DeletePrunedPosts :exec
+DELETE FROM pruned_posts;
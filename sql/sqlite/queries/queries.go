@@ -0,0 +1,9 @@
+// Package queries embeds the SQLite versions of the queries in sql/queries.
+// Each has the name of the Postgres query it stands in for and takes the
+// same parameters, numbered as sqlc numbers them in internal/database.
+package queries
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
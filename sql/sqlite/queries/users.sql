@@ -0,0 +1,106 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, name, role)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    CASE WHEN EXISTS (SELECT 1 FROM users) THEN 'member' ELSE 'admin' END
+)
+RETURNING id, created_at, updated_at, name, fever_api_key, email, last_digest_at, role, password_hash;
+
+-- name: GetUser :one
+SELECT id, created_at, updated_at, name, fever_api_key, email, last_digest_at, role, password_hash FROM users WHERE name = $1;
+
+-- name: DeleteUsers :execrows
+DELETE FROM users;
+
+-- name: GetUsers :many
+SELECT id, created_at, updated_at, name, fever_api_key, email, last_digest_at, role, password_hash FROM users;
+
+-- name: SetFeverApiKey :exec
+UPDATE users SET fever_api_key = $2, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: GetUserByFeverApiKey :one
+SELECT id, created_at, updated_at, name, fever_api_key, email, last_digest_at, role, password_hash FROM users WHERE fever_api_key = $1;
+
+-- name: SetUserEmail :exec
+UPDATE users SET email = $2, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: GetDigestRecipients :many
+SELECT id, created_at, updated_at, name, fever_api_key, email, last_digest_at, role, password_hash FROM users
+WHERE email IS NOT NULL
+ORDER BY name;
+
+-- name: SetLastDigestAt :exec
+UPDATE users SET last_digest_at = $2
+WHERE id = $1;
+
+-- name: SetUserRole :execrows
+UPDATE users SET role = $2, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE name = $1;
+
+-- name: CountAdmins :one
+SELECT COUNT(*) FROM users WHERE role = 'admin';
+
+-- name: SetUserPassword :exec
+UPDATE users SET password_hash = $2, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = $1;
+
+-- name: TransferFeedsAndDeleteUser :execrows
+UPDATE feeds SET user_id = $1, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE user_id = $2;
+DELETE FROM users WHERE id = $2;
+
+-- name: RenameUser :execrows
+UPDATE users SET name = $2, fever_api_key = NULL, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: GetUserStats :one
+SELECT
+    (SELECT COUNT(*) FROM feeds WHERE feeds.user_id = $1) AS feed_count,
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = $1) AS follow_count,
+    (SELECT COUNT(*) FROM post_reads WHERE post_reads.user_id = $1) AS read_count,
+    (SELECT COUNT(*) FROM post_stars WHERE post_stars.user_id = $1) AS star_count;
+
+-- name: GetLastPostReadAt :one
+SELECT created_at FROM post_reads
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: CountUserData :one
+SELECT
+    (SELECT COUNT(*) FROM feed_follows WHERE feed_follows.user_id = $1) AS follows,
+    (SELECT COUNT(*) FROM tags WHERE tags.user_id = $1) AS tags,
+    (SELECT COUNT(*) FROM post_reads WHERE post_reads.user_id = $1) AS reads,
+    (SELECT COUNT(*) FROM post_stars WHERE post_stars.user_id = $1) AS stars,
+    (SELECT COUNT(*) FROM post_rules WHERE post_rules.user_id = $1) AS rules,
+    (SELECT COUNT(*) FROM views WHERE views.user_id = $1) AS views,
+    (SELECT COUNT(*) FROM webhooks WHERE webhooks.user_id = $1) AS webhooks;
+
+-- name: DeleteUserFollows :execrows
+DELETE FROM feed_follows WHERE feed_follows.user_id = $1;
+
+-- name: DeleteUserTags :execrows
+DELETE FROM tags WHERE tags.user_id = $1;
+
+-- name: DeleteUserReads :execrows
+DELETE FROM post_reads WHERE post_reads.user_id = $1;
+
+-- name: DeleteUserStars :execrows
+DELETE FROM post_stars WHERE post_stars.user_id = $1;
+
+-- name: DeleteUserRules :execrows
+DELETE FROM post_rules WHERE post_rules.user_id = $1;
+
+-- name: DeleteUserViews :execrows
+DELETE FROM views WHERE views.user_id = $1;
+
+-- name: DeleteUserWebhooks :execrows
+DELETE FROM webhooks WHERE webhooks.user_id = $1;
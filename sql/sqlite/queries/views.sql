@@ -0,0 +1,24 @@
+-- name: SaveView :one
+INSERT INTO views (user_id, name, feed_ids, tags, keyword, max_age_seconds, unread_only)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (user_id, name) DO UPDATE
+SET updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+    feed_ids = EXCLUDED.feed_ids,
+    tags = EXCLUDED.tags,
+    keyword = EXCLUDED.keyword,
+    max_age_seconds = EXCLUDED.max_age_seconds,
+    unread_only = EXCLUDED.unread_only
+RETURNING id, created_at, updated_at, user_id, name, pq_array(feed_ids), pq_array(tags), keyword, max_age_seconds, unread_only;
+
+-- name: GetViewByName :one
+SELECT id, created_at, updated_at, user_id, name, pq_array(feed_ids), pq_array(tags), keyword, max_age_seconds, unread_only FROM views
+WHERE user_id = $1 AND name = $2;
+
+-- name: GetViewsForUser :many
+SELECT id, created_at, updated_at, user_id, name, pq_array(feed_ids), pq_array(tags), keyword, max_age_seconds, unread_only FROM views
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: DeleteView :execrows
+DELETE FROM views
+WHERE user_id = $1 AND name = $2;
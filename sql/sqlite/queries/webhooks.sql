@@ -0,0 +1,90 @@
+-- name: CreateWebhook :one
+INSERT INTO webhooks (id, created_at, updated_at, user_id, url, secret, feed_id, keyword)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING id, created_at, updated_at, user_id, url, secret, feed_id, keyword;
+
+-- name: GetWebhooksForUser :many
+SELECT webhooks.id, webhooks.created_at, webhooks.updated_at, webhooks.user_id, webhooks.url, webhooks.secret, webhooks.feed_id, webhooks.keyword, feeds.url AS feed_url
+FROM webhooks
+LEFT JOIN feeds ON feeds.id = webhooks.feed_id
+WHERE webhooks.user_id = $1
+ORDER BY webhooks.created_at;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (webhook_id, post_id)
+SELECT webhooks.id, posts.id
+FROM posts
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+INNER JOIN webhooks ON webhooks.user_id = feed_follows.user_id
+WHERE posts.id = $1
+AND (webhooks.feed_id IS NULL OR webhooks.feed_id = posts.feed_id)
+AND (
+    webhooks.keyword IS NULL
+    OR instr(lower(posts.title), lower(webhooks.keyword)) > 0
+    OR instr(lower(posts.description), lower(webhooks.keyword)) > 0
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET attempts = attempts + 1, next_attempt_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '+5 minutes'), updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+    ORDER BY next_attempt_at
+    LIMIT $1
+)
+RETURNING id, created_at, updated_at, webhook_id, post_id, status, attempts, next_attempt_at, last_status_code, last_error, delivered_at;
+
+-- name: GetWebhookDeliveryPayload :one
+SELECT
+    webhooks.url AS webhook_url,
+    webhooks.secret,
+    posts.title,
+    posts.url,
+    posts.description,
+    posts.published_at,
+    feeds.name AS feed_name,
+    feeds.url AS feed_url
+FROM webhook_deliveries
+INNER JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+INNER JOIN posts ON posts.id = webhook_deliveries.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE webhook_deliveries.id = $1;
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+SET status = 'delivered', delivered_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'), last_status_code = $2, last_error = NULL, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET
+    status = $1,
+    next_attempt_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now', '+' || $2 || ' seconds'),
+    last_status_code = $3,
+    last_error = $4,
+    updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE id = $5;
+
+-- name: GetWebhookDeliveriesForUser :many
+SELECT webhook_deliveries.id, webhook_deliveries.created_at, webhook_deliveries.updated_at, webhook_deliveries.webhook_id, webhook_deliveries.post_id, webhook_deliveries.status, webhook_deliveries.attempts, webhook_deliveries.next_attempt_at, webhook_deliveries.last_status_code, webhook_deliveries.last_error, webhook_deliveries.delivered_at, webhooks.url AS webhook_url, posts.title AS post_title
+FROM webhook_deliveries
+INNER JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id
+INNER JOIN posts ON posts.id = webhook_deliveries.post_id
+WHERE webhooks.user_id = $1
+ORDER BY webhook_deliveries.updated_at DESC
+LIMIT $2;
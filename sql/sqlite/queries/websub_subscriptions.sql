@@ -0,0 +1,28 @@
+-- name: UpsertWebsubSubscription :one
+INSERT INTO websub_subscriptions (feed_id, hub_url, topic_url, secret)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET hub_url = EXCLUDED.hub_url,
+    topic_url = EXCLUDED.topic_url,
+    secret = EXCLUDED.secret,
+    state = 'pending',
+    updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+RETURNING feed_id, created_at, updated_at, hub_url, topic_url, secret, state, lease_expires_at;
+
+-- name: GetWebsubSubscription :one
+SELECT feed_id, created_at, updated_at, hub_url, topic_url, secret, state, lease_expires_at FROM websub_subscriptions WHERE feed_id = $1;
+
+-- name: VerifyWebsubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'verified', lease_expires_at = $2, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE feed_id = $1;
+
+-- name: DenyWebsubSubscription :exec
+UPDATE websub_subscriptions
+SET state = 'denied', lease_expires_at = NULL, updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')
+WHERE feed_id = $1;
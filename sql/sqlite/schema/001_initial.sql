@@ -0,0 +1,204 @@
+-- The SQLite schema matches the Postgres one in sql/schema as of its version
+-- 20, with these differences:
+--   * UUIDs are TEXT. Columns with a default get a random version 4 UUID
+--     built from randomblob().
+--   * Timestamps are TEXT in UTC, formatted like 2006-01-02 15:04:05.999+00:00
+--     so they compare in order. Declaring them TIMESTAMP makes the driver
+--     return them as times.
+--   * short_id is the INTEGER PRIMARY KEY, SQLite's equivalent of BIGSERIAL,
+--     and id is a UNIQUE key that foreign keys reference.
+--   * Arrays are TEXT holding Postgres array literals such as {"a","b"}, the
+--     form lib/pq sends and expects back.
+
+-- +goose Up
+CREATE TABLE users (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL UNIQUE,
+    fever_api_key TEXT UNIQUE,
+    email TEXT,
+    last_digest_at TIMESTAMP,
+    role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'member')),
+    password_hash TEXT
+);
+
+CREATE TABLE feeds (
+    short_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    id TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_fetched_at TIMESTAMP,
+    retention_max_age_seconds INTEGER,
+    retention_max_posts INTEGER,
+    last_status_code INTEGER,
+    last_error TEXT,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_succeeded_at TIMESTAMP,
+    disabled_at TIMESTAMP,
+    redirect_url TEXT,
+    redirect_count INTEGER NOT NULL DEFAULT 0,
+    title TEXT,
+    site_url TEXT,
+    description TEXT,
+    language TEXT,
+    image_url TEXT,
+    generator TEXT,
+    fetch_interval_seconds INTEGER,
+    fetch_full_text BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE feed_follows (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    display_name TEXT,
+    UNIQUE (user_id, feed_id)
+);
+
+CREATE TABLE posts (
+    short_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    id TEXT NOT NULL UNIQUE DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    title TEXT NOT NULL,
+    url TEXT NOT NULL UNIQUE,
+    description TEXT,
+    published_at TIMESTAMP,
+    feed_id TEXT NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    author TEXT,
+    content TEXT
+);
+
+CREATE TABLE post_reads (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE post_stars (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE webhooks (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT,
+    feed_id TEXT REFERENCES feeds(id) ON DELETE CASCADE,
+    keyword TEXT
+);
+
+CREATE TABLE webhook_deliveries (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    webhook_id TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    last_status_code INTEGER,
+    last_error TEXT,
+    delivered_at TIMESTAMP,
+    UNIQUE (webhook_id, post_id)
+);
+
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at)
+WHERE status = 'pending';
+
+CREATE TABLE websub_subscriptions (
+    feed_id TEXT PRIMARY KEY REFERENCES feeds(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    hub_url TEXT NOT NULL,
+    topic_url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    state TEXT NOT NULL DEFAULT 'pending',
+    lease_expires_at TIMESTAMP
+);
+
+CREATE TABLE tags (
+    short_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    id TEXT NOT NULL UNIQUE DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    UNIQUE (user_id, name)
+);
+
+CREATE TABLE feed_follow_tags (
+    feed_follow_id TEXT NOT NULL REFERENCES feed_follows(id) ON DELETE CASCADE,
+    tag_id TEXT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (feed_follow_id, tag_id)
+);
+
+CREATE TABLE post_rules (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('keyword', 'regex', 'author', 'domain', 'feed')),
+    pattern TEXT NOT NULL,
+    feed_id TEXT REFERENCES feeds(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('mute', 'highlight', 'mark-read'))
+);
+
+CREATE TABLE views (
+    id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' || substr('89ab', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))),
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    feed_ids TEXT,
+    tags TEXT,
+    keyword TEXT,
+    max_age_seconds INTEGER,
+    unread_only BOOLEAN NOT NULL DEFAULT FALSE,
+    UNIQUE (user_id, name)
+);
+
+-- Pruned posts are remembered by url so they aren't saved again while the
+-- feed still lists them.
+CREATE TABLE pruned_posts (
+    url TEXT PRIMARY KEY,
+    pruned_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE TABLE api_tokens (
+    id TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    -- Only the SHA-256 of a token is stored; the token itself is shown once.
+    token_hash TEXT NOT NULL UNIQUE,
+    UNIQUE (user_id, name)
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
+DROP TABLE pruned_posts;
+DROP TABLE views;
+DROP TABLE post_rules;
+DROP TABLE feed_follow_tags;
+DROP TABLE tags;
+DROP TABLE websub_subscriptions;
+DROP TABLE webhook_deliveries;
+DROP TABLE webhooks;
+DROP TABLE post_stars;
+DROP TABLE post_reads;
+DROP TABLE posts;
+DROP TABLE feed_follows;
+DROP TABLE feeds;
+DROP TABLE users;
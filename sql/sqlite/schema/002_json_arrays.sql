@@ -0,0 +1,14 @@
+-- Arrays are now TEXT holding JSON arrays such as ["a","b"], which queries
+-- read with json_each. lib/pq's array types are sent as JSON, and pq_array()
+-- turns a column back into the Postgres array literal lib/pq scans.
+
+-- +goose Up
+-- lib/pq quotes every element and escapes only " and \, as JSON does, so
+-- the literals it wrote only need their braces swapped.
+UPDATE views SET feed_ids = '[' || substr(feed_ids, 2, length(feed_ids) - 2) || ']'
+WHERE feed_ids LIKE '{%';
+UPDATE views SET tags = '[' || substr(tags, 2, length(tags) - 2) || ']'
+WHERE tags LIKE '{%';
+
+-- +goose Down
+UPDATE views SET feed_ids = pq_array(feed_ids), tags = pq_array(tags);
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Posts of feeds with fetch_full_text wait here until agg downloads the
+-- article behind them.
+CREATE TABLE full_text_fetches (
+    post_id TEXT PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now')),
+    url TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'))
+);
+
+CREATE INDEX full_text_fetches_next_attempt_at_idx ON full_text_fetches (next_attempt_at);
+
+-- +goose Down
+DROP TABLE full_text_fetches;
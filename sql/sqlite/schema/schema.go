@@ -0,0 +1,10 @@
+// Package schema embeds the SQLite schema migrations, the counterpart of the
+// Postgres ones in sql/schema.
+package schema
+
+import "embed"
+
+// FS holds the migrations, named <version>_<name>.sql in goose's format.
+//
+//go:embed *.sql
+var FS embed.FS
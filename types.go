@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"io/fs"
+	"strings"
+
 	"github.com/inscrutabletaco/gator/internal/config"
-	"github.com/inscrutabletaco/gator/internal/database"
+	"github.com/inscrutabletaco/gator/internal/store"
+	"github.com/pressly/goose/v3"
 )
 
 type command struct {
@@ -11,26 +18,94 @@ type command struct {
 }
 
 type commands struct {
-	registeredCommands map[string]func(*state, command) error
+	registeredCommands map[string]func(context.Context, *state, command) error
 }
 
 type state struct {
-	db  *database.Queries
+	db  store.Store
 	cfg *config.Config
+	// sqlDB is the connection db runs on, for running migrations.
+	sqlDB *sql.DB
+	// dialect and migrations are those of the database db_url points to.
+	dialect    goose.Dialect
+	migrations fs.FS
 }
 
 type RSSFeed struct {
 	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
+		Title string `xml:"title"`
+		// AtomLinks must precede Link, which would otherwise also match
+		// <atom:link> elements and be overwritten by their empty text.
+		AtomLinks   []AtomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Link        string     `xml:"link"`
+		Description string     `xml:"description"`
+		Language    string     `xml:"language"`
+		Generator   string     `xml:"generator"`
+		// ItunesImage must precede Image for the same reason as AtomLinks.
+		ItunesImage struct {
+			Href string `xml:"href,attr"`
+		} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		Image struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
+		Item []RSSItem `xml:"item"`
 	} `xml:"channel"`
 }
 
+// imageURL returns the channel's <image>, or its podcast artwork if it has
+// none.
+func (feed *RSSFeed) imageURL() string {
+	if url := strings.TrimSpace(feed.Channel.Image.URL); url != "" {
+		return url
+	}
+	return strings.TrimSpace(feed.Channel.ItunesImage.Href)
+}
+
+// AtomLink is an <atom:link> element, used by RSS feeds to advertise their
+// canonical ("self") URL and any WebSub hubs ("hub").
+type AtomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
 type RSSItem struct {
 	Title       string `xml:"title"`
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	Author      string `xml:"author"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+// author returns the item's author, preferring <dc:creator> since RSS
+// <author> is meant to be an email address and is often left out.
+func (item RSSItem) author() string {
+	if creator := strings.TrimSpace(item.Creator); creator != "" {
+		return creator
+	}
+	return strings.TrimSpace(item.Author)
+}
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title       string `xml:"title"`
+		DateCreated string `xml:"dateCreated,omitempty"`
+	} `xml:"head"`
+	Body struct {
+		Outlines []OPMLOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+// OPMLOutline is either a feed, when XMLUrl is set, or a folder of nested
+// outlines. Category holds comma separated categories, which gator maps to
+// tags.
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLUrl   string        `xml:"xmlUrl,attr,omitempty"`
+	Category string        `xml:"category,attr,omitempty"`
+	Outlines []OPMLOutline `xml:"outline"`
 }